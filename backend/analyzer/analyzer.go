@@ -3,9 +3,10 @@ package analyzer
 import (
 	"context"
 	_ "embed"
-	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
 //go:embed default_prompt.txt
@@ -21,12 +22,18 @@ type Analyzer struct {
 }
 
 func NewAnalyzer(providerType, apiKey, model string) *Analyzer {
-	var provider LLMProvider
-	if providerType == "openai" {
-		provider = NewOpenAIProvider(apiKey, model)
-	} else {
-		provider = NewOllamaProvider(model)
+	return NewAnalyzerFromConfig(ProviderConfig{Type: providerType, APIKey: apiKey, Model: model})
+}
+
+// NewAnalyzerFromConfig creates an Analyzer for providers that need more than a key and model.
+func NewAnalyzerFromConfig(cfg ProviderConfig) *Analyzer {
+	return &Analyzer{
+		provider: NewProvider(cfg),
 	}
+}
+
+// NewAnalyzerWithProvider creates an Analyzer around an existing provider, e.g. a FallbackProvider.
+func NewAnalyzerWithProvider(provider LLMProvider) *Analyzer {
 	return &Analyzer{
 		provider: provider,
 	}
@@ -68,41 +75,56 @@ func (a *Analyzer) Analyze(ctx context.Context, text string, customPrompt string
 		prompt = RenderPrompt(defaultAnalysisPrompt, targetLang, text, false)
 	}
 
-	options := map[string]interface{}{
-		"num_ctx":     contextSize,
-		"temperature": 0.1,
-	}
-
-	responseText, err := a.provider.Chat(ctx, prompt, options, onToken)
-	if err != nil {
-		return nil, err
-	}
+	return a.run(ctx, prompt, analysisSchema(customPrompt), contextSize, onToken)
+}
 
-	// Clean up markdown code blocks if the LLM wrapped the output
-	if idx := strings.Index(responseText, "{"); idx != -1 {
-		responseText = responseText[idx:]
-	}
-	if idx := strings.LastIndex(responseText, "}"); idx != -1 {
-		responseText = responseText[:idx+1]
+// run sends a rendered prompt to the provider and parses the JSON analysis, which must match schema.
+// Structured output is requested from the provider, and responses that still fail
+// validation are sent back to the model for repair a bounded number of times.
+func (a *Analyzer) run(ctx context.Context, prompt string, schema *jsonschema.Definition, contextSize int, onToken func(string)) (*AnalysisResult, error) {
+	options := map[string]interface{}{
+		"num_ctx":        contextSize,
+		"temperature":    0.1,
+		OptionJSONSchema: schema,
 	}
 
-	var analysis AnalysisResult
-	if err := json.Unmarshal([]byte(responseText), &analysis); err != nil {
-		// Fallback: try to return just summary if JSON fails
-		analysis = AnalysisResult{Summary: responseText}
-	}
+	currentPrompt := prompt
+	var lastErr error
+	var responseText string
+	attempts := 0
+
+	for attempts <= maxRepairAttempts {
+		attempts++
+		var err error
+		responseText, err = a.provider.Chat(ctx, currentPrompt, options, onToken)
+		if err != nil {
+			return nil, err
+		}
 
-	// Fill provider info
-	analysis.Provider = a.provider.Name()
-	analysis.Model = a.provider.Model()
+		analysis, err := parseAnalysis(schema, responseText)
+		if err == nil {
+			// Fill provider info
+			analysis.Provider = a.provider.Name()
+			analysis.Model = a.provider.Model()
+
+			// Post-process: Sanitize Tags
+			for i, tag := range analysis.Tags {
+				// Replace spaces with hyphens
+				analysis.Tags[i] = strings.ReplaceAll(tag, " ", "-")
+			}
+			return analysis, nil
+		}
 
-	// Post-process: Sanitize Tags
-	for i, tag := range analysis.Tags {
-		// Replace spaces with hyphens
-		analysis.Tags[i] = strings.ReplaceAll(tag, " ", "-")
+		lastErr = err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// Repairs are not streamed; the UI already shows the first attempt.
+		onToken = nil
+		currentPrompt = repairPrompt(prompt, responseText, err)
 	}
 
-	return &analysis, nil
+	return nil, &InvalidOutputError{Attempts: attempts, Raw: responseText, Err: lastErr}
 }
 
 func (a *Analyzer) ListModels(ctx context.Context) ([]string, error) {
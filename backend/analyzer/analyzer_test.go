@@ -65,6 +65,23 @@ func TestNewAnalyzer(t *testing.T) {
 	if anOpenAI.provider.Name() != "openai" {
 		t.Errorf("Expected openai provider, got %s", anOpenAI.provider.Name())
 	}
+
+	// Test OpenAI-compatible servers
+	anCompat := NewAnalyzerFromConfig(ProviderConfig{Type: "openai-compatible", Model: "local", BaseURL: "http://localhost:1234/v1/"})
+	if anCompat.provider.Name() != "openai-compatible" {
+		t.Errorf("Expected openai-compatible provider, got %s", anCompat.provider.Name())
+	}
+	if got := anCompat.provider.(*OpenAICompatibleProvider).BaseURL(); got != "http://localhost:1234/v1" {
+		t.Errorf("Expected trailing slash to be trimmed, got %s", got)
+	}
+
+	anLlama := NewAnalyzer("llamacpp", "", "model.gguf")
+	if anLlama.provider.Name() != "llamacpp" {
+		t.Errorf("Expected llamacpp provider, got %s", anLlama.provider.Name())
+	}
+	if got := anLlama.provider.(*OpenAICompatibleProvider).BaseURL(); got != DefaultLlamaCppBaseURL {
+		t.Errorf("Expected default llama.cpp base URL, got %s", got)
+	}
 }
 
 func TestListModels(t *testing.T) {
@@ -0,0 +1,253 @@
+package analyzer
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//go:embed merge_prompt.txt
+var mergePrompt string
+
+// Analysis modes for long inputs.
+const (
+	AnalysisModeAuto    = "auto"    // chunk only when the input does not fit the context window
+	AnalysisModeSingle  = "single"  // always send the whole text in one prompt
+	AnalysisModeChunked = "chunked" // always use map-reduce
+)
+
+// ProgressFunc reports map-reduce progress: completed steps out of total.
+type ProgressFunc func(done, total int, stage string)
+
+// EstimateTokens gives a rough token count without a tokenizer.
+// CJK characters are counted as one token each, other text as ~4 characters per token.
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r), unicode.Is(unicode.Hangul, r):
+			cjk++
+		default:
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// windowBudget returns how many content tokens fit in a single prompt,
+// leaving room for the prompt template and the model's answer.
+func windowBudget(template string, contextSize int) int {
+	if contextSize <= 0 {
+		contextSize = 8192
+	}
+	reserved := contextSize / 4
+	if reserved < 1024 {
+		reserved = 1024
+	}
+	budget := contextSize - reserved - EstimateTokens(template)
+	if budget < 256 {
+		budget = 256
+	}
+	return budget
+}
+
+// NeedsChunking reports whether text must be split to fit into the context window.
+func NeedsChunking(text, customPrompt string, contextSize int) bool {
+	template := customPrompt
+	if template == "" {
+		template = defaultAnalysisPrompt
+	}
+	return EstimateTokens(text) > windowBudget(template, contextSize)
+}
+
+// Windows returns the pieces of text that AnalyzeChunked analyzes one by one.
+func Windows(text, customPrompt string, contextSize int) []string {
+	template := customPrompt
+	if template == "" {
+		template = defaultAnalysisPrompt
+	}
+	budget := windowBudget(template, contextSize)
+	return SplitWindows(text, budget, budget/10)
+}
+
+// SplitWindows splits text into windows of at most windowTokens (estimated),
+// with roughly overlapTokens of trailing context repeated at the start of the next window.
+func SplitWindows(text string, windowTokens, overlapTokens int) []string {
+	if windowTokens <= 0 {
+		return []string{text}
+	}
+	if overlapTokens >= windowTokens {
+		overlapTokens = windowTokens / 10
+	}
+
+	units := splitUnits(text, windowTokens)
+	if len(units) == 0 {
+		return nil
+	}
+
+	var windows []string
+	var current []string
+	currentTokens := 0
+
+	for _, unit := range units {
+		n := EstimateTokens(unit)
+		if currentTokens+n > windowTokens && len(current) > 0 {
+			windows = append(windows, strings.Join(current, "\n"))
+
+			// Carry trailing units over as overlap
+			var carry []string
+			carryTokens := 0
+			for i := len(current) - 1; i >= 0; i-- {
+				t := EstimateTokens(current[i])
+				if carryTokens+t > overlapTokens {
+					break
+				}
+				carry = append([]string{current[i]}, carry...)
+				carryTokens += t
+			}
+			current = carry
+			currentTokens = carryTokens
+		}
+		current = append(current, unit)
+		currentTokens += n
+	}
+	if len(current) > 0 {
+		windows = append(windows, strings.Join(current, "\n"))
+	}
+	return windows
+}
+
+// splitUnits breaks text into lines, hard-splitting any line larger than maxTokens.
+func splitUnits(text string, maxTokens int) []string {
+	var units []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		for EstimateTokens(line) > maxTokens {
+			runes := []rune(line)
+			// Find the longest prefix that fits, preferring a space or sentence boundary
+			cut := len(runes)
+			for cut > 1 && EstimateTokens(string(runes[:cut])) > maxTokens {
+				cut = cut * 3 / 4
+			}
+			for i := cut; i > cut/2; i-- {
+				if unicode.IsSpace(runes[i-1]) || strings.ContainsRune(".!?。！？", runes[i-1]) {
+					cut = i
+					break
+				}
+			}
+			units = append(units, strings.TrimSpace(string(runes[:cut])))
+			line = strings.TrimSpace(string(runes[cut:]))
+		}
+		if line != "" {
+			units = append(units, line)
+		}
+	}
+	return units
+}
+
+// AnalyzeChunked runs a map-reduce analysis: every window is analyzed on its own and
+// the partial results are merged into a single AnalysisResult. Only the final merge
+// is streamed to onToken.
+func (a *Analyzer) AnalyzeChunked(ctx context.Context, text string, customPrompt string, targetLang string, contextSize int, onProgress ProgressFunc, onToken func(string)) (*AnalysisResult, error) {
+	if targetLang == "" {
+		targetLang = "English"
+	}
+	windows := Windows(text, customPrompt, contextSize)
+	if len(windows) <= 1 {
+		return a.Analyze(ctx, text, customPrompt, targetLang, contextSize, onToken)
+	}
+
+	total := len(windows) + 1
+	report := func(done int, stage string) {
+		if onProgress != nil {
+			onProgress(done, total, stage)
+		}
+	}
+
+	// Map
+	partials := make([]AnalysisResult, 0, len(windows))
+	for i, window := range windows {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		report(i, fmt.Sprintf("window %d/%d", i+1, len(windows)))
+
+		partial, err := a.Analyze(ctx, window, customPrompt, targetLang, contextSize, nil)
+		if err != nil {
+			return nil, fmt.Errorf("analysis of window %d/%d failed: %w", i+1, len(windows), err)
+		}
+		partials = append(partials, *partial)
+	}
+
+	// Reduce
+	report(len(windows), "merge")
+	result, err := a.merge(ctx, partials, targetLang, contextSize, onToken)
+	if err != nil {
+		return nil, err
+	}
+	report(total, "done")
+	return result, nil
+}
+
+// merge combines partial results, reducing in groups when they do not fit one prompt.
+func (a *Analyzer) merge(ctx context.Context, partials []AnalysisResult, targetLang string, contextSize int, onToken func(string)) (*AnalysisResult, error) {
+	if len(partials) == 1 {
+		return &partials[0], nil
+	}
+
+	budget := windowBudget(mergePrompt, contextSize)
+	encoded := make([]string, len(partials))
+	totalTokens := 0
+	for i, p := range partials {
+		// Provider metadata is noise for the model
+		p.Provider, p.Model = "", ""
+		data, _ := json.Marshal(p)
+		encoded[i] = string(data)
+		totalTokens += EstimateTokens(encoded[i])
+	}
+
+	if totalTokens > budget && len(partials) > 2 {
+		// Hierarchical reduce: merge neighbouring groups first
+		var groups [][]AnalysisResult
+		var group []AnalysisResult
+		groupTokens := 0
+		for i, p := range partials {
+			n := EstimateTokens(encoded[i])
+			if groupTokens+n > budget && len(group) > 0 {
+				groups = append(groups, group)
+				group, groupTokens = nil, 0
+			}
+			group = append(group, p)
+			groupTokens += n
+		}
+		groups = append(groups, group)
+
+		// Make sure each level makes progress
+		if len(groups) == len(partials) {
+			groups = [][]AnalysisResult{partials[:len(partials)/2], partials[len(partials)/2:]}
+		}
+
+		reduced := make([]AnalysisResult, 0, len(groups))
+		for _, g := range groups {
+			r, err := a.merge(ctx, g, targetLang, contextSize, nil)
+			if err != nil {
+				return nil, err
+			}
+			reduced = append(reduced, *r)
+		}
+		return a.merge(ctx, reduced, targetLang, contextSize, onToken)
+	}
+
+	var content strings.Builder
+	for i, e := range encoded {
+		content.WriteString(fmt.Sprintf("Part %d:\n%s\n\n", i+1, e))
+	}
+	prompt := RenderPrompt(mergePrompt, targetLang, content.String(), false)
+	return a.run(ctx, prompt, AnalysisSchema, contextSize, onToken)
+}
@@ -0,0 +1,111 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// RecordingProvider returns a canned response and records every prompt.
+type RecordingProvider struct {
+	MockProvider
+	mu      sync.Mutex
+	Prompts []string
+}
+
+func (r *RecordingProvider) Chat(ctx context.Context, prompt string, options map[string]interface{}, streamCallback func(string)) (string, error) {
+	r.mu.Lock()
+	r.Prompts = append(r.Prompts, prompt)
+	r.mu.Unlock()
+	return r.MockProvider.Chat(ctx, prompt, options, streamCallback)
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens("abcdefgh"); got != 2 {
+		t.Errorf("Expected 2 tokens for 8 latin chars, got %d", got)
+	}
+	if got := EstimateTokens("你好世界"); got != 4 {
+		t.Errorf("Expected 4 tokens for 4 CJK chars, got %d", got)
+	}
+}
+
+func TestSplitWindows(t *testing.T) {
+	var lines []string
+	for i := 0; i < 40; i++ {
+		lines = append(lines, strings.Repeat("word ", 20)) // ~25 tokens each
+	}
+	text := strings.Join(lines, "\n")
+
+	windows := SplitWindows(text, 200, 50)
+	if len(windows) < 5 {
+		t.Fatalf("Expected multiple windows, got %d", len(windows))
+	}
+	for i, w := range windows {
+		if EstimateTokens(w) > 200 {
+			t.Errorf("Window %d exceeds budget: %d tokens", i, EstimateTokens(w))
+		}
+	}
+
+	// Overlap: the last line of a window starts the next one
+	first := strings.Split(windows[0], "\n")
+	second := strings.Split(windows[1], "\n")
+	if first[len(first)-1] != second[0] {
+		t.Error("Expected consecutive windows to overlap")
+	}
+}
+
+func TestSplitWindows_HardSplitsLongLines(t *testing.T) {
+	text := strings.Repeat("长", 1000)
+	windows := SplitWindows(text, 300, 0)
+	if len(windows) < 4 {
+		t.Fatalf("Expected a single long line to be split, got %d windows", len(windows))
+	}
+	total := 0
+	for _, w := range windows {
+		total += len([]rune(w))
+	}
+	if total != 1000 {
+		t.Errorf("Expected no text to be lost without overlap, got %d runes", total)
+	}
+}
+
+func TestAnalyzeChunked(t *testing.T) {
+	rec := &RecordingProvider{MockProvider: MockProvider{
+		Response: `{"summary": "Merged", "key_points": ["A"], "tags": ["long form"], "assessment": {}}`,
+	}}
+	an := &Analyzer{provider: rec}
+
+	text := strings.Repeat(strings.Repeat("token ", 100)+"\n", 100) // ~15k tokens
+	var progress []int
+	result, err := an.AnalyzeChunked(context.Background(), text, "", "English", 4096, func(done, total int, stage string) {
+		progress = append(progress, done)
+	}, nil)
+	if err != nil {
+		t.Fatalf("AnalyzeChunked failed: %v", err)
+	}
+
+	if len(rec.Prompts) < 3 {
+		t.Fatalf("Expected map calls plus a merge call, got %d calls", len(rec.Prompts))
+	}
+	if !strings.Contains(rec.Prompts[len(rec.Prompts)-1], "Partial analyses") {
+		t.Error("Expected the last call to be the merge prompt")
+	}
+	if result.Summary != "Merged" || result.Tags[0] != "long-form" {
+		t.Errorf("Unexpected merged result: %+v", result)
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i] < progress[i-1] {
+			t.Errorf("Progress is not monotonic: %v", progress)
+		}
+	}
+}
+
+func TestNeedsChunking(t *testing.T) {
+	if NeedsChunking("short text", "", 8192) {
+		t.Error("Short text should not need chunking")
+	}
+	if !NeedsChunking(strings.Repeat("word ", 40000), "", 8192) {
+		t.Error("Long text should need chunking")
+	}
+}
@@ -0,0 +1,100 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultLlamaCppBaseURL is where llama.cpp's llama-server exposes its OpenAI-compatible API.
+const DefaultLlamaCppBaseURL = "http://localhost:8080/v1"
+
+// OpenAICompatibleProvider talks to any server implementing the OpenAI chat API
+// (LM Studio, vLLM, llama.cpp llama-server, ...). Unlike OpenAIProvider it does not
+// require an API key and does not filter the model list.
+type OpenAICompatibleProvider struct {
+	OpenAIProvider
+	name    string
+	baseURL string
+}
+
+// NewOpenAICompatibleProvider creates a provider for the given base URL.
+// name is reported by Name() so notes record which kind of server answered.
+func NewOpenAICompatibleProvider(name, baseURL, apiKey, model string, headers map[string]string) *OpenAICompatibleProvider {
+	if name == "" {
+		name = ProviderOpenAICompatible
+	}
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" && name == ProviderLlamaCpp {
+		baseURL = DefaultLlamaCppBaseURL
+	}
+
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = baseURL
+	if len(headers) > 0 {
+		config.HTTPClient = &http.Client{Transport: &headerTransport{headers: headers, base: http.DefaultTransport}}
+	}
+
+	return &OpenAICompatibleProvider{
+		OpenAIProvider: OpenAIProvider{
+			client: openai.NewClientWithConfig(config),
+			model:  model,
+		},
+		name:    name,
+		baseURL: baseURL,
+	}
+}
+
+func (p *OpenAICompatibleProvider) Chat(ctx context.Context, prompt string, options map[string]interface{}, streamCallback func(string)) (string, error) {
+	if p.baseURL == "" {
+		return "", errors.New("base url not configured for openai-compatible provider")
+	}
+	if p.model == "" {
+		return "", errors.New("model not configured for openai-compatible provider")
+	}
+	return p.OpenAIProvider.Chat(ctx, prompt, options, streamCallback)
+}
+
+func (p *OpenAICompatibleProvider) Name() string {
+	return p.name
+}
+
+// BaseURL returns the configured API base URL.
+func (p *OpenAICompatibleProvider) BaseURL() string {
+	return p.baseURL
+}
+
+func (p *OpenAICompatibleProvider) ListModels(ctx context.Context) ([]string, error) {
+	if p.baseURL == "" {
+		return nil, errors.New("base url not configured for openai-compatible provider")
+	}
+	models, err := p.client.ListModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models from %s: %w", p.baseURL, err)
+	}
+	names := make([]string, 0, len(models.Models))
+	for _, m := range models.Models {
+		names = append(names, m.ID)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// headerTransport adds custom headers (e.g. for reverse proxies) to every request.
+type headerTransport struct {
+	headers map[string]string
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,82 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestOpenAICompatibleProvider_Chat(t *testing.T) {
+	var gotAuth, gotCustom, gotModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		gotAuth = r.Header.Get("Authorization")
+		gotCustom = r.Header.Get("X-Proxy-Token")
+
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		gotModel, _ = req["model"].(string)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hel\"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[],\"usage\":{\"total_tokens\":3}}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAICompatibleProvider("", server.URL+"/v1/", "", "local-model", map[string]string{"X-Proxy-Token": "abc"})
+	resp, err := p.Chat(context.Background(), "hi", nil, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp != "hello" {
+		t.Errorf("Unexpected response: %q", resp)
+	}
+	if gotAuth != "" {
+		t.Errorf("Expected no Authorization header without a key, got %q", gotAuth)
+	}
+	if gotCustom != "abc" {
+		t.Errorf("Expected custom header to be sent, got %q", gotCustom)
+	}
+	if gotModel != "local-model" {
+		t.Errorf("Expected model local-model, got %q", gotModel)
+	}
+	if p.Name() != ProviderOpenAICompatible {
+		t.Errorf("Expected name %s, got %s", ProviderOpenAICompatible, p.Name())
+	}
+}
+
+func TestOpenAICompatibleProvider_ListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[{"id":"qwen2.5-7b-instruct"},{"id":"llama-3.1-8b"}]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAICompatibleProvider(ProviderLlamaCpp, server.URL, "secret", "", nil)
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	// Unlike OpenAI, local model names are not filtered
+	want := []string{"llama-3.1-8b", "qwen2.5-7b-instruct"}
+	if !reflect.DeepEqual(models, want) {
+		t.Errorf("Expected %v, got %v", want, models)
+	}
+}
+
+func TestOpenAICompatibleProvider_RequiresModel(t *testing.T) {
+	p := NewOpenAICompatibleProvider("", "http://localhost:1/v1", "", "", nil)
+	if _, err := p.Chat(context.Background(), "hi", nil, nil); err == nil {
+		t.Error("Expected error when no model is configured")
+	}
+}
@@ -0,0 +1,167 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// FallbackProvider tries a list of providers in order. The next provider is used when
+// the current one cannot be reached, times out, or (for structured requests) returns
+// output that does not match the requested JSON schema.
+//
+// Tokens are streamed live from whichever provider is answering. When a provider
+// fails after streaming part of an answer, OnFallback is told so, and the caller
+// can mark the switch in its stream.
+//
+// Name and Model report the provider that answered the most recent request, so the
+// analysis result records who actually produced it.
+type FallbackProvider struct {
+	providers []LLMProvider
+
+	// OnFallback is called before moving on to the next provider. streamed reports
+	// whether the failed provider already passed tokens to the stream callback.
+	OnFallback func(failed LLMProvider, err error, streamed bool)
+
+	mu   sync.Mutex
+	last LLMProvider
+	down map[int]bool // providers that were unreachable; skipped for the rest of the chain's life
+}
+
+// NewFallbackProvider chains the given providers; the first one is the primary.
+func NewFallbackProvider(providers ...LLMProvider) *FallbackProvider {
+	return &FallbackProvider{
+		providers: providers,
+		down:      make(map[int]bool),
+	}
+}
+
+func (f *FallbackProvider) Chat(ctx context.Context, prompt string, options map[string]interface{}, streamCallback func(string)) (string, error) {
+	if len(f.providers) == 0 {
+		return "", errors.New("no providers configured")
+	}
+	schema, _ := options[OptionJSONSchema].(*jsonschema.Definition)
+
+	var errs []error
+	for i, p := range f.providers {
+		isLast := i == len(f.providers)-1
+		if !isLast && f.isDown(i) {
+			continue
+		}
+
+		callback := streamCallback
+		streamed := false
+		if streamCallback != nil {
+			callback = func(token string) {
+				streamed = true
+				streamCallback(token)
+			}
+		}
+
+		resp, err := p.Chat(ctx, prompt, options, callback)
+		if err == nil && schema != nil && !isLast {
+			// The last provider's output is left to the caller's repair loop
+			if verr := validateJSONResponse(*schema, resp); verr != nil {
+				err = fmt.Errorf("invalid JSON output: %w", verr)
+			}
+		}
+		if err == nil {
+			f.mu.Lock()
+			f.last = p
+			f.mu.Unlock()
+			return resp, nil
+		}
+
+		if ctx.Err() != nil || !shouldFallback(err) {
+			return "", err
+		}
+		errs = append(errs, fmt.Errorf("%s/%s: %w", p.Name(), p.Model(), err))
+		if isUnreachable(err) {
+			f.markDown(i)
+		}
+		if !isLast && f.OnFallback != nil {
+			f.OnFallback(p, err, streamed)
+		}
+	}
+	return "", fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+// Name returns the name of the provider that answered last (the primary before any call).
+func (f *FallbackProvider) Name() string {
+	if p := f.current(); p != nil {
+		return p.Name()
+	}
+	return "fallback"
+}
+
+// Model returns the model of the provider that answered last (the primary before any call).
+func (f *FallbackProvider) Model() string {
+	if p := f.current(); p != nil {
+		return p.Model()
+	}
+	return ""
+}
+
+// ListModels lists the primary provider's models.
+func (f *FallbackProvider) ListModels(ctx context.Context) ([]string, error) {
+	if len(f.providers) == 0 {
+		return nil, errors.New("no providers configured")
+	}
+	return f.providers[0].ListModels(ctx)
+}
+
+func (f *FallbackProvider) current() LLMProvider {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.last != nil {
+		return f.last
+	}
+	if len(f.providers) > 0 {
+		return f.providers[0]
+	}
+	return nil
+}
+
+func (f *FallbackProvider) isDown(i int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.down[i]
+}
+
+func (f *FallbackProvider) markDown(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down[i] = true
+}
+
+// shouldFallback reports whether err means "try another provider":
+// connection failures, timeouts and invalid structured output.
+func shouldFallback(err error) bool {
+	if isUnreachable(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var schemaErr *schemaError
+	return errors.As(err, &schemaErr)
+}
+
+// isUnreachable reports connection-level failures (refused, reset, DNS, no route).
+func isUnreachable(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EHOSTUNREACH) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
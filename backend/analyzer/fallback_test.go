@@ -0,0 +1,147 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// NamedProvider is a MockProvider with a configurable identity.
+type NamedProvider struct {
+	MockProvider
+	name, model string
+	calls       int
+}
+
+func (n *NamedProvider) Chat(ctx context.Context, prompt string, options map[string]interface{}, streamCallback func(string)) (string, error) {
+	n.calls++
+	return n.MockProvider.Chat(ctx, prompt, options, streamCallback)
+}
+
+func (n *NamedProvider) Name() string  { return n.name }
+func (n *NamedProvider) Model() string { return n.model }
+
+// unreachableOllama returns an Ollama provider pointing at a closed port.
+func unreachableOllama(model string) *OllamaProvider {
+	server := httptest.NewServer(nil)
+	url := server.URL
+	server.Close()
+	return NewOllamaProviderWithConfig(model, OllamaConfig{Host: url})
+}
+
+func TestFallbackProvider_ConnectionError(t *testing.T) {
+	backup := &NamedProvider{
+		MockProvider: MockProvider{Response: `{"summary": "ok", "key_points": [], "tags": [], "assessment": {}}`},
+		name:         "openai",
+		model:        "gpt-4o-mini",
+	}
+	chain := NewFallbackProvider(unreachableOllama("qwen3:8b"), backup)
+
+	var switched []string
+	chain.OnFallback = func(failed LLMProvider, err error, streamed bool) {
+		switched = append(switched, failed.Model())
+	}
+
+	an := NewAnalyzerWithProvider(chain)
+	result, err := an.Analyze(context.Background(), "text", "", "English", 4096, nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if result.Provider != "openai" || result.Model != "gpt-4o-mini" {
+		t.Errorf("Expected result to record the answering provider, got %s/%s", result.Provider, result.Model)
+	}
+	if len(switched) != 1 || switched[0] != "qwen3:8b" {
+		t.Errorf("Expected one fallback from qwen3:8b, got %v", switched)
+	}
+
+	// Unreachable providers are skipped on later calls
+	if _, err := chain.Chat(context.Background(), "again", nil, nil); err != nil {
+		t.Fatalf("Second call failed: %v", err)
+	}
+	if len(switched) != 1 {
+		t.Errorf("Expected unreachable provider to be skipped, got %v", switched)
+	}
+}
+
+func TestFallbackProvider_InvalidJSON(t *testing.T) {
+	bad := &NamedProvider{MockProvider: MockProvider{Response: "Sorry, I cannot do that."}, name: "ollama", model: "qwen3:0.6b"}
+	good := &NamedProvider{
+		MockProvider: MockProvider{Response: `{"summary": "ok", "key_points": ["a"], "tags": ["t"], "assessment": {}}`},
+		name:         "ollama",
+		model:        "qwen3:8b",
+	}
+	an := NewAnalyzerWithProvider(NewFallbackProvider(bad, good))
+
+	result, err := an.Analyze(context.Background(), "text", "", "English", 4096, nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if result.Model != "qwen3:8b" {
+		t.Errorf("Expected qwen3:8b to answer, got %s", result.Model)
+	}
+	if bad.calls != 1 || good.calls != 1 {
+		t.Errorf("Expected one call each, got bad=%d good=%d", bad.calls, good.calls)
+	}
+
+	// Without a schema, free text is a valid answer
+	if _, err := NewFallbackProvider(bad, good).Chat(context.Background(), "translate", nil, nil); err != nil || good.calls != 1 {
+		t.Errorf("Expected plain requests not to fall back (err=%v, good calls=%d)", err, good.calls)
+	}
+}
+
+func TestFallbackProvider_StreamsLiveAndReportsSwitch(t *testing.T) {
+	bad := &NamedProvider{MockProvider: MockProvider{Response: "Sorry, I cannot"}, name: "ollama", model: "small"}
+	good := &NamedProvider{MockProvider: MockProvider{Response: `{"summary": "ok", "key_points": [], "tags": [], "assessment": {}}`}, name: "openai", model: "big"}
+
+	var streamed strings.Builder
+	onToken := func(token string) { streamed.WriteString(token) }
+	chain := NewFallbackProvider(bad, good)
+	var switched []bool
+	chain.OnFallback = func(failed LLMProvider, err error, partial bool) {
+		switched = append(switched, partial)
+		streamed.WriteString("|")
+	}
+	if _, err := NewAnalyzerWithProvider(chain).Analyze(context.Background(), "text", "", "English", 4096, onToken); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if want := bad.Response + "|" + good.Response; streamed.String() != want {
+		t.Errorf("Expected both answers streamed live around the switch, got %q", streamed.String())
+	}
+	if len(switched) != 1 || !switched[0] {
+		t.Errorf("Expected one switch reported as streamed, got %v", switched)
+	}
+
+	// Without a stream callback nothing counts as streamed
+	switched = nil
+	if _, err := chain.Chat(context.Background(), "hi", map[string]interface{}{OptionJSONSchema: AnalysisSchema}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(switched) != 1 || switched[0] {
+		t.Errorf("Expected one switch reported as not streamed, got %v", switched)
+	}
+}
+
+func TestFallbackProvider_AllFail(t *testing.T) {
+	chain := NewFallbackProvider(unreachableOllama("a"), unreachableOllama("b"))
+	_, err := chain.Chat(context.Background(), "hi", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "all providers failed") {
+		t.Fatalf("Expected combined error, got %v", err)
+	}
+}
+
+func TestFallbackProvider_CancelledContextDoesNotFallBack(t *testing.T) {
+	backup := &NamedProvider{MockProvider: MockProvider{Response: "ok"}, name: "openai", model: "gpt-4o"}
+	chain := NewFallbackProvider(unreachableOllama("a"), backup)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := chain.Chat(ctx, "hi", nil, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if backup.calls != 0 {
+		t.Errorf("Expected no fallback after cancellation, got %d calls", backup.calls)
+	}
+}
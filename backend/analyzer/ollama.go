@@ -3,34 +3,113 @@ package analyzer
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 )
 
+// DefaultOllamaHost is used when neither config nor OLLAMA_HOST specify a host.
+const DefaultOllamaHost = "http://localhost:11434"
+
 type OllamaProvider struct {
 	modelName string
-	apiURL    string
+	host      string
+	keepAlive string
+	headers   map[string]string
+	timeout   time.Duration
+	client    *http.Client
+}
+
+// OllamaConfig holds connection settings for an Ollama server.
+type OllamaConfig struct {
+	Host      string            // e.g. "http://192.168.1.20:11434"; falls back to OLLAMA_HOST, then DefaultOllamaHost
+	Timeout   time.Duration     // how long the server may stay silent (connecting, loading the model, between tokens); 0 means no limit
+	KeepAlive string            // how long the model stays loaded, e.g. "10m" or "-1"; empty uses the server default
+	Headers   map[string]string // extra headers, e.g. from OllamaAuthHeaders
 }
 
 func NewOllamaProvider(model string) *OllamaProvider {
+	return NewOllamaProviderWithConfig(model, OllamaConfig{})
+}
+
+// NewOllamaProviderWithConfig creates a provider for a local or remote Ollama server.
+func NewOllamaProviderWithConfig(model string, cfg OllamaConfig) *OllamaProvider {
 	if model == "" {
 		model = "qwen3:8b"
 	}
 	return &OllamaProvider{
 		modelName: model,
-		apiURL:    "http://localhost:11434/api/generate",
+		host:      NormalizeOllamaHost(cfg.Host),
+		keepAlive: cfg.KeepAlive,
+		headers:   cfg.Headers,
+		timeout:   cfg.Timeout,
+		// No client timeout: it would also cap a long streamed generation
+		client: &http.Client{},
+	}
+}
+
+// NormalizeOllamaHost resolves an Ollama host setting into a base URL without trailing slash.
+// It accepts the same forms as OLLAMA_HOST ("host:port", "http://host:port").
+func NormalizeOllamaHost(host string) string {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		host = strings.TrimSpace(os.Getenv("OLLAMA_HOST"))
+	}
+	if host == "" {
+		return DefaultOllamaHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
 	}
+	host = strings.TrimRight(host, "/")
+	// "0.0.0.0" is a bind address, not something we can connect to
+	return strings.Replace(host, "://0.0.0.0", "://127.0.0.1", 1)
+}
+
+// OllamaAuthHeaders builds the Authorization header for a reverse proxy in front of Ollama.
+// A bearer token takes precedence over basic auth. Returns nil when no credentials are set.
+func OllamaAuthHeaders(username, password, token string) map[string]string {
+	switch {
+	case token != "":
+		return map[string]string{"Authorization": "Bearer " + token}
+	case username != "":
+		creds := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		return map[string]string{"Authorization": "Basic " + creds}
+	default:
+		return nil
+	}
+}
+
+// Host returns the base URL of the Ollama server.
+func (p *OllamaProvider) Host() string {
+	return p.host
+}
+
+func (p *OllamaProvider) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
 }
 
 // Ollama API Structures
 type OllamaRequest struct {
-	Model   string                 `json:"model"`
-	Prompt  string                 `json:"prompt"`
-	Stream  bool                   `json:"stream"`
-	Options map[string]interface{} `json:"options,omitempty"`
+	Model     string                 `json:"model"`
+	Prompt    string                 `json:"prompt"`
+	Stream    bool                   `json:"stream"`
+	Format    interface{}            `json:"format,omitempty"` // "json" or a JSON schema
+	KeepAlive string                 `json:"keep_alive,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
 }
 
 type OllamaResponse struct {
@@ -40,23 +119,40 @@ type OllamaResponse struct {
 
 func (p *OllamaProvider) Chat(ctx context.Context, prompt string, options map[string]interface{}, streamCallback func(string)) (string, error) {
 	reqBody := OllamaRequest{
-		Model:   p.modelName,
-		Prompt:  prompt,
-		Stream:  true,
-		Options: options,
+		Model:     p.modelName,
+		Prompt:    prompt,
+		Stream:    true,
+		KeepAlive: p.keepAlive,
+	}
+	// Structured output goes into the top-level "format" field, not the model options
+	if len(options) > 0 {
+		reqBody.Options = make(map[string]interface{}, len(options))
+		for k, v := range options {
+			if k == OptionJSONSchema {
+				reqBody.Format = v
+				continue
+			}
+			reqBody.Options[k] = v
+		}
 	}
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
 		return "", err
 	}
-	req, err := http.NewRequestWithContext(ctx, "POST", p.apiURL, bytes.NewBuffer(jsonData))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	idle := p.idleTimer(cancel)
+	defer idle.Stop()
+
+	req, err := p.newRequest(ctx, "POST", "/api/generate", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("ollama request failed: %w", err)
+		return "", fmt.Errorf("ollama request to %s failed: %w", p.host, timeoutCause(ctx, err))
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
@@ -64,14 +160,14 @@ func (p *OllamaProvider) Chat(ctx context.Context, prompt string, options map[st
 		return "", fmt.Errorf("ollama error %s: %s", resp.Status, string(body))
 	}
 	var fullResponse strings.Builder
-	decoder := json.NewDecoder(resp.Body)
+	decoder := json.NewDecoder(&idleReader{r: resp.Body, timer: idle, timeout: p.timeout})
 	for {
 		var result OllamaResponse
 		if err := decoder.Decode(&result); err != nil {
 			if err == io.EOF {
 				break
 			}
-			return "", fmt.Errorf("failed to decode stream: %w", err)
+			return "", fmt.Errorf("failed to decode stream: %w", timeoutCause(ctx, err))
 		}
 		fullResponse.WriteString(result.Response)
 		if streamCallback != nil {
@@ -93,14 +189,18 @@ func (p *OllamaProvider) Model() string {
 }
 
 func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
-	tagsURL := strings.Replace(p.apiURL, "/api/generate", "/api/tags", 1)
-	req, err := http.NewRequestWithContext(ctx, "GET", tagsURL, nil)
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	req, err := p.newRequest(ctx, "GET", "/api/tags", nil)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := p.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to ollama: %w", err)
+		return nil, fmt.Errorf("failed to connect to ollama at %s: %w", p.host, err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
@@ -120,3 +220,41 @@ func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
 	}
 	return names, nil
 }
+
+// errOllamaIdle is the cancel cause of requests the server stopped answering.
+var errOllamaIdle = errors.New("no response from ollama within the timeout")
+
+// idleTimer cancels a request once the server has been silent for the configured
+// timeout. The timer never fires when no timeout is set.
+func (p *OllamaProvider) idleTimer(cancel context.CancelCauseFunc) *time.Timer {
+	if p.timeout <= 0 {
+		t := time.NewTimer(time.Hour)
+		t.Stop()
+		return t
+	}
+	return time.AfterFunc(p.timeout, func() { cancel(errOllamaIdle) })
+}
+
+// timeoutCause reports an idle timeout instead of the bare "context canceled".
+func timeoutCause(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); errors.Is(cause, errOllamaIdle) {
+		return cause
+	}
+	return err
+}
+
+// idleReader restarts the idle timer whenever data arrives, so a slow but
+// steady generation is never cut off.
+type idleReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *idleReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if n > 0 && r.timeout > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
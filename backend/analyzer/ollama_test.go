@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeOllamaHost(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "")
+	tests := map[string]string{
+		"":                        DefaultOllamaHost,
+		"192.168.1.20:11434":      "http://192.168.1.20:11434",
+		"https://ollama.lan/":     "https://ollama.lan",
+		"0.0.0.0:11434":           "http://127.0.0.1:11434",
+		" http://gpu-box:11434/ ": "http://gpu-box:11434",
+	}
+	for in, want := range tests {
+		if got := NormalizeOllamaHost(in); got != want {
+			t.Errorf("NormalizeOllamaHost(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	t.Setenv("OLLAMA_HOST", "10.0.0.5:11434")
+	if got := NormalizeOllamaHost(""); got != "http://10.0.0.5:11434" {
+		t.Errorf("Expected OLLAMA_HOST fallback, got %q", got)
+	}
+}
+
+func TestOllamaAuthHeaders(t *testing.T) {
+	if h := OllamaAuthHeaders("", "", ""); h != nil {
+		t.Errorf("Expected no headers, got %v", h)
+	}
+	if h := OllamaAuthHeaders("alice", "secret", ""); h["Authorization"] != "Basic YWxpY2U6c2VjcmV0" {
+		t.Errorf("Unexpected basic auth header: %v", h)
+	}
+	if h := OllamaAuthHeaders("alice", "secret", "tok"); h["Authorization"] != "Bearer tok" {
+		t.Errorf("Expected bearer token to take precedence, got %v", h)
+	}
+}
+
+func TestOllamaProvider_RemoteHost(t *testing.T) {
+	var gotAuth string
+	var gotReq map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/api/generate":
+			json.NewDecoder(r.Body).Decode(&gotReq)
+			w.Write([]byte(`{"response": "ok", "done": true}`))
+		case "/api/tags":
+			w.Write([]byte(`{"models": [{"name": "qwen3:8b"}, {"name": "llama3:8b"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	p := NewProvider(ProviderConfig{
+		Type:      ProviderOllama,
+		Model:     "qwen3:8b",
+		BaseURL:   server.URL,
+		Headers:   OllamaAuthHeaders("", "", "tok"),
+		KeepAlive: "10m",
+		Timeout:   5 * time.Second,
+	})
+
+	if _, err := p.Chat(context.Background(), "hi", nil, nil); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if gotReq["keep_alive"] != "10m" {
+		t.Errorf("Expected keep_alive=10m, got %v", gotReq["keep_alive"])
+	}
+	if gotAuth != "Bearer tok" {
+		t.Errorf("Expected auth header on generate, got %q", gotAuth)
+	}
+
+	gotAuth = ""
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if !reflect.DeepEqual(models, []string{"qwen3:8b", "llama3:8b"}) {
+		t.Errorf("Unexpected models: %v", models)
+	}
+	if gotAuth != "Bearer tok" {
+		t.Errorf("Expected auth header on tags, got %q", gotAuth)
+	}
+}
+
+func TestOllamaProvider_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p := NewOllamaProviderWithConfig("test", OllamaConfig{Host: server.URL, Timeout: 50 * time.Millisecond})
+	if _, err := p.Chat(context.Background(), "hi", nil, nil); err == nil {
+		t.Fatal("Expected timeout error")
+	}
+}
+
+func TestOllamaProvider_TimeoutIsIdleNotTotal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Generation takes longer than the timeout, but tokens keep arriving
+		for i := 0; i < 6; i++ {
+			fmt.Fprintf(w, `{"response": "t%d", "done": false}`+"\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(30 * time.Millisecond)
+		}
+		w.Write([]byte(`{"response": "", "done": true}`))
+	}))
+	defer server.Close()
+
+	p := NewOllamaProviderWithConfig("test", OllamaConfig{Host: server.URL, Timeout: 100 * time.Millisecond})
+	got, err := p.Chat(context.Background(), "hi", nil, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if got != "t0t1t2t3t4t5" {
+		t.Errorf("Expected the full stream, got %q", got)
+	}
+}
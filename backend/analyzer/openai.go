@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	openai "github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
 type OpenAIProvider struct {
@@ -56,6 +57,15 @@ func (p *OpenAIProvider) Chat(ctx context.Context, prompt string, options map[st
 			}
 		}
 	}
+	if schema, ok := options[OptionJSONSchema].(*jsonschema.Definition); ok && schema != nil {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "analysis_result",
+				Schema: schema,
+			},
+		}
+	}
 	stream, err := p.client.CreateChatCompletionStream(ctx, req)
 	if err != nil {
 		return "", fmt.Errorf("openai stream error: %w", err)
@@ -70,6 +80,10 @@ func (p *OpenAIProvider) Chat(ctx context.Context, prompt string, options map[st
 		if err != nil {
 			return "", fmt.Errorf("stream recv error: %w", err)
 		}
+		// Some compatible servers send a final usage chunk without choices
+		if len(response.Choices) == 0 {
+			continue
+		}
 		content := response.Choices[0].Delta.Content
 		fullResponse.WriteString(content)
 		if streamCallback != nil {
@@ -1,6 +1,9 @@
 package analyzer
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // LLMProvider defines the interface for AI backends (Ollama, OpenAI, etc.)
 type LLMProvider interface {
@@ -14,3 +17,46 @@ type LLMProvider interface {
 	// ListModels returns a list of available models from the provider
 	ListModels(ctx context.Context) ([]string, error)
 }
+
+// Provider types selectable in config.
+const (
+	ProviderOllama           = "ollama"
+	ProviderOpenAI           = "openai"
+	ProviderOpenAICompatible = "openai-compatible" // LM Studio, vLLM, any OpenAI-style server
+	ProviderLlamaCpp         = "llamacpp"          // llama.cpp llama-server
+)
+
+// ProviderConfig holds the settings needed to construct an LLMProvider.
+type ProviderConfig struct {
+	Type    string
+	Model   string
+	APIKey  string
+	BaseURL string            // OpenAI-compatible base URL or Ollama host
+	Headers map[string]string // extra HTTP headers (proxy auth etc.)
+
+	// Ollama only
+	Timeout   time.Duration
+	KeepAlive string
+}
+
+// NewProvider constructs the LLMProvider described by cfg. Unknown types fall back to Ollama.
+func NewProvider(cfg ProviderConfig) LLMProvider {
+	switch cfg.Type {
+	case ProviderOpenAI:
+		return NewOpenAIProvider(cfg.APIKey, cfg.Model)
+	case ProviderOpenAICompatible, ProviderLlamaCpp:
+		return NewOpenAICompatibleProvider(cfg.Type, cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.Headers)
+	default:
+		return NewOllamaProviderWithConfig(cfg.Model, OllamaConfig{
+			Host:      cfg.BaseURL,
+			Timeout:   cfg.Timeout,
+			KeepAlive: cfg.KeepAlive,
+			Headers:   cfg.Headers,
+		})
+	}
+}
+
+// IsOpenAICompatible reports whether the provider type uses a custom OpenAI-style endpoint.
+func IsOpenAICompatible(providerType string) bool {
+	return providerType == ProviderOpenAICompatible || providerType == ProviderLlamaCpp
+}
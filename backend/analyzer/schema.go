@@ -0,0 +1,207 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// OptionJSONSchema is the Chat option key used to request structured output.
+// The value must be a *jsonschema.Definition. Providers translate it into their
+// native mechanism (Ollama "format", OpenAI "response_format").
+const OptionJSONSchema = "json_schema"
+
+// maxRepairAttempts bounds how many times an invalid response is sent back to the model.
+const maxRepairAttempts = 2
+
+// AnalysisSchema describes the JSON object expected from analysis prompts.
+var AnalysisSchema = &jsonschema.Definition{
+	Type: jsonschema.Object,
+	Properties: map[string]jsonschema.Definition{
+		"summary": {Type: jsonschema.String},
+		"key_points": {
+			Type:  jsonschema.Array,
+			Items: &jsonschema.Definition{Type: jsonschema.String},
+		},
+		"tags": {
+			Type:  jsonschema.Array,
+			Items: &jsonschema.Definition{Type: jsonschema.String},
+		},
+		"assessment": {
+			Type: jsonschema.Object,
+			Properties: map[string]jsonschema.Definition{
+				"authenticity":  {Type: jsonschema.String},
+				"effectiveness": {Type: jsonschema.String},
+				"timeliness":    {Type: jsonschema.String},
+				"alternatives":  {Type: jsonschema.String},
+			},
+			// Custom prompts may use their own dimensions, so only value types are enforced.
+			AdditionalProperties: jsonschema.Definition{Type: jsonschema.String},
+		},
+	},
+	Required: []string{"summary", "key_points", "tags", "assessment"},
+}
+
+// CustomAnalysisSchema is used for custom prompts, which may ask for a different
+// shape: only the summary is required, and known fields must have the right type.
+var CustomAnalysisSchema = &jsonschema.Definition{
+	Type:       jsonschema.Object,
+	Properties: AnalysisSchema.Properties,
+	Required:   []string{"summary"},
+}
+
+// analysisSchema returns the schema responses to a prompt must match.
+func analysisSchema(customPrompt string) *jsonschema.Definition {
+	if customPrompt != "" {
+		return CustomAnalysisSchema
+	}
+	return AnalysisSchema
+}
+
+// InvalidOutputError is returned when the model keeps producing output that does
+// not match the analysis schema after all repair attempts.
+type InvalidOutputError struct {
+	Attempts int    // total number of model calls made
+	Raw      string // last raw response
+	Err      error  // last parse or validation error
+}
+
+func (e *InvalidOutputError) Error() string {
+	return fmt.Sprintf("model returned invalid analysis JSON after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *InvalidOutputError) Unwrap() error {
+	return e.Err
+}
+
+// schemaError is returned when a response is not JSON or does not match the requested schema.
+type schemaError struct {
+	msg string
+	err error
+}
+
+func (e *schemaError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("%s: %v", e.msg, e.err)
+	}
+	return e.msg
+}
+
+func (e *schemaError) Unwrap() error {
+	return e.err
+}
+
+// extractJSON returns the outermost JSON object in a model response.
+func extractJSON(responseText string) (string, error) {
+	// Clean up markdown code blocks if the LLM wrapped the output
+	start := strings.Index(responseText, "{")
+	end := strings.LastIndex(responseText, "}")
+	if start == -1 || end < start {
+		return "", &schemaError{msg: "no JSON object found in response"}
+	}
+	return responseText[start : end+1], nil
+}
+
+// validateJSONResponse checks that the response contains a JSON object matching schema.
+func validateJSONResponse(schema jsonschema.Definition, responseText string) error {
+	raw, err := extractJSON(responseText)
+	if err != nil {
+		return err
+	}
+	var generic interface{}
+	if err := json.Unmarshal([]byte(raw), &generic); err != nil {
+		return &schemaError{msg: "invalid JSON", err: err}
+	}
+	if problems := validateSchema(schema, generic, "$"); len(problems) > 0 {
+		return &schemaError{msg: "schema validation failed: " + strings.Join(problems, "; ")}
+	}
+	return nil
+}
+
+// ParseAnalysis extracts the JSON object from a model response and validates it
+// against AnalysisSchema.
+func ParseAnalysis(responseText string) (*AnalysisResult, error) {
+	return parseAnalysis(AnalysisSchema, responseText)
+}
+
+func parseAnalysis(schema *jsonschema.Definition, responseText string) (*AnalysisResult, error) {
+	if err := validateJSONResponse(*schema, responseText); err != nil {
+		return nil, err
+	}
+	raw, _ := extractJSON(responseText)
+
+	var analysis AnalysisResult
+	if err := json.Unmarshal([]byte(raw), &analysis); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if strings.TrimSpace(analysis.Summary) == "" {
+		return nil, &schemaError{msg: "schema validation failed: $.summary must not be empty"}
+	}
+	return &analysis, nil
+}
+
+// validateSchema returns human readable violations, suitable for feeding back to the model.
+func validateSchema(schema jsonschema.Definition, data interface{}, path string) []string {
+	var problems []string
+	switch schema.Type {
+	case jsonschema.Object:
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s must be an object", path)}
+		}
+		for _, field := range schema.Required {
+			if _, exists := obj[field]; !exists {
+				problems = append(problems, fmt.Sprintf("%s.%s is required", path, field))
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propSchema, known := schema.Properties[key]
+			if !known {
+				switch extra := schema.AdditionalProperties.(type) {
+				case bool:
+					if !extra {
+						problems = append(problems, fmt.Sprintf("%s.%s is not allowed", path, key))
+					}
+				case jsonschema.Definition:
+					problems = append(problems, validateSchema(extra, obj[key], path+"."+key)...)
+				}
+				continue
+			}
+			problems = append(problems, validateSchema(propSchema, obj[key], path+"."+key)...)
+		}
+	case jsonschema.Array:
+		arr, ok := data.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s must be an array", path)}
+		}
+		if schema.Items != nil {
+			for i, item := range arr {
+				problems = append(problems, validateSchema(*schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case jsonschema.String:
+		if _, ok := data.(string); !ok {
+			problems = append(problems, fmt.Sprintf("%s must be a string", path))
+		}
+	}
+	return problems
+}
+
+// repairPrompt asks the model to fix its previous answer.
+func repairPrompt(original, response string, parseErr error) string {
+	return fmt.Sprintf(`%s
+
+Your previous response was:
+%s
+
+That response is invalid: %v
+Return ONLY the corrected JSON object that matches the required structure exactly. Do not add any other text.`, original, response, parseErr)
+}
@@ -0,0 +1,156 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// SequenceProvider returns the given responses in order, repeating the last one.
+type SequenceProvider struct {
+	MockProvider
+	Responses []string
+	Prompts   []string
+	Options   []map[string]interface{}
+}
+
+func (s *SequenceProvider) Chat(ctx context.Context, prompt string, options map[string]interface{}, streamCallback func(string)) (string, error) {
+	s.Prompts = append(s.Prompts, prompt)
+	s.Options = append(s.Options, options)
+	idx := len(s.Prompts) - 1
+	if idx >= len(s.Responses) {
+		idx = len(s.Responses) - 1
+	}
+	return s.Responses[idx], nil
+}
+
+func TestParseAnalysis(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		wantErr  string
+	}{
+		{"valid in code fence", "```json\n{\"summary\": \"S\", \"key_points\": [], \"tags\": [], \"assessment\": {}}\n```", ""},
+		{"no json", "I cannot do that.", "no JSON object"},
+		{"broken json", `{"summary": "S", "key_points": [}`, "invalid JSON"},
+		{"missing field", `{"summary": "S", "key_points": [], "tags": []}`, "$.assessment is required"},
+		{"wrong type", `{"summary": "S", "key_points": "one", "tags": [], "assessment": {}}`, "$.key_points must be an array"},
+		{"empty summary", `{"summary": " ", "key_points": [], "tags": [], "assessment": {}}`, "summary must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAnalysis(tt.response)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAnalyze_RepairsInvalidJSON(t *testing.T) {
+	seq := &SequenceProvider{Responses: []string{
+		`{"summary": "S", "key_points": "oops"}`,
+		`{"summary": "Fixed", "key_points": ["A"], "tags": [], "assessment": {}}`,
+	}}
+	an := &Analyzer{provider: seq}
+
+	result, err := an.Analyze(context.Background(), "text", "", "English", 4096, nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if result.Summary != "Fixed" {
+		t.Errorf("Expected repaired summary, got %q", result.Summary)
+	}
+	if len(seq.Prompts) != 2 {
+		t.Fatalf("Expected 2 calls, got %d", len(seq.Prompts))
+	}
+	if !strings.Contains(seq.Prompts[1], "$.key_points must be an array") {
+		t.Error("Expected repair prompt to include the validation error")
+	}
+	if seq.Options[0][OptionJSONSchema] != AnalysisSchema {
+		t.Error("Expected the analysis schema to be requested from the provider")
+	}
+}
+
+func TestAnalyze_TypedErrorAfterRetries(t *testing.T) {
+	seq := &SequenceProvider{Responses: []string{"not json at all"}}
+	an := &Analyzer{provider: seq}
+
+	_, err := an.Analyze(context.Background(), "text", "", "English", 4096, nil)
+	var invalid *InvalidOutputError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected InvalidOutputError, got %v", err)
+	}
+	if invalid.Attempts != maxRepairAttempts+1 || len(seq.Prompts) != maxRepairAttempts+1 {
+		t.Errorf("Expected %d attempts, got %d (calls: %d)", maxRepairAttempts+1, invalid.Attempts, len(seq.Prompts))
+	}
+	if invalid.Raw != "not json at all" {
+		t.Errorf("Expected raw response to be kept, got %q", invalid.Raw)
+	}
+}
+
+func TestOllamaProvider_SendsFormat(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"response": "{}", "done": true}`))
+	}))
+	defer server.Close()
+
+	p := NewOllamaProviderWithConfig("test", OllamaConfig{Host: server.URL})
+
+	_, err := p.Chat(context.Background(), "hi", map[string]interface{}{
+		"num_ctx":        4096,
+		OptionJSONSchema: AnalysisSchema,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	format, ok := got["format"].(map[string]interface{})
+	if !ok || format["type"] != "object" {
+		t.Fatalf("Expected JSON schema in format field, got %v", got["format"])
+	}
+	opts := got["options"].(map[string]interface{})
+	if _, leaked := opts[OptionJSONSchema]; leaked {
+		t.Error("Schema must not be sent as a model option")
+	}
+	if opts["num_ctx"] != float64(4096) {
+		t.Errorf("Expected num_ctx option to be kept, got %v", opts["num_ctx"])
+	}
+}
+
+func TestAnalyze_CustomPromptOnlyRequiresSummary(t *testing.T) {
+	seq := &SequenceProvider{Responses: []string{
+		`{"summary": "S", "questions": ["Why?"], "rating": "4/5"}`,
+	}}
+	an := &Analyzer{provider: seq}
+
+	result, err := an.Analyze(context.Background(), "text", "Summarize and list open questions as JSON.", "English", 4096, nil)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if result.Summary != "S" || len(seq.Prompts) != 1 {
+		t.Errorf("Expected the custom shape to be accepted on the first attempt, got %q after %d calls", result.Summary, len(seq.Prompts))
+	}
+	if seq.Options[0][OptionJSONSchema] != CustomAnalysisSchema {
+		t.Error("Expected the relaxed schema to be requested for custom prompts")
+	}
+
+	seq = &SequenceProvider{Responses: []string{`{"answer": "no summary"}`}}
+	an = &Analyzer{provider: seq}
+	if _, err := an.Analyze(context.Background(), "text", "Custom", "English", 4096, nil); err == nil || !strings.Contains(err.Error(), "$.summary is required") {
+		t.Errorf("Expected a missing summary to fail, got %v", err)
+	}
+}
@@ -5,20 +5,22 @@ import (
 	"Varys/backend/config"
 	"Varys/backend/dependency"
 	"Varys/backend/downloader"
+	"Varys/backend/queue"
 	"Varys/backend/service"
 	"Varys/backend/storage"
 	"Varys/backend/transcriber"
 	"Varys/backend/translation"
+	"Varys/backend/ytdlp"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"sync"
 	"time"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
@@ -36,9 +38,9 @@ type App struct {
 	translator     *translation.Translator
 	coreService    *service.CoreService
 
-	// Task Management
-	taskMutex  sync.Mutex
-	taskCancel context.CancelFunc
+	// Job queue (see jobs.go)
+	queue       *queue.Queue
+	queueCancel context.CancelFunc
 }
 
 // NewApp creates a new App application struct
@@ -82,7 +84,7 @@ func (a *App) Startup(ctx context.Context) {
 	a.storageManager = storage.NewManager(vaultPath)
 
 	// Downloader
-	a.downloader = downloader.NewDownloader(a.depManager)
+	a.downloader = downloader.NewDownloaderWithCookies(a.depManager, cfg.YtDlpCookies())
 
 	// Transcriber (Model passed dynamically now)
 	a.transcriber = transcriber.NewTranscriber(a.depManager)
@@ -105,157 +107,31 @@ func (a *App) Startup(ctx context.Context) {
 			analyzerModel = "gpt-4o"
 		}
 	}
+	opts := service.OptionsFromConfig(cfg)
+	if analyzer.IsOpenAICompatible(aiProvider) {
+		analyzerModel = opts.AnalysisModel()
+	}
 
-	a.analyzer = analyzer.NewAnalyzer(aiProvider, cfg.OpenAIKey, analyzerModel)
+	a.analyzer = analyzer.NewAnalyzerFromConfig(opts.ProviderConfig(analyzerModel))
 	
 	// Create a dedicated provider for translation
-	translationProvider := analyzer.NewAnalyzer(aiProvider, cfg.OpenAIKey, translationModel).GetProvider()
+	translationProvider := analyzer.NewProvider(opts.ProviderConfig(translationModel))
 	if aiProvider == "openai" && translationModel == "qwen3:0.6b" {
 		// Fallback to small cloud model if local model name is still at default while using OpenAI
 		translationProvider = analyzer.NewAnalyzer("openai", cfg.OpenAIKey, "gpt-4o-mini").GetProvider()
 	}
+	if analyzer.IsOpenAICompatible(aiProvider) && translationModel == "qwen3:0.6b" {
+		translationProvider = analyzer.NewProvider(opts.ProviderConfig(cfg.CompatibleModel))
+	}
 	a.translator = translation.NewTranslator(translationProvider)
 
 	// Initialize Core Service
 	a.coreService = service.NewCoreService(a.depManager)
 
-	wailsRuntime.LogInfo(a.ctx, "Backend initialized successfully.")
-}
-
-// CancelTask cancels the currently running task
-func (a *App) CancelTask() {
-	a.taskMutex.Lock()
-	defer a.taskMutex.Unlock()
-	if a.taskCancel != nil {
-		a.taskCancel()
-		a.taskCancel = nil
-		wailsRuntime.LogInfo(a.ctx, "Task cancellation requested.")
-	}
-}
-
-// WailsLogger implements service.EventLogger for the desktop app.
-type WailsLogger struct {
-	app       *App
-	ctx       context.Context
-	logBuffer []string
-	hasError  bool
-}
-
-func (l *WailsLogger) Log(msg string) {
-	if l.ctx.Err() != nil {
-		return
-	}
-	l.logBuffer = append(l.logBuffer, fmt.Sprintf("[%s] %s", time.Now().Format("15:04:05"), msg))
-	wailsRuntime.EventsEmit(l.app.ctx, "task:log", msg)
-
-	msgLower := strings.ToLower(msg)
-	if strings.Contains(msgLower, "error") || strings.Contains(msgLower, "failed") {
-		l.hasError = true
-	}
-}
-
-func (l *WailsLogger) Progress(percentage float64) {
-	if l.ctx.Err() == nil {
-		wailsRuntime.EventsEmit(l.app.ctx, "task:progress", percentage)
-	}
-}
-
-func (l *WailsLogger) AnalysisChunk(token string) {
-	if l.ctx.Err() == nil {
-		wailsRuntime.EventsEmit(l.app.ctx, "task:analysis", token)
-	}
-}
-
-func (l *WailsLogger) Error(err error) {
-	l.Log(fmt.Sprintf("Error: %v", err))
-}
-
-// SubmitTask starts the pipeline
-func (a *App) SubmitTask(url string, audioOnly bool) (taskResult string, taskErr error) {
-	wailsRuntime.LogInfo(a.ctx, fmt.Sprintf("Received task for URL: %s (AudioOnly: %v)", url, audioOnly))
-
-	// Setup Cancellation Context
-	a.taskMutex.Lock()
-	if a.taskCancel != nil {
-		a.taskCancel()
-	}
-	ctx, cancel := context.WithCancel(a.ctx)
-	a.taskCancel = cancel
-	a.taskMutex.Unlock()
-
-	defer func() {
-		a.taskMutex.Lock()
-		if a.taskCancel != nil {
-			a.taskCancel()
-			a.taskCancel = nil
-		}
-		a.taskMutex.Unlock()
-	}()
-
-	logger := &WailsLogger{app: a, ctx: ctx}
-
-	// Dump logs on exit if error occurred (and not cancelled)
-	defer func() {
-		if ctx.Err() == context.Canceled {
-			logger.Log("Task cancelled by user.")
-			return
-		}
-		if taskErr != nil || logger.hasError {
-			timestamp := time.Now().Format("2006-01-02_15-04-05")
-			logContent := strings.Join(logger.logBuffer, "\n")
-			if taskErr != nil {
-				logContent += fmt.Sprintf("\n\n[FATAL ERROR] %v", taskErr)
-			}
-
-			logDir, err := config.GetLogDir()
-			if err != nil {
-				// Fallback to current dir if system log dir fails
-				logDir = "logs"
-			}
-			os.MkdirAll(logDir, 0755)
-
-			filename := fmt.Sprintf("error_%s.log", timestamp)
-			filePath := filepath.Join(logDir, filename)
-			os.WriteFile(filePath, []byte(logContent), 0644)
-
-			latestPath := filepath.Join(logDir, "error_latest.log")
-			os.WriteFile(latestPath, []byte(logContent), 0644)
-
-			logger.Log(fmt.Sprintf("Logs dumped to %s", filePath))
-		}
-	}()
-
-	// Load latest config
-	cfg := a.loadConfigSafe()
+	// Job Queue (restores jobs left over from the last session)
+	a.startQueue(cfg)
 
-	opts := service.Options{
-		AudioOnly:      audioOnly,
-		ModelPath:      cfg.ModelPath,
-		LLMModel:       cfg.LLMModel,
-		TranslationMod: cfg.TranslationModel,
-		AIProvider:     cfg.AIProvider,
-		OpenAIKey:      cfg.OpenAIKey,
-		OpenAIModel:    cfg.OpenAIModel,
-		TargetLanguage: cfg.TargetLanguage,
-		ContextSize:    cfg.ContextSize,
-		CustomPrompt:   cfg.CustomPrompt,
-		VaultPath:      cfg.VaultPath,
-	}
-
-	if opts.ContextSize == 0 {
-		opts.ContextSize = 8192
-	}
-
-	result, err := a.coreService.ProcessTask(ctx, url, opts, logger)
-	if err != nil {
-		if ctx.Err() != nil {
-			return "", ctx.Err()
-		}
-		taskErr = err
-		return "", err
-	}
-
-	return fmt.Sprintf("Saved to: %s", result.NotePath), nil
+	wailsRuntime.LogInfo(a.ctx, "Backend initialized successfully.")
 }
 
 // GetAppVersion returns the current application version
@@ -431,6 +307,8 @@ func (a *App) GetStartupDiagnostics() StartupDiagnostics {
 		[]string{"brew install yt-dlp"},
 	))
 
+	addItem(buildCookiesItem(cfg.YtDlpCookies()))
+
 	addItem(buildBinaryItem(
 		"ffmpeg",
 		"ffmpeg",
@@ -451,6 +329,20 @@ func (a *App) GetStartupDiagnostics() StartupDiagnostics {
 		[]string{"brew install whisper-cpp"},
 	))
 
+	pdftotextOk := false
+	if a.depManager != nil {
+		_, pdftotextOk = a.depManager.CheckSystemDependency("pdftotext")
+	}
+	addItem(buildBinaryItem(
+		"pdftotext",
+		"pdftotext (poppler)",
+		pdftotextOk,
+		[]string{"documents"},
+		false,
+		"Install poppler to ingest PDF files.",
+		[]string{"brew install poppler"},
+	))
+
 	modelPath := strings.TrimSpace(cfg.ModelPath)
 	modelOk := modelPath != ""
 	if modelOk {
@@ -470,9 +362,10 @@ func (a *App) GetStartupDiagnostics() StartupDiagnostics {
 	))
 
 	ollamaBlocker := provider == "ollama"
-	ollamaRunning := checkOllamaRunning()
-	addItem(buildOllamaItem(depStatus.Ollama, ollamaRunning, ollamaBlocker))
-	addItem(buildOllamaModelsItem(ollamaRunning, ollamaBlocker))
+	ollama := newOllamaProbe(cfg)
+	ollamaRunning := checkOllamaRunning(ollama)
+	addItem(buildOllamaItem(depStatus.Ollama, ollamaRunning, ollamaBlocker, ollama.Host()))
+	addItem(buildOllamaModelsItem(ollama, ollamaRunning, ollamaBlocker))
 
 	vaultPath := strings.TrimSpace(cfg.VaultPath)
 	addItem(buildConfigItem(
@@ -499,6 +392,8 @@ func (a *App) GetStartupDiagnostics() StartupDiagnostics {
 		[]string{"Enter your OpenAI API key in Settings."},
 	))
 
+	addItem(buildCompatibleItem(cfg, analyzer.IsOpenAICompatible(provider)))
+
 	diag.Ready = len(diag.Blockers) == 0
 	return diag
 }
@@ -546,7 +441,7 @@ func buildConfigItem(id, name string, ok bool, requiredFor []string, blockerIfBa
 	return item
 }
 
-func buildOllamaItem(installed bool, running bool, blockerIfBad bool) DiagnosticItem {
+func buildOllamaItem(installed bool, running bool, blockerIfBad bool, host string) DiagnosticItem {
 	item := DiagnosticItem{
 		ID:          "ollama",
 		Name:        "Ollama",
@@ -554,6 +449,23 @@ func buildOllamaItem(installed bool, running bool, blockerIfBad bool) Diagnostic
 		CanAutoFix:  false,
 	}
 
+	if !isLocalOllamaHost(host) {
+		// A remote server does not need a local binary
+		item.DetectedPath = host
+		if running {
+			item.Status = "ok"
+			item.IsBlocker = false
+			item.FixSuggestion = "Remote Ollama server is reachable."
+			item.FixCommands = []string{}
+			return item
+		}
+		item.Status = "misconfigured"
+		item.IsBlocker = blockerIfBad
+		item.FixSuggestion = fmt.Sprintf("The Ollama server at %s is not reachable. Check the host, proxy credentials and network.", host)
+		item.FixCommands = []string{"curl " + host + "/api/tags"}
+		return item
+	}
+
 	if !installed {
 		item.Status = "missing"
 		item.IsBlocker = blockerIfBad
@@ -579,9 +491,9 @@ func buildOllamaItem(installed bool, running bool, blockerIfBad bool) Diagnostic
 	return item
 }
 
-func buildOllamaModelsItem(ollamaRunning bool, blockerIfBad bool) DiagnosticItem {
+func buildOllamaModelsItem(ollama *analyzer.OllamaProvider, ollamaRunning bool, blockerIfBad bool) DiagnosticItem {
 	modelsPath := getOllamaModelsPath()
-	models, _ := getOllamaModelsFromAPI(ollamaRunning)
+	models, _ := getOllamaModelsFromAPI(ollama, ollamaRunning)
 	hasModels := len(models) > 0 || hasAnyModelFiles(modelsPath)
 
 	item := DiagnosticItem{
@@ -609,6 +521,127 @@ func buildOllamaModelsItem(ollamaRunning bool, blockerIfBad bool) DiagnosticItem
 	return item
 }
 
+// buildCookiesItem checks that the configured yt-dlp cookie source can be used.
+// A missing browser profile is reported but does not block, since yt-dlp may find it elsewhere.
+func buildCookiesItem(cookies ytdlp.Cookies) DiagnosticItem {
+	item := DiagnosticItem{
+		ID:          "cookies",
+		Name:        "yt-dlp Cookies",
+		RequiredFor: []string{"download"},
+		CanAutoFix:  false,
+	}
+
+	if err := cookies.Validate(); err != nil {
+		item.Status = "misconfigured"
+		item.IsBlocker = true
+		item.FixSuggestion = fmt.Sprintf("Invalid cookie settings: %v.", err)
+		item.FixCommands = []string{"In Settings, choose a supported browser, a cookies.txt file, or no cookies."}
+		return item
+	}
+
+	switch cookies.Source {
+	case ytdlp.CookiesNone:
+		item.Status = "ok"
+		item.FixSuggestion = "Cookies are disabled. Age-restricted or members-only videos may fail to download."
+		item.FixCommands = []string{}
+	case ytdlp.CookiesFile:
+		item.DetectedPath = cookies.File
+		if st, err := os.Stat(cookies.File); err != nil || st.IsDir() {
+			item.Status = "missing"
+			item.IsBlocker = true
+			item.FixSuggestion = "The cookies file does not exist. Export cookies.txt from your browser or choose another source."
+			item.FixCommands = []string{"yt-dlp --cookies-from-browser firefox --cookies cookies.txt --skip-download <url>"}
+			return item
+		}
+		item.Status = "ok"
+		item.FixSuggestion = "Cookies are read from the cookies file."
+		item.FixCommands = []string{}
+	default:
+		browser := cookies.Browser
+		if browser == "" {
+			browser = ytdlp.DefaultBrowser
+		}
+		dir := ytdlp.BrowserProfileDir(browser)
+		item.DetectedPath = dir
+		if dir == "" {
+			item.Status = "misconfigured"
+			item.FixSuggestion = fmt.Sprintf("No %s profile was found on this machine, so yt-dlp will fail to read cookies. Pick an installed browser, a cookies.txt file, or disable cookies.", browser)
+			item.FixCommands = []string{"In Settings, change the cookie source."}
+			return item
+		}
+		item.Status = "ok"
+		item.FixSuggestion = fmt.Sprintf("Cookies are read from %s.", browser)
+		item.FixCommands = []string{}
+	}
+	return item
+}
+
+// buildCompatibleItem checks that the OpenAI-compatible server is configured and answers /models.
+func buildCompatibleItem(cfg *config.Config, blockerIfBad bool) DiagnosticItem {
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.CompatibleBaseURL), "/")
+	if baseURL == "" && cfg.AIProvider == analyzer.ProviderLlamaCpp {
+		baseURL = analyzer.DefaultLlamaCppBaseURL
+	}
+
+	item := DiagnosticItem{
+		ID:           "openai_compatible",
+		Name:         "OpenAI-Compatible Server",
+		RequiredFor:  []string{"analyze", "translate"},
+		DetectedPath: baseURL,
+		CanAutoFix:   false,
+	}
+
+	if baseURL == "" {
+		item.Status = "missing"
+		item.IsBlocker = blockerIfBad
+		item.FixSuggestion = "Enter the base URL of your OpenAI-compatible server in Settings (e.g. http://localhost:1234/v1)."
+		item.FixCommands = []string{"In Settings, fill in the Base URL field."}
+		return item
+	}
+
+	if !checkCompatibleServer(baseURL, cfg.CompatibleKey, cfg.CompatibleHeaders) {
+		item.Status = "misconfigured"
+		item.IsBlocker = blockerIfBad
+		item.FixSuggestion = "The OpenAI-compatible server did not respond. Start it and verify the base URL."
+		item.FixCommands = []string{"llama-server -m <model.gguf> --port 8080"}
+		return item
+	}
+
+	if strings.TrimSpace(cfg.CompatibleModel) == "" {
+		item.Status = "misconfigured"
+		item.IsBlocker = blockerIfBad
+		item.FixSuggestion = "The server is reachable but no model is selected. Choose a model in Settings."
+		item.FixCommands = []string{"In Settings, select a model from the list."}
+		return item
+	}
+
+	item.Status = "ok"
+	item.IsBlocker = false
+	item.FixSuggestion = "OpenAI-compatible server is reachable."
+	item.FixCommands = []string{}
+	return item
+}
+
+func checkCompatibleServer(baseURL, apiKey string, headers map[string]string) bool {
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/models", nil)
+	if err != nil {
+		return false
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	client := http.Client{Timeout: 1500 * time.Millisecond}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
 func findBinaryPath(name string) string {
 	path, err := exec.LookPath(name)
 	if err != nil {
@@ -617,14 +650,29 @@ func findBinaryPath(name string) string {
 	return path
 }
 
-func checkOllamaRunning() bool {
-	client := http.Client{Timeout: 1500 * time.Millisecond}
-	resp, err := client.Get("http://127.0.0.1:11434/api/tags")
+// newOllamaProbe builds an Ollama client from config for health checks.
+func newOllamaProbe(cfg *config.Config) *analyzer.OllamaProvider {
+	conn := service.OptionsFromConfig(cfg).Ollama
+	conn.Timeout = 1500 * time.Millisecond
+	return analyzer.NewOllamaProviderWithConfig("", conn)
+}
+
+// isLocalOllamaHost reports whether the host points at this machine, where we can start/stop ollama.
+func isLocalOllamaHost(host string) bool {
+	u, err := url.Parse(host)
 	if err != nil {
 		return false
 	}
-	defer resp.Body.Close()
-	return resp.StatusCode >= 200 && resp.StatusCode < 300
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+func checkOllamaRunning(ollama *analyzer.OllamaProvider) bool {
+	_, err := ollama.ListModels(context.Background())
+	return err == nil
 }
 
 func getOllamaModelsPath() string {
@@ -638,33 +686,18 @@ func getOllamaModelsPath() string {
 	return filepath.Join(home, ".ollama", "models")
 }
 
-type ollamaTagsResponse struct {
-	Models []struct {
-		Name string `json:"name"`
-	} `json:"models"`
-}
-
-func getOllamaModelsFromAPI(ollamaRunning bool) ([]string, error) {
+func getOllamaModelsFromAPI(ollama *analyzer.OllamaProvider, ollamaRunning bool) ([]string, error) {
 	if !ollamaRunning {
 		return nil, nil
 	}
-	client := http.Client{Timeout: 1500 * time.Millisecond}
-	resp, err := client.Get("http://127.0.0.1:11434/api/tags")
+	models, err := ollama.ListModels(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
-	}
-	var parsed ollamaTagsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
-		return nil, err
-	}
-	result := make([]string, 0, len(parsed.Models))
-	for _, m := range parsed.Models {
-		if strings.TrimSpace(m.Name) != "" {
-			result = append(result, m.Name)
+	result := make([]string, 0, len(models))
+	for _, m := range models {
+		if strings.TrimSpace(m) != "" {
+			result = append(result, m)
 		}
 	}
 	return result, nil
@@ -697,6 +730,7 @@ func defaultConfig() *config.Config {
 		TranslationModel: "qwen3:0.6b",
 		AIProvider:       "ollama",
 		OpenAIModel:      "gpt-4o",
+		AnalysisMode:     "auto",
 	}
 }
 
@@ -728,7 +762,11 @@ func (a *App) StartOllamaService() (string, error) {
 		return "", fmt.Errorf("ollama binary not found in PATH")
 	}
 
-	if checkOllamaRunning() {
+	ollama := newOllamaProbe(a.loadConfigSafe())
+	if !isLocalOllamaHost(ollama.Host()) {
+		return "", fmt.Errorf("ollama is configured to run on %s; start it on that machine", ollama.Host())
+	}
+	if checkOllamaRunning(ollama) {
 		return "ollama is already running", nil
 	}
 
@@ -743,7 +781,7 @@ func (a *App) StartOllamaService() (string, error) {
 
 	deadline := time.Now().Add(6 * time.Second)
 	for time.Now().Before(deadline) {
-		if checkOllamaRunning() {
+		if checkOllamaRunning(ollama) {
 			return "ollama started successfully", nil
 		}
 		time.Sleep(300 * time.Millisecond)
@@ -754,7 +792,11 @@ func (a *App) StartOllamaService() (string, error) {
 
 // StopOllamaService tries to stop ollama server processes.
 func (a *App) StopOllamaService() (string, error) {
-	if !checkOllamaRunning() {
+	ollama := newOllamaProbe(a.loadConfigSafe())
+	if !isLocalOllamaHost(ollama.Host()) {
+		return "", fmt.Errorf("ollama is configured to run on %s; stop it on that machine", ollama.Host())
+	}
+	if !checkOllamaRunning(ollama) {
 		return "ollama is already stopped", nil
 	}
 
@@ -766,7 +808,7 @@ func (a *App) StopOllamaService() (string, error) {
 
 	deadline := time.Now().Add(6 * time.Second)
 	for time.Now().Before(deadline) {
-		if !checkOllamaRunning() {
+		if !checkOllamaRunning(ollama) {
 			return "ollama stopped successfully", nil
 		}
 		time.Sleep(300 * time.Millisecond)
@@ -857,13 +899,33 @@ func (a *App) UpdateConfig(cfg config.Config) error {
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
-	return a.cfgManager.Save(&cfg)
+	if _, err := service.OptionsFromConfig(&cfg).LoadNoteTemplate(); err != nil {
+		return err
+	}
+	if err := a.cfgManager.Save(&cfg); err != nil {
+		return err
+	}
+	if a.queue != nil {
+		a.queue.SetWorkers(cfg.QueueWorkers)
+	}
+	return nil
+}
+
+// GetNoteTemplates returns the names of the built-in note templates.
+func (a *App) GetNoteTemplates() []string {
+	return storage.BuiltinTemplates()
 }
 
 // GetAIModels fetches available models from the selected AI provider
 func (a *App) GetAIModels(providerType, apiKey string) ([]string, error) {
-	an := analyzer.NewAnalyzer(providerType, apiKey, "")
-	return an.ListModels(a.ctx)
+	// Connection settings (base URL, headers, Ollama host) are only kept in config
+	opts := service.OptionsFromConfig(a.loadConfigSafe())
+	opts.AIProvider = providerType
+	pc := opts.ProviderConfig("")
+	if apiKey != "" {
+		pc.APIKey = apiKey
+	}
+	return analyzer.NewProvider(pc).ListModels(a.ctx)
 }
 
 // YtDlpUpdateInfo holds information about yt-dlp version status.
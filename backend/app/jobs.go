@@ -0,0 +1,267 @@
+package app
+
+import (
+	"Varys/backend/config"
+	"Varys/backend/downloader"
+	"Varys/backend/queue"
+	"Varys/backend/service"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Job events emitted to the frontend:
+//
+//	job:update   queue.Job                        status, progress or result changed
+//	job:log      {"job_id": ..., "message": ...}  one log line
+//	job:analysis {"job_id": ..., "message": ...}  streamed analysis token
+const (
+	eventJobUpdate   = "job:update"
+	eventJobLog      = "job:log"
+	eventJobAnalysis = "job:analysis"
+)
+
+// JobMessage is the payload of job:log and job:analysis events.
+type JobMessage struct {
+	JobID   string `json:"job_id"`
+	Message string `json:"message"`
+}
+
+// startQueue restores persisted jobs and starts the workers.
+func (a *App) startQueue(cfg *config.Config) {
+	path := ""
+	if dir, err := config.GetConfigDir(); err == nil {
+		path = filepath.Join(dir, "jobs.json")
+	}
+
+	a.queue = queue.New(path, cfg.QueueWorkers, a.runJob)
+	a.queue.OnEvent = a.emitJobEvent
+	if err := a.queue.Load(); err != nil {
+		wailsRuntime.LogErrorf(a.ctx, "Error restoring job queue: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.queueCancel = cancel
+	a.queue.Start(ctx)
+}
+
+// Shutdown stops running jobs. They are queued again on the next start.
+func (a *App) Shutdown(ctx context.Context) {
+	if a.queueCancel != nil {
+		a.queueCancel()
+		a.queue.Wait()
+	}
+}
+
+func (a *App) emitJobEvent(e queue.Event) {
+	switch e.Type {
+	case queue.EventUpdate:
+		wailsRuntime.EventsEmit(a.ctx, eventJobUpdate, e.Job)
+	case queue.EventLog:
+		wailsRuntime.EventsEmit(a.ctx, eventJobLog, JobMessage{JobID: e.JobID, Message: e.Message})
+	case queue.EventAnalysis:
+		wailsRuntime.EventsEmit(a.ctx, eventJobAnalysis, JobMessage{JobID: e.JobID, Message: e.Message})
+	}
+}
+
+// EnqueueTask queues a single URL, local file or playlist. Playlist and channel URLs
+// are expanded into one note per entry plus an index note.
+func (a *App) EnqueueTask(url string, audioOnly bool) (queue.Job, error) {
+	if a.queue == nil {
+		return queue.Job{}, fmt.Errorf("job queue not initialized")
+	}
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return queue.Job{}, fmt.Errorf("url is required")
+	}
+	wailsRuntime.LogInfo(a.ctx, fmt.Sprintf("Queued URL: %s (AudioOnly: %v)", url, audioOnly))
+	return a.queue.Add(url, audioOnly, nil), nil
+}
+
+// EnqueueTasks queues several URLs at once. Blank lines are skipped.
+func (a *App) EnqueueTasks(urls []string, audioOnly bool) ([]queue.Job, error) {
+	var jobs []queue.Job
+	for _, url := range urls {
+		if strings.TrimSpace(url) == "" {
+			continue
+		}
+		job, err := a.EnqueueTask(url, audioOnly)
+		if err != nil {
+			return jobs, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// EnqueuePlaylist queues a playlist or channel with filters.
+// after/before are YYYY-MM-DD dates; empty values and zero disable a filter.
+func (a *App) EnqueuePlaylist(url string, audioOnly bool, after, before string, maxItems int, titlePattern string) (queue.Job, error) {
+	if a.queue == nil {
+		return queue.Job{}, fmt.Errorf("job queue not initialized")
+	}
+	// Validate now so mistakes are reported before the job is queued
+	if _, err := downloader.ParsePlaylistFilter(after, before, maxItems, titlePattern); err != nil {
+		return queue.Job{}, err
+	}
+	return a.queue.Add(strings.TrimSpace(url), audioOnly, &queue.PlaylistOptions{
+		After:        after,
+		Before:       before,
+		MaxItems:     maxItems,
+		TitlePattern: titlePattern,
+	}), nil
+}
+
+// ListJobs returns all jobs of this session plus queued and failed jobs from earlier ones.
+func (a *App) ListJobs() []queue.Job {
+	if a.queue == nil {
+		return nil
+	}
+	return a.queue.List()
+}
+
+// GetJobLogs returns the log lines of a job.
+func (a *App) GetJobLogs(id string) ([]string, error) {
+	if a.queue == nil {
+		return nil, fmt.Errorf("job queue not initialized")
+	}
+	return a.queue.Logs(id)
+}
+
+// CancelJob stops a running job or drops a queued one.
+func (a *App) CancelJob(id string) error {
+	if a.queue == nil {
+		return fmt.Errorf("job queue not initialized")
+	}
+	return a.queue.Cancel(id)
+}
+
+// RetryJob queues a failed or cancelled job again.
+func (a *App) RetryJob(id string) (queue.Job, error) {
+	if a.queue == nil {
+		return queue.Job{}, fmt.Errorf("job queue not initialized")
+	}
+	return a.queue.Retry(id)
+}
+
+// RemoveJob deletes a job that is not running.
+func (a *App) RemoveJob(id string) error {
+	if a.queue == nil {
+		return fmt.Errorf("job queue not initialized")
+	}
+	return a.queue.Remove(id)
+}
+
+// ClearFinishedJobs removes done and cancelled jobs from the list.
+func (a *App) ClearFinishedJobs() {
+	if a.queue != nil {
+		a.queue.ClearFinished()
+	}
+}
+
+// runJob is the queue.Runner of the desktop app. It returns the path of the saved note.
+func (a *App) runJob(ctx context.Context, job queue.Job, jobLogger service.EventLogger) (result string, taskErr error) {
+	logger := &logRecorder{EventLogger: jobLogger}
+
+	// Dump logs on exit if error occurred (and not cancelled)
+	defer func() {
+		if ctx.Err() != nil {
+			return
+		}
+		if lines, hasError := logger.snapshot(); taskErr != nil || hasError {
+			if path := dumpLogs(lines, taskErr); path != "" {
+				logger.Log(fmt.Sprintf("Logs dumped to %s", path))
+			}
+		}
+	}()
+
+	// Load latest config
+	cfg := a.loadConfigSafe()
+
+	opts := service.OptionsFromConfig(cfg)
+	opts.AudioOnly = job.AudioOnly
+
+	if job.Playlist != nil || downloader.IsPlaylistURL(job.URL) {
+		var filter downloader.PlaylistFilter
+		if p := job.Playlist; p != nil {
+			var err error
+			if filter, err = downloader.ParsePlaylistFilter(p.After, p.Before, p.MaxItems, p.TitlePattern); err != nil {
+				return "", err
+			}
+		}
+		res, err := a.coreService.ProcessPlaylist(ctx, job.URL, opts, filter, logger)
+		if err != nil {
+			return "", err
+		}
+		return res.IndexPath, nil
+	}
+
+	res, err := a.coreService.ProcessTask(ctx, job.URL, opts, logger)
+	if err != nil {
+		return "", err
+	}
+	return res.NotePath, nil
+}
+
+// logRecorder keeps a copy of a job's log for error dumps. Log is called from
+// several goroutines at once, e.g. by parallel translation workers.
+type logRecorder struct {
+	service.EventLogger
+	mu       sync.Mutex
+	lines    []string
+	hasError bool
+}
+
+func (l *logRecorder) Log(msg string) {
+	msgLower := strings.ToLower(msg)
+	l.mu.Lock()
+	l.lines = append(l.lines, fmt.Sprintf("[%s] %s", time.Now().Format("15:04:05"), msg))
+	if strings.Contains(msgLower, "error") || strings.Contains(msgLower, "failed") {
+		l.hasError = true
+	}
+	l.mu.Unlock()
+	l.EventLogger.Log(msg)
+}
+
+// snapshot returns a copy of the recorded lines and whether any of them reported an error.
+func (l *logRecorder) snapshot() ([]string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...), l.hasError
+}
+
+func (l *logRecorder) Error(err error) {
+	l.Log(fmt.Sprintf("Error: %v", err))
+}
+
+// dumpLogs writes the log of a failed job to the log directory and returns the file path.
+func dumpLogs(lines []string, taskErr error) string {
+	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	logContent := strings.Join(lines, "\n")
+	if taskErr != nil {
+		logContent += fmt.Sprintf("\n\n[FATAL ERROR] %v", taskErr)
+	}
+
+	logDir, err := config.GetLogDir()
+	if err != nil {
+		// Fallback to current dir if system log dir fails
+		logDir = "logs"
+	}
+	os.MkdirAll(logDir, 0755)
+
+	filename := fmt.Sprintf("error_%s.log", timestamp)
+	filePath := filepath.Join(logDir, filename)
+	if err := os.WriteFile(filePath, []byte(logContent), 0644); err != nil {
+		return ""
+	}
+
+	latestPath := filepath.Join(logDir, "error_latest.log")
+	os.WriteFile(latestPath, []byte(logContent), 0644)
+	return filePath
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Log(string)           {}
+func (discardLogger) Progress(float64)     {}
+func (discardLogger) AnalysisChunk(string) {}
+func (discardLogger) Error(error)          {}
+
+func TestLogRecorder_ConcurrentLogs(t *testing.T) {
+	logger := &logRecorder{EventLogger: discardLogger{}}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				logger.Log(fmt.Sprintf("worker %d line %d", w, i))
+			}
+		}(w)
+	}
+	logger.Error(fmt.Errorf("batch failed"))
+	wg.Wait()
+
+	lines, hasError := logger.snapshot()
+	if len(lines) != 201 || !hasError {
+		t.Errorf("recorded %d lines (hasError %v), want 201 with an error", len(lines), hasError)
+	}
+}
@@ -2,8 +2,11 @@
 package app
 
 import (
+	"Varys/backend/analyzer"
 	"Varys/backend/config"
 	"Varys/backend/secret"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -134,7 +137,7 @@ func TestGetStartupDiagnostics_OllamaModelsMissingBlocksWhenProviderOllama(t *te
 	secret.UseMockStore()
 	defer secret.ResetStore()
 
-	if checkOllamaRunning() {
+	if checkOllamaRunning(analyzer.NewOllamaProvider("")) {
 		t.Skip("ollama is running with real models; skip deterministic empty-models test")
 	}
 
@@ -193,3 +196,81 @@ func findDiagnosticItem(t *testing.T, diag StartupDiagnostics, id string) Diagno
 	t.Fatalf("diagnostic item not found: %s", id)
 	return DiagnosticItem{}
 }
+
+func TestGetStartupDiagnostics_OpenAICompatible(t *testing.T) {
+	secret.UseMockStore()
+	defer secret.ResetStore()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("X-Proxy-Token") != "abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"local"}]}`))
+	}))
+	defer server.Close()
+
+	app := buildTestAppWithConfig(t, config.Config{
+		AIProvider:        "openai-compatible",
+		CompatibleBaseURL: server.URL + "/v1",
+		CompatibleModel:   "local",
+		CompatibleHeaders: map[string]string{"X-Proxy-Token": "abc"},
+		VaultPath:         t.TempDir(),
+		ModelPath:         createTempModel(t),
+	})
+
+	diag := app.GetStartupDiagnostics()
+	item := findDiagnosticItem(t, diag, "openai_compatible")
+	if item.Status != "ok" || item.IsBlocker {
+		t.Fatalf("expected openai_compatible ok, got status=%s blocker=%v", item.Status, item.IsBlocker)
+	}
+	if findDiagnosticItem(t, diag, "ollama").IsBlocker {
+		t.Fatalf("expected ollama to be non-blocker for openai-compatible provider")
+	}
+
+	app = buildTestAppWithConfig(t, config.Config{
+		AIProvider: "openai-compatible",
+		VaultPath:  t.TempDir(),
+		ModelPath:  createTempModel(t),
+	})
+	item = findDiagnosticItem(t, app.GetStartupDiagnostics(), "openai_compatible")
+	if item.Status != "missing" || !item.IsBlocker {
+		t.Fatalf("expected missing base url to block, got status=%s blocker=%v", item.Status, item.IsBlocker)
+	}
+}
+
+func TestGetStartupDiagnostics_Cookies(t *testing.T) {
+	secret.UseMockStore()
+	defer secret.ResetStore()
+
+	base := config.Config{
+		AIProvider: "openai",
+		OpenAIKey:  "sk-test",
+		VaultPath:  t.TempDir(),
+		ModelPath:  createTempModel(t),
+	}
+
+	cfg := base
+	cfg.CookiesSource = "none"
+	item := findDiagnosticItem(t, buildTestAppWithConfig(t, cfg).GetStartupDiagnostics(), "cookies")
+	if item.Status != "ok" || item.IsBlocker {
+		t.Fatalf("expected cookies=none to be ok, got status=%s blocker=%v", item.Status, item.IsBlocker)
+	}
+
+	cfg = base
+	cfg.CookiesSource = "file"
+	cfg.CookiesFile = filepath.Join(t.TempDir(), "missing.txt")
+	item = findDiagnosticItem(t, buildTestAppWithConfig(t, cfg).GetStartupDiagnostics(), "cookies")
+	if item.Status != "missing" || !item.IsBlocker {
+		t.Fatalf("expected missing cookies file to block, got status=%s blocker=%v", item.Status, item.IsBlocker)
+	}
+
+	cfg.CookiesFile = filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(cfg.CookiesFile, []byte("# Netscape HTTP Cookie File\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	item = findDiagnosticItem(t, buildTestAppWithConfig(t, cfg).GetStartupDiagnostics(), "cookies")
+	if item.Status != "ok" || item.DetectedPath != cfg.CookiesFile {
+		t.Fatalf("expected cookies file to be ok, got status=%s path=%s", item.Status, item.DetectedPath)
+	}
+}
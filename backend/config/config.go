@@ -2,6 +2,7 @@ package config
 
 import (
 	"Varys/backend/secret"
+	"Varys/backend/ytdlp"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -11,15 +12,82 @@ import (
 type Config struct {
 	VaultPath        string `json:"vault_path"`
 	ModelPath        string `json:"model_path"`        // Whisper Model Path
+	SubtitleSource   string `json:"subtitle_source"`   // "manual" (default), "auto" or "whisper": where transcripts come from
 	LLMModel         string `json:"llm_model"`         // Ollama Model Name
 	TranslationModel string `json:"translation_model"` // Ollama Model for Translation (Default: qwen3:0.6b)
 	TargetLanguage   string `json:"target_language"`   // Output language for analysis and translation
 	ContextSize      int    `json:"context_size"`      // Context window size for Ollama (default: 8192)
 	CustomPrompt     string `json:"custom_prompt"`     // Custom user prompt for analysis
-	AIProvider       string `json:"ai_provider"`       // "ollama" or "openai"
+	AnalysisMode     string `json:"analysis_mode"`     // "auto" (default), "single" or "chunked" (map-reduce for long texts)
+
+	TranslationWorkers   int    `json:"translation_workers"`    // Parallel translation requests (default: 1)
+	TranslationRateLimit int    `json:"translation_rate_limit"` // Max translation requests per minute (0 = unlimited)
+	GlossaryPath         string `json:"glossary_path"`          // CSV or YAML terminology list used during translation
+
+	QueueWorkers  int    `json:"queue_workers"`  // Jobs the desktop app processes at the same time (default: 2)
+	DuplicateMode string `json:"duplicate_mode"` // Resubmitted sources: "update" (default), "skip" or "version"
+	NoteTemplate  string `json:"note_template"`  // Built-in template name ("default", "english", "minimal") or a template file
+
+	AIProvider       string `json:"ai_provider"`       // "ollama", "openai", "openai-compatible" or "llamacpp"
 	OpenAIModel      string `json:"openai_model"`      // e.g. "gpt-4o"
 	OpenAIKey        string `json:"openai_key,omitempty"` // Stored in Keyring, passed via Wails
 	TavilyKey        string `json:"tavily_key,omitempty"` // Stored in Keyring, passed via Wails
+
+	// yt-dlp authentication (needed for age-restricted or members-only media)
+	CookiesSource  string `json:"cookies_source"`  // "browser" (default), "file" or "none"
+	CookiesBrowser string `json:"cookies_browser"` // e.g. "chrome" (default), "firefox", "safari"
+	CookiesProfile string `json:"cookies_profile"` // Optional browser profile name or path
+	CookiesFile    string `json:"cookies_file"`    // Netscape cookies.txt exported from a browser
+
+	// OpenAI-compatible servers (LM Studio, vLLM, llama.cpp llama-server)
+	CompatibleBaseURL string            `json:"compatible_base_url"`          // e.g. "http://localhost:1234/v1"
+	CompatibleModel   string            `json:"compatible_model"`             // Model name as reported by the server
+	CompatibleKey     string            `json:"compatible_key,omitempty"`     // Optional, stored in Keyring
+	CompatibleHeaders map[string]string `json:"compatible_headers,omitempty"` // Extra HTTP headers sent with every request
+
+	// Ollama connection (local or a shared server on the LAN)
+	OllamaHost      string `json:"ollama_host"`               // e.g. "http://192.168.1.20:11434"; empty uses OLLAMA_HOST or localhost
+	OllamaTimeout   int    `json:"ollama_timeout"`            // Seconds without a response (connecting or between tokens) before giving up (0 = no limit)
+	OllamaKeepAlive string `json:"ollama_keep_alive"`         // How long models stay loaded, e.g. "10m"; empty uses the server default
+	OllamaUsername  string `json:"ollama_username,omitempty"` // Basic auth user for reverse proxies
+	OllamaPassword  string `json:"ollama_password,omitempty"` // Basic auth password, stored in Keyring
+	OllamaToken     string `json:"ollama_token,omitempty"`    // Bearer token, stored in Keyring (takes precedence over basic auth)
+
+	// FallbackProviders are tried in order when the primary provider is unreachable,
+	// times out or returns invalid JSON. Connection settings come from the sections above.
+	FallbackProviders []ProviderRef `json:"fallback_providers,omitempty"`
+}
+
+// YtDlpCookies returns the cookie settings passed to every yt-dlp call.
+func (c *Config) YtDlpCookies() ytdlp.Cookies {
+	return ytdlp.Cookies{
+		Source:  c.CookiesSource,
+		Browser: c.CookiesBrowser,
+		Profile: c.CookiesProfile,
+		File:    c.CookiesFile,
+	}
+}
+
+// ProviderRef names a provider and model, e.g. {"provider": "openai", "model": "gpt-4o-mini"}.
+type ProviderRef struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+// secretField maps a Config field to its Keyring account.
+type secretField struct {
+	account string
+	jsonKey string
+	field   func(*Config) *string
+}
+
+// secretFields lists every value that is kept in the Keyring instead of config.json.
+var secretFields = []secretField{
+	{secret.KeyAccountOpenAI, "openai_key", func(c *Config) *string { return &c.OpenAIKey }},
+	{secret.KeyAccountTavily, "tavily_key", func(c *Config) *string { return &c.TavilyKey }},
+	{secret.KeyAccountCompatible, "compatible_key", func(c *Config) *string { return &c.CompatibleKey }},
+	{secret.KeyAccountOllamaPassword, "ollama_password", func(c *Config) *string { return &c.OllamaPassword }},
+	{secret.KeyAccountOllamaToken, "ollama_token", func(c *Config) *string { return &c.OllamaToken }},
 }
 
 type Manager struct {
@@ -79,6 +147,36 @@ func GetLogDir() (string, error) {
 	return logDir, nil
 }
 
+// GetWorkDir returns the directory holding per-URL task checkpoints, so
+// interrupted or failed tasks can resume where they stopped.
+func GetWorkDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "Varys", "work"), nil
+}
+
+// GetFeedStatePath returns the file listing feed subscriptions and the items
+// already ingested from them.
+func GetFeedStatePath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "feeds.json"), nil
+}
+
+// GetAPITokenPath returns the file holding the token clients of the local API
+// ("varys-cli serve") must send.
+func GetAPITokenPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "api-token"), nil
+}
+
 func NewManager() (*Manager, error) {
 	configDir, err := GetConfigDir()
 	if err != nil {
@@ -122,27 +220,22 @@ func (m *Manager) Load() (*Config, error) {
 	}
 
 	// 2. Fetch secrets from Keyring
-	if key, err := secret.GetSecret(secret.KeyAccountOpenAI); err == nil && key != "" {
-		cfg.OpenAIKey = key
-	}
-	if tKey, err := secret.GetSecret(secret.KeyAccountTavily); err == nil && tKey != "" {
-		cfg.TavilyKey = tKey
+	for _, sf := range secretFields {
+		if val, err := secret.GetSecret(sf.account); err == nil && val != "" {
+			*sf.field(&cfg) = val
+		}
 	}
 
 	// 3. Migration: Check if config.json still contains plain-text keys
 	var raw map[string]interface{}
 	json.Unmarshal(data, &raw)
 	migrated := false
-	if oldKey, ok := raw["openai_key"].(string); ok && oldKey != "" {
-		if err := secret.SetSecret(secret.KeyAccountOpenAI, oldKey); err == nil {
-			cfg.OpenAIKey = oldKey
-			migrated = true
-		}
-	}
-	if oldTKey, ok := raw["tavily_key"].(string); ok && oldTKey != "" {
-		if err := secret.SetSecret(secret.KeyAccountTavily, oldTKey); err == nil {
-			cfg.TavilyKey = oldTKey
-			migrated = true
+	for _, sf := range secretFields {
+		if old, ok := raw[sf.jsonKey].(string); ok && old != "" {
+			if err := secret.SetSecret(sf.account, old); err == nil {
+				*sf.field(&cfg) = old
+				migrated = true
+			}
 		}
 	}
 
@@ -166,23 +259,40 @@ func (m *Manager) Load() (*Config, error) {
 	if cfg.TargetLanguage == "" {
 		cfg.TargetLanguage = "English"
 	}
+	if cfg.AnalysisMode == "" {
+		cfg.AnalysisMode = "auto"
+	}
+	if cfg.SubtitleSource == "" {
+		cfg.SubtitleSource = "manual"
+	}
+	if cfg.CookiesSource == "" {
+		cfg.CookiesSource = ytdlp.CookiesBrowser
+	}
+	if cfg.CookiesSource == ytdlp.CookiesBrowser && cfg.CookiesBrowser == "" {
+		cfg.CookiesBrowser = ytdlp.DefaultBrowser
+	}
+	if cfg.TranslationWorkers == 0 {
+		cfg.TranslationWorkers = 1
+	}
+	if cfg.QueueWorkers == 0 {
+		cfg.QueueWorkers = 2
+	}
+	if cfg.DuplicateMode == "" {
+		cfg.DuplicateMode = "update"
+	}
 
 	return &cfg, nil
 }
 
 func (m *Manager) Save(cfg *Config) error {
-	// 1. Save sensitive data to Keyring
-	if cfg.OpenAIKey != "" {
-		secret.SetSecret(secret.KeyAccountOpenAI, cfg.OpenAIKey)
-	}
-	if cfg.TavilyKey != "" {
-		secret.SetSecret(secret.KeyAccountTavily, cfg.TavilyKey)
-	}
-
-	// 2. Prepare a copy for file storage (without sensitive keys)
+	// Save sensitive data to Keyring and keep it out of the file copy
 	fileCfg := *cfg
-	fileCfg.OpenAIKey = ""
-	fileCfg.TavilyKey = "" // Clear before saving to disk
+	for _, sf := range secretFields {
+		if val := *sf.field(cfg); val != "" {
+			secret.SetSecret(sf.account, val)
+		}
+		*sf.field(&fileCfg) = "" // Clear before saving to disk
+	}
 
 	data, err := json.MarshalIndent(fileCfg, "", "  ")
 	if err != nil {
@@ -207,5 +317,43 @@ func (c *Config) Validate() error {
 	if c.AIProvider == "openai" && c.OpenAIKey == "" {
 		return fmt.Errorf("openai api key is required when openai provider is selected")
 	}
+	if c.AIProvider == "openai-compatible" && c.CompatibleBaseURL == "" {
+		return fmt.Errorf("base url is required when openai-compatible provider is selected")
+	}
+	for i, fb := range c.FallbackProviders {
+		if fb.Provider == "" {
+			return fmt.Errorf("fallback provider #%d has no provider", i+1)
+		}
+		if fb.Provider == "openai" && c.OpenAIKey == "" {
+			return fmt.Errorf("openai api key is required for fallback provider #%d", i+1)
+		}
+	}
+	if c.TranslationWorkers < 0 || c.TranslationRateLimit < 0 {
+		return fmt.Errorf("translation workers and rate limit must not be negative")
+	}
+	if c.QueueWorkers < 0 {
+		return fmt.Errorf("queue workers must not be negative")
+	}
+	switch c.SubtitleSource {
+	case "", "manual", "auto", "whisper":
+	default:
+		return fmt.Errorf("invalid subtitle source %q (use manual, auto or whisper)", c.SubtitleSource)
+	}
+	switch c.AnalysisMode {
+	case "", "auto", "single", "chunked":
+	default:
+		return fmt.Errorf("invalid analysis mode %q (use auto, single or chunked)", c.AnalysisMode)
+	}
+	switch c.DuplicateMode {
+	case "", "update", "skip", "version":
+	default:
+		return fmt.Errorf("invalid duplicate mode %q (use update, skip or version)", c.DuplicateMode)
+	}
+	if err := c.YtDlpCookies().Validate(); err != nil {
+		return err
+	}
+	if c.OllamaTimeout < 0 {
+		return fmt.Errorf("ollama timeout must not be negative")
+	}
 	return nil
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"Varys/backend/secret"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -86,6 +88,76 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Valid OpenAI-Compatible Config Without Key",
+			config: Config{
+				VaultPath:         "/path/to/vault",
+				AIProvider:        "openai-compatible",
+				CompatibleBaseURL: "http://localhost:1234/v1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Missing OpenAI-Compatible Base URL",
+			config: Config{
+				VaultPath:  "/path/to/vault",
+				AIProvider: "openai-compatible",
+			},
+			wantErr: true,
+		},
+		{
+			name: "OpenAI Fallback Without Key",
+			config: Config{
+				VaultPath:         "/path/to/vault",
+				AIProvider:        "ollama",
+				FallbackProviders: []ProviderRef{{Provider: "openai", Model: "gpt-4o-mini"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Subtitle Source",
+			config: Config{
+				VaultPath:      "/path/to/vault",
+				AIProvider:     "ollama",
+				SubtitleSource: "youtube",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative Queue Workers",
+			config: Config{
+				VaultPath:    "/path/to/vault",
+				AIProvider:   "ollama",
+				QueueWorkers: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Analysis Mode",
+			config: Config{
+				VaultPath:    "/path/to/vault",
+				AIProvider:   "ollama",
+				AnalysisMode: "chunk",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Duplicate Mode",
+			config: Config{
+				VaultPath:     "/path/to/vault",
+				AIProvider:    "ollama",
+				DuplicateMode: "overwrite",
+			},
+			wantErr: true,
+		},
+		{
+			name: "llama.cpp Uses Default Base URL",
+			config: Config{
+				VaultPath:  "/path/to/vault",
+				AIProvider: "llamacpp",
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -97,3 +169,44 @@ func TestConfigValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigSecretsStayOutOfFile(t *testing.T) {
+	secret.UseMockStore()
+	defer secret.ResetStore()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	mgr := &Manager{configPath: path}
+
+	cfg := &Config{
+		VaultPath:      "/tmp/vault",
+		OllamaHost:     "http://192.168.1.20:11434",
+		OllamaUsername: "alice",
+		OllamaPassword: "hunter2",
+		OllamaToken:    "tok-123",
+		CompatibleKey:  "sk-local",
+	}
+	if err := mgr.Save(cfg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{"hunter2", "tok-123", "sk-local"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("Secret %q written to config file", leaked)
+		}
+	}
+
+	loaded, err := mgr.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.OllamaHost != cfg.OllamaHost || loaded.OllamaUsername != "alice" {
+		t.Errorf("Ollama connection settings not restored: %+v", loaded)
+	}
+	if loaded.OllamaPassword != "hunter2" || loaded.OllamaToken != "tok-123" || loaded.CompatibleKey != "sk-local" {
+		t.Errorf("Secrets not restored from keyring: %+v", loaded)
+	}
+}
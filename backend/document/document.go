@@ -0,0 +1,329 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Supported document formats.
+const (
+	FormatPDF      = "pdf"
+	FormatEPUB     = "epub"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatText     = "text"
+)
+
+// Document is the text and metadata extracted from a document file.
+type Document struct {
+	Format    string
+	Title     string
+	Author    string
+	Subject   string
+	Keywords  []string
+	Language  string    // "en", "zh" or "auto" when unsure
+	Published time.Time // zero if unknown
+	Pages     []Page    // PDFs
+	Chapters  []Chapter // EPUBs; a single untitled chapter for Markdown, HTML and text
+	Headings  []Heading
+}
+
+// Page is the text of one page. Lines of a paragraph are joined, so every line
+// is a paragraph or a heading.
+type Page struct {
+	Number int
+	Text   string
+}
+
+// Chapter is one section of a book, in reading order.
+type Chapter struct {
+	Title string
+	Text  string
+}
+
+// Heading is a section title found in the text. Level 1 is a top-level section.
+// Page is 0 for formats without pages.
+type Heading struct {
+	Level int
+	Text  string
+	Page  int
+}
+
+// Span is the range of pages or chapters a piece of the text came from.
+type Span struct {
+	Unit  string // "page" or "chapter"
+	First int
+	Last  int
+}
+
+func (s Span) String() string {
+	if s.First == s.Last {
+		return fmt.Sprintf("%s %d", s.Unit, s.First)
+	}
+	return fmt.Sprintf("%ss %d-%d", s.Unit, s.First, s.Last)
+}
+
+// part is a page or chapter with its number.
+type part struct {
+	number int
+	text   string
+}
+
+// parts returns the non-empty pages or chapters and the unit they are counted in.
+func (d *Document) parts() ([]part, string) {
+	var parts []part
+	if len(d.Pages) > 0 {
+		for _, p := range d.Pages {
+			if p.Text != "" {
+				parts = append(parts, part{p.Number, p.Text})
+			}
+		}
+		return parts, "page"
+	}
+	for i, c := range d.Chapters {
+		if c.Text != "" {
+			parts = append(parts, part{i + 1, c.Text})
+		}
+	}
+	return parts, "chapter"
+}
+
+// Text returns the text of all pages or chapters.
+func (d *Document) Text() string {
+	parts, _ := d.parts()
+	texts := make([]string, len(parts))
+	for i, p := range parts {
+		texts[i] = p.text
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// Length returns the number of pages or chapters and the unit they are counted in.
+func (d *Document) Length() (int, string) {
+	if len(d.Pages) > 0 {
+		return len(d.Pages), "page"
+	}
+	return len(d.Chapters), "chapter"
+}
+
+// Locate maps pieces of Text(), in order, to the pages or chapters they were taken
+// from. Pieces are matched by their first and last line; one that cannot be found
+// gets a zero span.
+func (d *Document) Locate(pieces []string) []Span {
+	parts, unit := d.parts()
+
+	// Start offset of every part in Text()
+	starts := make([]int, len(parts))
+	offset := 0
+	for i, p := range parts {
+		if i > 0 {
+			offset += 2
+		}
+		starts[i] = offset
+		offset += len(p.text)
+	}
+	numberAt := func(off int) int {
+		i := sort.Search(len(starts), func(i int) bool { return starts[i] > off }) - 1
+		if i < 0 {
+			return 0
+		}
+		return parts[i].number
+	}
+
+	text := d.Text()
+	spans := make([]Span, len(pieces))
+	cursor := 0
+	for i, piece := range pieces {
+		lines := strings.Split(strings.TrimSpace(piece), "\n")
+		first := strings.TrimSpace(lines[0])
+		last := strings.TrimSpace(lines[len(lines)-1])
+		if first == "" {
+			continue
+		}
+		at := strings.Index(text[cursor:], first)
+		if at < 0 {
+			continue
+		}
+		start := cursor + at
+		end := start
+		if at := strings.Index(text[start:], last); at >= 0 {
+			end = start + at
+		}
+		spans[i] = Span{Unit: unit, First: numberAt(start), Last: numberAt(end)}
+		// Pieces may overlap, so the next one starts after this one's start
+		cursor = start
+	}
+	return spans
+}
+
+// Attachable reports whether files of format are kept next to the note. Text
+// formats are not: the note already holds all of their content.
+func Attachable(format string) bool {
+	return format == FormatPDF || format == FormatEPUB
+}
+
+// Detect returns the document format of the file at path, judged by its extension
+// and then by its first bytes. It returns "" for anything else, e.g. media files.
+func Detect(path string) string {
+	if format := DetectName(path); format != "" {
+		return format
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	return sniff(head[:n])
+}
+
+// DetectName returns the document format of a file name, judged by its
+// extension only.
+func DetectName(name string) string {
+	return formatFromExt(filepath.Ext(name))
+}
+
+// DetectURL returns the document format a URL points to, judged by its path.
+// Web pages are left to the article scraper, so HTML is never reported.
+func DetectURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if format := formatFromExt(path.Ext(u.Path)); format != FormatHTML {
+		return format
+	}
+	return ""
+}
+
+func formatFromExt(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".pdf":
+		return FormatPDF
+	case ".epub":
+		return FormatEPUB
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML
+	case ".txt", ".text":
+		return FormatText
+	}
+	return ""
+}
+
+// extensions maps formats to the file extension used for downloads.
+var extensions = map[string]string{
+	FormatPDF:      ".pdf",
+	FormatEPUB:     ".epub",
+	FormatMarkdown: ".md",
+	FormatHTML:     ".html",
+	FormatText:     ".txt",
+}
+
+// sniff recognizes a document by its first bytes.
+func sniff(head []byte) string {
+	if format := sniffBinary(head); format != "" {
+		return format
+	}
+	text := bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	if !looksLikeText(text) {
+		return ""
+	}
+	start := strings.ToLower(string(bytes.TrimSpace(text)))
+	if strings.HasPrefix(start, "<!doctype html") || strings.HasPrefix(start, "<html") {
+		return FormatHTML
+	}
+	return FormatText
+}
+
+// sniffBinary recognizes PDFs and EPUBs by their magic bytes.
+func sniffBinary(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return FormatPDF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")) && bytes.Contains(head, []byte("mimetypeapplication/epub+zip")):
+		// EPUBs are ZIP files starting with an uncompressed "mimetype" entry
+		return FormatEPUB
+	}
+	return ""
+}
+
+// looksLikeText reports whether head is UTF-8 text without control characters,
+// which binary formats such as audio or video are full of.
+func looksLikeText(head []byte) bool {
+	if len(bytes.TrimSpace(head)) == 0 {
+		return false
+	}
+	// The last character may have been cut off at the buffer's end
+	for i := 0; i < utf8.UTFMax-1 && len(head) > 0 && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	if !utf8.Valid(head) {
+		return false
+	}
+	for _, r := range string(head) {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' && r != '\f' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeLanguage turns a language tag such as "en-US" into "en".
+func normalizeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i > 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
+
+// guessLanguage tells Chinese and English text apart, which is all the translation
+// step needs to decide whether to skip. Anything else is "auto".
+func guessLanguage(text string) string {
+	han, letters := 0, 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			han++
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters == 0 {
+		return "auto"
+	}
+	// Chinese papers mix in plenty of Latin terms, and each Han character is a word
+	if han*100/letters >= 30 {
+		return "zh"
+	}
+
+	// English is recognized by its most common function words
+	words, common := 0, 0
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		words++
+		if englishWords[w] {
+			common++
+		}
+	}
+	if words > 0 && common*100/words >= 8 {
+		return "en"
+	}
+	return "auto"
+}
+
+var englishWords = map[string]bool{
+	"the": true, "and": true, "of": true, "to": true, "is": true, "that": true,
+	"for": true, "with": true, "this": true, "are": true, "we": true, "it": true,
+}
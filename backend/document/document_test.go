@@ -0,0 +1,125 @@
+package document
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0644)
+		return path
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{write("paper.pdf", "anything"), FormatPDF},
+		{write("PAPER.PDF", "anything"), FormatPDF},
+		{write("book.epub", "anything"), FormatEPUB},
+		{write("notes.md", "# Notes"), FormatMarkdown},
+		{write("page.htm", "<p>Hi</p>"), FormatHTML},
+		{write("readme.txt", "Hello"), FormatText},
+		// No extension: magic bytes
+		{write("download", "%PDF-1.7\n..."), FormatPDF},
+		{write("book", "PK\x03\x04\x14\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x14\x00\x00\x00\x14\x00\x00\x00\x08\x00\x00\x00mimetypeapplication/epub+zip"), FormatEPUB},
+		{write("saved", "\n  <!DOCTYPE html><html><body>Hi</body></html>"), FormatHTML},
+		{write("LICENSE", "Permission is hereby granted, free of charge,\nto any person"), FormatText},
+		{write("utf8-cut", "Text ending in a cut-off character:"+strings.Repeat("é", 300)), FormatText},
+		{write("archive.zip", "PK\x03\x04\x14\x00"), ""},
+		{write("talk.mp3", "ID3\x04\x00\x00\x00\x00"), ""},
+		{write("audio", "RIFF\x24\x08\x00\x00WAVEfmt "), ""},
+		{write("empty", ""), ""},
+		{filepath.Join(dir, "missing"), ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.path); got != tt.want {
+			t.Errorf("Detect(%s) = %q, want %q", filepath.Base(tt.path), got, tt.want)
+		}
+	}
+}
+
+func TestDetectURL(t *testing.T) {
+	tests := map[string]string{
+		"https://arxiv.org/pdf/1706.03762v7.pdf":   FormatPDF,
+		"https://example.com/files/Report.PDF?x=1": FormatPDF,
+		"https://example.com/book.epub":            FormatEPUB,
+		"https://example.com/raw/README.md":        FormatMarkdown,
+		"https://example.com/post.html":            "",
+		"https://www.youtube.com/watch?v=abc":      "",
+	}
+	for url, want := range tests {
+		if got := DetectURL(url); got != want {
+			t.Errorf("DetectURL(%s) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestLocate_Pages(t *testing.T) {
+	doc := &Document{Pages: []Page{
+		{Number: 1, Text: "alpha one\nalpha two"},
+		{Number: 2, Text: ""}, // blank page
+		{Number: 3, Text: "gamma one\ngamma two"},
+		{Number: 4, Text: "delta one"},
+	}}
+
+	pieces := []string{
+		"alpha one\nalpha two\ngamma one",
+		"gamma one\ngamma two\ndelta one", // overlaps the first piece
+		"delta one",
+		"not in the text",
+	}
+	want := []Span{{"page", 1, 3}, {"page", 3, 4}, {"page", 4, 4}, {}}
+	got := doc.Locate(pieces)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("piece %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+	if s := got[0].String(); s != "pages 1-3" {
+		t.Errorf("String() = %q", s)
+	}
+	if s := got[2].String(); s != "page 4" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestLocate_Chapters(t *testing.T) {
+	doc := &Document{Chapters: []Chapter{
+		{Title: "One", Text: "One\nfirst chapter"},
+		{Title: "Two", Text: "Two\nsecond chapter"},
+	}}
+	if n, unit := doc.Length(); n != 2 || unit != "chapter" {
+		t.Errorf("Length() = %d %s", n, unit)
+	}
+	got := doc.Locate([]string{"first chapter\nTwo"})
+	if want := (Span{"chapter", 1, 2}); got[0] != want {
+		t.Errorf("got %v, want %v", got[0], want)
+	}
+	if s := got[0].String(); s != "chapters 1-2" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestGuessLanguage(t *testing.T) {
+	tests := map[string]string{
+		"We show that the model is trained with data and it works.": "en",
+		"本文提出了一种基于 Transformer 的方法，并在多个数据集上进行了实验。":                  "zh",
+		"Nous montrons que le modèle fonctionne avec des données.":  "auto",
+		"Wir zeigen, dass das Modell mit Daten funktioniert.":       "auto",
+		"123 456": "auto",
+	}
+	for text, want := range tests {
+		if got := guessLanguage(text); got != want {
+			t.Errorf("guessLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+	if got := guessLanguage(strings.Repeat("the cat and the dog ", 10)); got != "en" {
+		t.Errorf("repeated English = %q", got)
+	}
+}
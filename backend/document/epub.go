@@ -0,0 +1,241 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// EPUB package structures, see https://www.w3.org/TR/epub/. Element names match
+// without their namespace prefix.
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Metadata struct {
+		Title       []string `xml:"title"`
+		Creator     []string `xml:"creator"`
+		Language    []string `xml:"language"`
+		Date        []string `xml:"date"`
+		Subject     []string `xml:"subject"`
+		Description []string `xml:"description"`
+	} `xml:"metadata"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc   string `xml:"toc,attr"`
+		Items []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type ncxPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []ncxPoint `xml:"navPoint"`
+}
+
+// extractEPUB reads a book chapter by chapter in reading order (the spine).
+// Chapter titles come from the table of contents, or else from the chapter's
+// first heading.
+func extractEPUB(file string) (*Document, error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EPUB: %w", err)
+	}
+	defer zr.Close()
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var container epubContainer
+	if err := readXML(files, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, fmt.Errorf("invalid EPUB: no package document")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err := readXML(files, opfPath, &pkg); err != nil {
+		return nil, err
+	}
+	base := path.Dir(opfPath)
+
+	doc := &Document{Format: FormatEPUB}
+	meta := pkg.Metadata
+	doc.Title = first(meta.Title)
+	doc.Author = strings.Join(meta.Creator, ", ")
+	doc.Subject = first(meta.Description)
+	doc.Keywords = meta.Subject
+	doc.Language = normalizeLanguage(first(meta.Language))
+	if date := first(meta.Date); len(date) >= 4 {
+		for _, layout := range []string{time.RFC3339, "2006-01-02", "2006-01", "2006"} {
+			if t, err := time.Parse(layout, date); err == nil {
+				doc.Published = t
+				break
+			}
+		}
+	}
+
+	hrefs := map[string]string{}
+	toc := map[string]string{}
+	for _, item := range pkg.Manifest {
+		href := resolveHref(base, item.Href)
+		hrefs[item.ID] = href
+		switch {
+		case item.ID == pkg.Spine.Toc || item.MediaType == "application/x-dtbncx+xml":
+			readNCX(files, href, toc)
+		case strings.Contains(item.Properties, "nav"):
+			readNav(files, href, toc)
+		}
+	}
+
+	for _, ref := range pkg.Spine.Items {
+		if ref.Linear == "no" {
+			continue
+		}
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		data, err := readFile(files, href)
+		if err != nil {
+			return nil, err
+		}
+		root, err := html.Parse(bytes.NewReader(data))
+		if err != nil {
+			continue
+		}
+		page := htmlText(root)
+		if page.text == "" {
+			// Cover and title pages are often just an image
+			continue
+		}
+		title := toc[href]
+		if title == "" && len(page.headings) > 0 {
+			title = page.headings[0].Text
+		}
+		doc.Chapters = append(doc.Chapters, Chapter{Title: title, Text: page.text})
+		if title != "" {
+			doc.Headings = append(doc.Headings, Heading{Level: 1, Text: title})
+		}
+	}
+	if doc.Language == "" {
+		doc.Language = guessLanguage(doc.Text())
+	}
+	return doc, nil
+}
+
+// readNCX collects chapter titles from an EPUB 2 table of contents.
+func readNCX(files map[string]*zip.File, name string, toc map[string]string) {
+	var ncx struct {
+		Points []ncxPoint `xml:"navMap>navPoint"`
+	}
+	if readXML(files, name, &ncx) != nil {
+		return
+	}
+	var add func([]ncxPoint)
+	add = func(points []ncxPoint) {
+		for _, p := range points {
+			addTOCEntry(toc, resolveHref(path.Dir(name), p.Content.Src), p.Label)
+			add(p.Children)
+		}
+	}
+	add(ncx.Points)
+}
+
+// readNav collects chapter titles from an EPUB 3 navigation document.
+func readNav(files map[string]*zip.File, name string, toc map[string]string) {
+	data, err := readFile(files, name)
+	if err != nil {
+		return
+	}
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	for n := range root.Descendants() {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Nav || attr(n, "epub:type") != "toc" {
+			continue
+		}
+		for a := range n.Descendants() {
+			if a.Type == html.ElementNode && a.DataAtom == atom.A {
+				addTOCEntry(toc, resolveHref(path.Dir(name), attr(a, "href")), nodeText(a))
+			}
+		}
+	}
+}
+
+// addTOCEntry keeps the first title of each file: later entries point at
+// sections within the chapter.
+func addTOCEntry(toc map[string]string, href, label string) {
+	label = strings.Join(strings.Fields(label), " ")
+	if _, ok := toc[href]; !ok && label != "" {
+		toc[href] = label
+	}
+}
+
+// resolveHref turns a link relative to dir into a path inside the archive,
+// dropping the fragment.
+func resolveHref(dir, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Join(dir, href)
+}
+
+func readFile(files map[string]*zip.File, name string) ([]byte, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid EPUB: %s is missing", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func readXML(files map[string]*zip.File, name string, v interface{}) error {
+	data, err := readFile(files, name)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid EPUB: %s: %w", name, err)
+	}
+	return nil
+}
+
+func first(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package document
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeEPUB builds a minimal book. The "mimetype" entry comes first and is
+// stored uncompressed, as the format requires.
+func writeEPUB(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	w, _ := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	w.Write([]byte("application/epub+zip"))
+	for name, content := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const sampleOPF = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>The Sample Book</dc:title>
+    <dc:creator>Ann Author</dc:creator>
+    <dc:creator>Bob Writer</dc:creator>
+    <dc:language>en-GB</dc:language>
+    <dc:date>2021-06-01</dc:date>
+    <dc:subject>Testing</dc:subject>
+    <dc:description>A book about tests.</dc:description>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="cover" href="text/cover.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="text/ch2.xhtml" media-type="application/xhtml+xml"/>
+    <item id="notes" href="text/notes.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine>
+    <itemref idref="cover"/>
+    <itemref idref="c1"/>
+    <itemref idref="c2"/>
+    <itemref idref="notes" linear="no"/>
+  </spine>
+</package>`
+
+func TestExtractEPUB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.epub")
+	writeEPUB(t, path, map[string]string{
+		"META-INF/container.xml": `<?xml version="1.0"?><container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`,
+		"OEBPS/content.opf":      sampleOPF,
+		"OEBPS/nav.xhtml":        `<html xmlns:epub="http://www.idpf.org/2007/ops"><body><nav epub:type="toc"><ol><li><a href="text/chapter%201.xhtml">Chapter One: Beginnings</a><ol><li><a href="text/chapter%201.xhtml#s1">A Section</a></li></ol></li></ol></nav></body></html>`,
+		"OEBPS/text/cover.xhtml": `<html><body><img src="cover.jpg"/></body></html>`,
+		"OEBPS/text/chapter 1.xhtml": `<html><head><title>ignored</title><style>p{}</style></head><body>
+			<h1>Beginnings</h1><p>It was a <em>dark</em>
+			and stormy night.</p><p>The end of the start.</p></body></html>`,
+		"OEBPS/text/ch2.xhtml":   `<html><body><h2>Second Chapter</h2><p>More text.</p></body></html>`,
+		"OEBPS/text/notes.xhtml": `<html><body><p>Footnotes</p></body></html>`,
+	})
+
+	doc, err := NewExtractor(nil).Extract(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Format != FormatEPUB || doc.Title != "The Sample Book" || doc.Author != "Ann Author, Bob Writer" || doc.Language != "en" {
+		t.Errorf("metadata = %+v", doc)
+	}
+	if doc.Subject != "A book about tests." || len(doc.Keywords) != 1 || !doc.Published.Equal(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("metadata = %+v", doc)
+	}
+
+	// The cover has no text and the notes are not in the reading order
+	if len(doc.Chapters) != 2 {
+		t.Fatalf("chapters = %+v", doc.Chapters)
+	}
+	if c := doc.Chapters[0]; c.Title != "Chapter One: Beginnings" || c.Text != "Beginnings\nIt was a dark and stormy night.\nThe end of the start." {
+		t.Errorf("chapter 1 = %+v", c)
+	}
+	// Without a TOC entry the first heading names the chapter
+	if c := doc.Chapters[1]; c.Title != "Second Chapter" {
+		t.Errorf("chapter 2 = %+v", c)
+	}
+	if len(doc.Headings) != 2 || doc.Headings[1].Text != "Second Chapter" || doc.Headings[1].Page != 0 {
+		t.Errorf("headings = %+v", doc.Headings)
+	}
+	if strings.Contains(doc.Text(), "ignored") || strings.Contains(doc.Text(), "Footnotes") {
+		t.Errorf("text = %q", doc.Text())
+	}
+}
+
+func TestExtractEPUB_NCX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.epub")
+	writeEPUB(t, path, map[string]string{
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="content.opf"/></rootfiles></container>`,
+		"content.opf": `<package><metadata><title>Old Book</title></metadata>
+			<manifest><item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/><item id="c1" href="c1.html" media-type="application/xhtml+xml"/></manifest>
+			<spine toc="ncx"><itemref idref="c1"/></spine></package>`,
+		"toc.ncx": `<ncx><navMap><navPoint><navLabel><text>Prologue</text></navLabel><content src="c1.html"/></navPoint></navMap></ncx>`,
+		"c1.html": `<html><body><p>Once upon a time.</p></body></html>`,
+	})
+
+	doc, err := NewExtractor(nil).Extract(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Old Book" || len(doc.Chapters) != 1 || doc.Chapters[0].Title != "Prologue" {
+		t.Errorf("doc = %+v", doc)
+	}
+}
+
+func TestExtractEPUB_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.epub")
+	os.WriteFile(path, []byte("not a zip"), 0644)
+	if _, err := NewExtractor(nil).Extract(context.Background(), path); err == nil {
+		t.Error("expected an error for a broken EPUB")
+	}
+}
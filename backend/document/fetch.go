@@ -0,0 +1,92 @@
+package document
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotDocument is returned by Download when the URL serves something other
+// than a supported document, typically an HTML page.
+var ErrNotDocument = errors.New("not a document")
+
+// Content types served for documents. HTML is missing on purpose: web pages are
+// articles for the scraper.
+var contentTypes = map[string]string{
+	"application/pdf":      FormatPDF,
+	"application/epub+zip": FormatEPUB,
+	"text/markdown":        FormatMarkdown,
+	"text/x-markdown":      FormatMarkdown,
+	"text/plain":           FormatText,
+}
+
+// Download saves the document at rawURL into dir and returns its path. The format
+// is recognized by the Content-Type header or the first bytes of the body, so only
+// the start of a web page is read before ErrNotDocument is returned.
+func Download(ctx context.Context, rawURL, dir string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	head, _ := body.Peek(512)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	format := sniffBinary(head)
+	if format == "" {
+		format = contentTypes[mediaType]
+	}
+	if format == FormatText && DetectURL(rawURL) == FormatMarkdown {
+		// Markdown files are often served as plain text
+		format = FormatMarkdown
+	}
+	if format == "" {
+		return "", ErrNotDocument
+	}
+
+	dest := filepath.Join(dir, downloadName(rawURL, format))
+	out, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, body); err != nil {
+		out.Close()
+		os.Remove(dest)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// downloadName derives a file name from the URL path, e.g. "2401.00001.pdf".
+func downloadName(rawURL, format string) string {
+	name := "document"
+	if u, err := url.Parse(rawURL); err == nil {
+		if base := path.Base(u.Path); base != "." && base != "/" {
+			name = strings.TrimSuffix(base, path.Ext(base))
+		}
+	}
+	return name + extensions[format]
+}
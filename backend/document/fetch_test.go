@@ -0,0 +1,55 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/papers/1706.03762.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-1.7 body"))
+		case "/download":
+			// Served without a telling URL or content type
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("%PDF-1.4 body"))
+		case "/post":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><body>Article</body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path, err := Download(context.Background(), server.URL+"/papers/1706.03762.pdf", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "1706.03762.pdf" {
+		t.Errorf("saved as %s", filepath.Base(path))
+	}
+	if data, _ := os.ReadFile(path); string(data) != "%PDF-1.7 body" {
+		t.Errorf("saved %q", data)
+	}
+
+	path, err = Download(context.Background(), server.URL+"/download", dir)
+	if err != nil || filepath.Base(path) != "download.pdf" {
+		t.Errorf("sniffed download: %s, %v", path, err)
+	}
+
+	if _, err := Download(context.Background(), server.URL+"/post", dir); !errors.Is(err, ErrNotDocument) {
+		t.Errorf("web page: expected ErrNotDocument, got %v", err)
+	}
+	if _, err := Download(context.Background(), server.URL+"/missing.pdf", dir); err == nil || errors.Is(err, ErrNotDocument) {
+		t.Errorf("404: expected a download error, got %v", err)
+	}
+}
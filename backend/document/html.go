@@ -0,0 +1,172 @@
+package document
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-shiori/go-readability"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// extractHTML reads a saved web page. The main content is picked with the same
+// readability engine the article scraper uses; pages it cannot make sense of are
+// read in full.
+func extractHTML(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	page := htmlText(root)
+
+	doc := &Document{
+		Format:   FormatHTML,
+		Title:    page.title,
+		Language: page.lang,
+	}
+	content := page
+	abs, _ := filepath.Abs(path)
+	if article, err := readability.FromReader(bytes.NewReader(data), &url.URL{Scheme: "file", Path: abs}); err == nil && article.Node != nil {
+		if main := htmlText(article.Node); main.text != "" {
+			content = main
+		}
+		if doc.Title == "" {
+			doc.Title = article.Title
+		}
+		doc.Author = article.Byline
+		if article.PublishedTime != nil {
+			doc.Published = *article.PublishedTime
+		}
+	}
+
+	doc.Chapters = []Chapter{{Text: content.text}}
+	doc.Headings = content.headings
+	if doc.Language == "" {
+		doc.Language = guessLanguage(content.text)
+	}
+	return doc, nil
+}
+
+// htmlPage is the readable text of an HTML tree.
+type htmlPage struct {
+	title    string
+	lang     string
+	text     string // one line per block element
+	headings []Heading
+}
+
+// Elements whose text is not part of the content.
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Math: true, atom.Head: true, atom.Iframe: true, atom.Object: true,
+}
+
+// Elements that start a new line.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Ul: true, atom.Ol: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Tr: true, atom.Table: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Section: true, atom.Article: true,
+	atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Nav: true, atom.Main: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Hr: true, atom.Address: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+var headingLevels = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+// htmlText converts an HTML tree into plain text with one line per paragraph,
+// list item or heading, and collects the h1-h6 headings.
+func htmlText(root *html.Node) htmlPage {
+	var page htmlPage
+	var lines []string
+	var line strings.Builder
+	flush := func() {
+		if text := strings.Join(strings.Fields(line.String()), " "); text != "" {
+			lines = append(lines, text)
+		}
+		line.Reset()
+	}
+
+	var walk func(n *html.Node, pre bool)
+	walk = func(n *html.Node, pre bool) {
+		switch n.Type {
+		case html.TextNode:
+			if pre {
+				// Keep the lines of code blocks and poems
+				for i, l := range strings.Split(n.Data, "\n") {
+					if i > 0 {
+						flush()
+					}
+					line.WriteString(l)
+				}
+				return
+			}
+			line.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			if n.DataAtom == atom.Html {
+				page.lang = normalizeLanguage(attr(n, "lang"))
+			}
+			if skippedElements[n.DataAtom] {
+				return
+			}
+			if level := headingLevels[n.DataAtom]; level > 0 {
+				if text := strings.Join(strings.Fields(nodeText(n)), " "); text != "" {
+					page.headings = append(page.headings, Heading{Level: level, Text: text})
+				}
+			}
+			pre = pre || n.DataAtom == atom.Pre
+		}
+
+		block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+		if block {
+			flush()
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, pre)
+		}
+		if block {
+			flush()
+		}
+	}
+	// The title lives in <head>, which is skipped for the text
+	for n := range root.Descendants() {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Title && n.Parent != nil && n.Parent.DataAtom == atom.Head {
+			page.title = strings.TrimSpace(nodeText(n))
+			break
+		}
+	}
+	walk(root, false)
+	flush()
+
+	page.text = strings.Join(lines, "\n")
+	return page
+}
+
+// nodeText returns the text inside n.
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	for d := range n.Descendants() {
+		if d.Type == html.TextNode {
+			b.WriteString(d.Data)
+		}
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
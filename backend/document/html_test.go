@@ -0,0 +1,66 @@
+package document
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractHTML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "saved.html")
+	paragraph := "Readability needs a few sentences before it trusts a block of text, so this paragraph repeats itself. "
+	os.WriteFile(path, []byte(`<!DOCTYPE html>
+<html lang="en-US">
+<head><title>Saved Page</title><script>var x = 1;</script></head>
+<body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<article>
+<h1>Saved Page</h1>
+<p>`+strings.Repeat(paragraph, 4)+`</p>
+<h2>Part Two</h2>
+<p>`+strings.Repeat(paragraph, 3)+`</p>
+<pre>line one
+line two</pre>
+</article>
+</body></html>`), 0644)
+
+	doc, err := NewExtractor(nil).Extract(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Format != FormatHTML || doc.Title != "Saved Page" || doc.Language != "en" {
+		t.Errorf("metadata = %+v", doc)
+	}
+	text := doc.Text()
+	for _, want := range []string{"Part Two\n", "\nline one\nline two"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text missing %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "var x") {
+		t.Errorf("text contains the script:\n%s", text)
+	}
+	found := false
+	for _, h := range doc.Headings {
+		found = found || (h.Level == 2 && h.Text == "Part Two")
+	}
+	if !found {
+		t.Errorf("headings = %+v", doc.Headings)
+	}
+}
+
+func TestExtractHTML_Fragment(t *testing.T) {
+	// Too short for readability: the whole page is read
+	path := filepath.Join(t.TempDir(), "snippet.htm")
+	os.WriteFile(path, []byte("<p>Just <b>one</b><br>short note.</p>"), 0644)
+
+	doc, err := NewExtractor(nil).Extract(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Text() != "Just one\nshort note." {
+		t.Errorf("text = %q", doc.Text())
+	}
+}
@@ -0,0 +1,282 @@
+package document
+
+import (
+	"Varys/backend/dependency"
+	"Varys/backend/process"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Extractor reads the text of documents. PDFs are read with poppler's pdftotext
+// and pdfinfo; EPUB, Markdown, HTML and plain text need no external tools.
+type Extractor struct {
+	dep *dependency.Manager
+}
+
+func NewExtractor(dep *dependency.Manager) *Extractor {
+	return &Extractor{dep: dep}
+}
+
+// Extract reads the document at path.
+func (e *Extractor) Extract(ctx context.Context, path string) (*Document, error) {
+	var doc *Document
+	var err error
+	switch format := Detect(path); format {
+	case FormatPDF:
+		return e.ExtractPDF(ctx, path)
+	case FormatEPUB:
+		doc, err = extractEPUB(path)
+	case FormatMarkdown:
+		doc, err = extractMarkdown(path)
+	case FormatHTML:
+		doc, err = extractHTML(path)
+	case FormatText:
+		doc, err = extractText(path)
+	default:
+		return nil, fmt.Errorf("unsupported document: %s", filepath.Base(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(doc.Text()) == "" {
+		return nil, fmt.Errorf("no text found in %s", filepath.Base(path))
+	}
+	return doc, nil
+}
+
+// ExtractPDF reads the pages, headings and embedded metadata of a PDF.
+func (e *Extractor) ExtractPDF(ctx context.Context, path string) (*Document, error) {
+	textPath := e.dep.GetBinaryPath("pdftotext")
+	if textPath == "" {
+		return nil, fmt.Errorf("pdftotext not found (install poppler)")
+	}
+
+	out, err := process.Command(ctx, textPath, "-enc", "UTF-8", path, "-").Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("pdftotext failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("pdftotext failed: %w", err)
+	}
+
+	doc := &Document{Format: FormatPDF}
+	doc.Pages, doc.Headings = parsePages(string(out))
+	if strings.TrimSpace(doc.Text()) == "" {
+		return nil, fmt.Errorf("no text found in %s (scanned PDFs need OCR first)", filepath.Base(path))
+	}
+	doc.Language = guessLanguage(doc.Text())
+
+	// Metadata is optional: a PDF without it is still worth reading
+	if infoPath := e.dep.GetBinaryPath("pdfinfo"); infoPath != "" {
+		if out, err := process.Command(ctx, infoPath, "-enc", "UTF-8", "-isodates", path).Output(); err == nil {
+			applyPDFInfo(doc, string(out))
+		} else if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return doc, nil
+}
+
+// applyPDFInfo copies the fields of pdfinfo's "Key: value" output into doc.
+func applyPDFInfo(doc *Document, info string) {
+	for _, line := range strings.Split(info, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Title":
+			doc.Title = cleanTitle(value)
+		case "Author":
+			doc.Author = value
+		case "Subject":
+			doc.Subject = value
+		case "Keywords":
+			doc.Keywords = splitKeywords(value)
+		case "CreationDate":
+			if t, err := parsePDFDate(value); err == nil {
+				doc.Published = t
+			}
+		}
+	}
+}
+
+// cleanTitle drops titles that are really file names left by the authoring tool.
+func cleanTitle(title string) string {
+	title = strings.TrimPrefix(title, "Microsoft Word - ")
+	switch strings.ToLower(filepath.Ext(title)) {
+	case ".doc", ".docx", ".tex", ".dvi", ".pdf", ".indd":
+		return ""
+	}
+	if strings.EqualFold(title, "untitled") {
+		return ""
+	}
+	return title
+}
+
+func splitKeywords(value string) []string {
+	var keywords []string
+	for _, k := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+		if k = strings.TrimSpace(k); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords
+}
+
+func parsePDFDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if len(value) >= 10 {
+		return time.Parse("2006-01-02", value[:10])
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parsePages splits pdftotext output into pages (separated by form feeds),
+// picks out headings and joins the hard-wrapped lines of each paragraph.
+func parsePages(text string) ([]Page, []Heading) {
+	raw := strings.Split(text, "\f")
+	// pdftotext ends every page with a form feed
+	if len(raw) > 1 && strings.TrimSpace(raw[len(raw)-1]) == "" {
+		raw = raw[:len(raw)-1]
+	}
+
+	var pages []Page
+	var headings []Heading
+	seen := map[string]bool{}
+	for i, content := range raw {
+		number := i + 1
+		var paragraphs []string
+		var current []string
+		flush := func() {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, joinLines(current))
+				current = nil
+			}
+		}
+		for _, line := range strings.Split(content, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				flush()
+				continue
+			}
+			if level, title := headingLevel(line); level > 0 {
+				flush()
+				paragraphs = append(paragraphs, line)
+				// Running headers repeat on every page
+				if key := strings.ToLower(title); !seen[key] {
+					seen[key] = true
+					headings = append(headings, Heading{Level: level, Text: line, Page: number})
+				}
+				continue
+			}
+			current = append(current, line)
+		}
+		flush()
+		pages = append(pages, Page{Number: number, Text: strings.Join(paragraphs, "\n")})
+	}
+	return pages, headings
+}
+
+// joinLines joins the lines of a paragraph, undoing hyphenation at line ends.
+func joinLines(lines []string) string {
+	joined := lines[0]
+	for _, line := range lines[1:] {
+		prev, _ := utf8.DecodeLastRuneInString(joined)
+		next, _ := utf8.DecodeRuneInString(line)
+		switch {
+		case prev == '-' && unicode.IsLower(next) && endsInLetterHyphen(joined):
+			joined = strings.TrimSuffix(joined, "-") + line
+		case isCJK(prev) || isCJK(next):
+			// No spaces between Chinese or Japanese lines
+			joined += line
+		default:
+			joined += " " + line
+		}
+	}
+	return joined
+}
+
+// endsInLetterHyphen reports whether s ends in a word broken by a hyphen, as
+// opposed to a dash on its own.
+func endsInLetterHyphen(s string) bool {
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimSuffix(s, "-"))
+	return unicode.IsLetter(r)
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+var (
+	// "2 Methods", "3.1 Data collection", "4.2.1. Results"
+	reNumberedHeading = regexp.MustCompile(`^(\d{1,2}(?:\.\d{1,2}){0,3})\.?\s+(\p{Lu}.*)$`)
+	// "IV. EXPERIMENTS"
+	reRomanHeading = regexp.MustCompile(`^([IVX]{1,5})\.\s+(\p{Lu}.*)$`)
+	// "第三章 方法", "二、相关工作"
+	reChineseHeading = regexp.MustCompile(`^(第[一二三四五六七八九十百\d]+[章节部分]|[一二三四五六七八九十]+、)\s*(\S.*)$`)
+)
+
+// Unnumbered section titles common in papers and reports.
+var knownHeadings = map[string]bool{
+	"abstract": true, "introduction": true, "background": true, "related work": true,
+	"method": true, "methods": true, "methodology": true, "results": true,
+	"discussion": true, "conclusion": true, "conclusions": true, "limitations": true,
+	"acknowledgments": true, "acknowledgements": true, "references": true,
+	"bibliography": true, "appendix": true, "summary": true,
+	"摘要": true, "引言": true, "结论": true, "参考文献": true,
+}
+
+// headingLevel reports whether line looks like a section title and at which level.
+// PDFs carry no structure once converted to text, so this only catches numbered
+// titles and well-known section names.
+func headingLevel(line string) (int, string) {
+	if utf8.RuneCountInString(line) > 80 || strings.ContainsAny(line[len(line)-1:], ".,;:") {
+		return 0, ""
+	}
+	if knownHeadings[strings.ToLower(line)] {
+		return 1, line
+	}
+	if m := reNumberedHeading.FindStringSubmatch(line); m != nil && len(strings.Fields(m[2])) <= 12 && !looksNumeric(m[2]) {
+		return strings.Count(m[1], ".") + 1, m[2]
+	}
+	if m := reRomanHeading.FindStringSubmatch(line); m != nil && len(strings.Fields(m[2])) <= 12 {
+		return 1, m[2]
+	}
+	if m := reChineseHeading.FindStringSubmatch(line); m != nil {
+		level := 1
+		if strings.HasSuffix(m[1], "节") {
+			level = 2
+		}
+		return level, m[2]
+	}
+	return 0, ""
+}
+
+// looksNumeric catches table rows such as "3 A 0.52 0.61" that start like a heading.
+func looksNumeric(s string) bool {
+	digits := 0
+	for _, f := range strings.Fields(s) {
+		if _, err := strconv.ParseFloat(strings.TrimSuffix(f, "%"), 64); err == nil {
+			digits++
+		}
+	}
+	return digits >= 2
+}
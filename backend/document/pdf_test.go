@@ -0,0 +1,154 @@
+package document
+
+import (
+	"Varys/backend/dependency"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const samplePDFText = "A Study of Things\nJane Doe\n\nAbstract\nWe study things and the\nways they work.\n\n1 Introduction\nThings are every-\nwhere, and this is well\nknown.\n\f" +
+	"1.1 Motivation\nWhy things matter.\n\nA Study of Things\n\n2 Methods\nWe counted.\n\nTable 1\n3 A 0.52 0.61\n\f"
+
+const samplePDFInfo = `Title:          A Study of Things
+Subject:        Things
+Keywords:       things, counting; methods
+Author:         Jane Doe
+Creator:        LaTeX with hyperref
+Producer:       pdfTeX-1.40.25
+CreationDate:   2024-03-05T10:20:30+01:00
+Pages:          2
+Encrypted:      no
+`
+
+// mockPoppler puts fake pdftotext and pdfinfo binaries on PATH.
+func mockPoppler(t *testing.T, text, info string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows mock not implemented")
+	}
+	binDir := t.TempDir()
+	textFile := filepath.Join(binDir, "text.txt")
+	infoFile := filepath.Join(binDir, "info.txt")
+	os.WriteFile(textFile, []byte(text), 0644)
+	os.WriteFile(infoFile, []byte(info), 0644)
+	os.WriteFile(filepath.Join(binDir, "pdftotext"), []byte("#!/bin/sh\ncat \""+textFile+"\"\n"), 0755)
+	if info != "" {
+		os.WriteFile(filepath.Join(binDir, "pdfinfo"), []byte("#!/bin/sh\ncat \""+infoFile+"\"\n"), 0755)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestExtractPDF(t *testing.T) {
+	mockPoppler(t, samplePDFText, samplePDFInfo)
+	path := filepath.Join(t.TempDir(), "paper.pdf")
+	os.WriteFile(path, []byte("%PDF-1.7\n"), 0644)
+
+	doc, err := NewExtractor(&dependency.Manager{}).Extract(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Format != FormatPDF || doc.Title != "A Study of Things" || doc.Author != "Jane Doe" || doc.Subject != "Things" {
+		t.Errorf("metadata = %+v", doc)
+	}
+	if want := []string{"things", "counting", "methods"}; !reflect.DeepEqual(doc.Keywords, want) {
+		t.Errorf("keywords = %v, want %v", doc.Keywords, want)
+	}
+	if want := time.Date(2024, 3, 5, 9, 20, 30, 0, time.UTC); !doc.Published.Equal(want) {
+		t.Errorf("published = %v, want %v", doc.Published, want)
+	}
+	if doc.Language != "en" {
+		t.Errorf("language = %q, want en", doc.Language)
+	}
+
+	if len(doc.Pages) != 2 || doc.Pages[1].Number != 2 {
+		t.Fatalf("pages = %+v", doc.Pages)
+	}
+	// Paragraph lines are joined and hyphenation is undone
+	if !strings.Contains(doc.Pages[0].Text, "\nThings are everywhere, and this is well known.") {
+		t.Errorf("page 1 text:\n%s", doc.Pages[0].Text)
+	}
+
+	want := []Heading{
+		{Level: 1, Text: "Abstract", Page: 1},
+		{Level: 1, Text: "1 Introduction", Page: 1},
+		{Level: 2, Text: "1.1 Motivation", Page: 2},
+		{Level: 1, Text: "2 Methods", Page: 2},
+	}
+	if !reflect.DeepEqual(doc.Headings, want) {
+		t.Errorf("headings = %+v\nwant %+v", doc.Headings, want)
+	}
+}
+
+func TestExtractPDF_WithoutMetadata(t *testing.T) {
+	mockPoppler(t, "Just text.\f", "")
+	path := filepath.Join(t.TempDir(), "notes.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4\n"), 0644)
+
+	// pdfinfo may be installed on the machine running the tests
+	dm := &dependency.Manager{}
+	if _, found := dm.CheckSystemDependency("pdfinfo"); found {
+		t.Skip("pdfinfo is installed")
+	}
+	doc, err := NewExtractor(dm).Extract(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "" || len(doc.Pages) != 1 || doc.Pages[0].Text != "Just text." {
+		t.Errorf("doc = %+v", doc)
+	}
+}
+
+func TestExtractPDF_NoText(t *testing.T) {
+	mockPoppler(t, "\f\f", "")
+	path := filepath.Join(t.TempDir(), "scan.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4\n"), 0644)
+
+	if _, err := NewExtractor(&dependency.Manager{}).Extract(context.Background(), path); err == nil || !strings.Contains(err.Error(), "OCR") {
+		t.Errorf("expected a no-text error, got %v", err)
+	}
+}
+
+func TestHeadingLevel(t *testing.T) {
+	tests := map[string]int{
+		"Introduction":               1,
+		"3.2 Data Collection":        2,
+		"4.1.2. Results by Region":   3,
+		"IV. EXPERIMENTS":            1,
+		"第二章 方法":                     1,
+		"第一节 数据":                     2,
+		"参考文献":                       1,
+		"1 This sentence ends.":      0,
+		"2019 Annual Report":         0,
+		"3 A 0.52 0.61":              0,
+		"12 apples were counted":     0,
+		"We counted, and then":       0,
+		"Introduction to the method": 0,
+	}
+	for line, want := range tests {
+		if got, _ := headingLevel(line); got != want {
+			t.Errorf("headingLevel(%q) = %d, want %d", line, got, want)
+		}
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := map[string]string{
+		"Attention Is All You Need":    "Attention Is All You Need",
+		"Microsoft Word - report.docx": "",
+		"Microsoft Word - Annual Plan": "Annual Plan",
+		"main.tex":                     "",
+		"Untitled":                     "",
+	}
+	for in, want := range tests {
+		if got := cleanTitle(in); got != want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
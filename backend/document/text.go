@@ -0,0 +1,191 @@
+package document
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// extractText reads a plain-text file. Hard-wrapped paragraphs are joined and
+// section titles are found the same way as in PDFs.
+func extractText(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pages, headings := parsePages(decodeText(data))
+	var texts []string
+	for _, p := range pages {
+		if p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	// Form feeds in text files are not meaningful pages
+	for i := range headings {
+		headings[i].Page = 0
+	}
+
+	text := strings.Join(texts, "\n")
+	return &Document{
+		Format:   FormatText,
+		Language: guessLanguage(text),
+		Chapters: []Chapter{{Text: text}},
+		Headings: headings,
+	}, nil
+}
+
+// decodeText returns data as UTF-8, converting UTF-16 files (recognized by their
+// byte order mark) and replacing invalid bytes.
+func decodeText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xef\xbb\xbf")):
+		data = data[3:]
+	case bytes.HasPrefix(data, []byte("\xff\xfe")), bytes.HasPrefix(data, []byte("\xfe\xff")):
+		bigEndian := data[0] == 0xfe
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			if bigEndian {
+				units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+			} else {
+				units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+			}
+		}
+		return strings.ReplaceAll(string(utf16.Decode(units)), "\r\n", "\n")
+	}
+	text := string(data)
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, "�")
+	}
+	return strings.ReplaceAll(text, "\r\n", "\n")
+}
+
+var (
+	reATXHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	reCodeFence  = regexp.MustCompile("^(```|~~~)")
+	// Lines that must stay on their own: lists, quotes, tables, rules and HTML
+	reMarkdownBlock = regexp.MustCompile(`^([-*+]\s|\d+[.)]\s|>|\||<|---|\*\*\*|___|!\[|\[\^)`)
+)
+
+// extractMarkdown reads a Markdown file. Properties in YAML frontmatter provide
+// the metadata, and ATX headings ("## Title") the outline.
+func extractMarkdown(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := decodeText(data)
+	doc := &Document{Format: FormatMarkdown}
+
+	if front, body, ok := cutFrontmatter(text); ok {
+		applyMarkdownProperties(doc, front)
+		text = body
+	}
+
+	var lines, paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			lines = append(lines, joinLines(paragraph))
+			paragraph = nil
+		}
+	}
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case reCodeFence.MatchString(trimmed):
+			flush()
+			inFence = !inFence
+			lines = append(lines, line)
+		case inFence:
+			lines = append(lines, line)
+		case trimmed == "":
+			flush()
+		case reATXHeading.MatchString(trimmed):
+			flush()
+			m := reATXHeading.FindStringSubmatch(trimmed)
+			doc.Headings = append(doc.Headings, Heading{Level: len(m[1]), Text: m[2]})
+			if doc.Title == "" && len(m[1]) == 1 {
+				doc.Title = m[2]
+			}
+			lines = append(lines, trimmed)
+		case reMarkdownBlock.MatchString(trimmed):
+			flush()
+			lines = append(lines, line)
+		default:
+			// Hard-wrapped paragraph lines are joined, so each is translated as a whole
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	body := strings.TrimSpace(strings.Join(lines, "\n"))
+	doc.Chapters = []Chapter{{Text: body}}
+	if doc.Language == "" {
+		doc.Language = guessLanguage(body)
+	}
+	return doc, nil
+}
+
+// cutFrontmatter splits a leading "---" YAML block from text.
+func cutFrontmatter(text string) (front, body string, ok bool) {
+	if !strings.HasPrefix(text, "---\n") {
+		return "", text, false
+	}
+	end := strings.Index(text[4:], "\n---")
+	if end < 0 {
+		return "", text, false
+	}
+	front = text[4 : 4+end]
+	body = strings.TrimPrefix(text[4+end+4:], "\n")
+	return front, body, true
+}
+
+// applyMarkdownProperties copies common frontmatter properties into doc. Notes
+// written by Varys itself use the same names.
+func applyMarkdownProperties(doc *Document, front string) {
+	var props map[string]interface{}
+	if yaml.Unmarshal([]byte(front), &props) != nil {
+		return
+	}
+	str := func(keys ...string) string {
+		for _, k := range keys {
+			if v, ok := props[k]; ok && v != nil {
+				switch v := v.(type) {
+				case string:
+					return v
+				case time.Time:
+					return v.Format("2006-01-02")
+				}
+			}
+		}
+		return ""
+	}
+	doc.Title = str("title")
+	doc.Author = str("author", "uploader")
+	doc.Subject = str("description", "summary")
+	doc.Language = normalizeLanguage(str("lang", "language"))
+	if date := str("date", "published", "created"); len(date) >= 10 {
+		if t, err := time.Parse("2006-01-02", date[:10]); err == nil {
+			doc.Published = t
+		}
+	}
+	for _, key := range []string{"tags", "keywords"} {
+		if list, ok := props[key].([]interface{}); ok {
+			for _, v := range list {
+				if s, ok := v.(string); ok && s != "" {
+					doc.Keywords = append(doc.Keywords, s)
+				}
+			}
+		}
+	}
+	if doc.Language != "" && len(doc.Language) != 2 {
+		// e.g. "English" in notes written by Varys: not a language code
+		doc.Language = ""
+	}
+}
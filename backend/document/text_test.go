@@ -0,0 +1,100 @@
+package document
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExtractMarkdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	os.WriteFile(path, []byte(`---
+title: "Design Notes"
+author: Jane
+date: 2023-04-05
+tags: [design, go]
+---
+
+# Ignored Heading
+
+This paragraph is
+hard-wrapped in the
+source.
+
+## Steps
+
+- first item
+- second item
+
+`+"```go\nfunc main() {\n\n}\n```"+`
+
+### Details ###
+Closing words.
+`), 0644)
+
+	doc, err := NewExtractor(nil).Extract(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Format != FormatMarkdown || doc.Title != "Design Notes" || doc.Author != "Jane" || doc.Language != "en" {
+		t.Errorf("metadata = %+v", doc)
+	}
+	if !doc.Published.Equal(time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC)) || !reflect.DeepEqual(doc.Keywords, []string{"design", "go"}) {
+		t.Errorf("metadata = %+v", doc)
+	}
+
+	want := "# Ignored Heading\nThis paragraph is hard-wrapped in the source.\n## Steps\n- first item\n- second item\n```go\nfunc main() {\n\n}\n```\n### Details ###\nClosing words."
+	if got := doc.Text(); got != want {
+		t.Errorf("text:\n%s\nwant:\n%s", got, want)
+	}
+	wantHeadings := []Heading{{Level: 1, Text: "Ignored Heading"}, {Level: 2, Text: "Steps"}, {Level: 3, Text: "Details"}}
+	if !reflect.DeepEqual(doc.Headings, wantHeadings) {
+		t.Errorf("headings = %+v", doc.Headings)
+	}
+}
+
+func TestExtractMarkdown_TitleFromHeading(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readme.md")
+	os.WriteFile(path, []byte("Intro line.\n\n# Project Name\n\nText.\n"), 0644)
+	doc, err := NewExtractor(nil).Extract(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Project Name" {
+		t.Errorf("title = %q", doc.Title)
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "book.txt")
+	os.WriteFile(path, []byte("\xef\xbb\xbfCHAPTER ONE\r\n\r\n1 Introduction\r\nIt was the best of times, it was\r\nthe worst of times.\r\n\r\n第一章 开始\r\n"), 0644)
+
+	doc, err := NewExtractor(nil).Extract(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CHAPTER ONE\n1 Introduction\nIt was the best of times, it was the worst of times.\n第一章 开始"
+	if doc.Format != FormatText || doc.Text() != want {
+		t.Errorf("text = %q", doc.Text())
+	}
+	if len(doc.Headings) != 2 || doc.Headings[0].Text != "1 Introduction" || doc.Headings[0].Page != 0 {
+		t.Errorf("headings = %+v", doc.Headings)
+	}
+
+	// UTF-16 with a byte order mark, as written by some Windows editors
+	utf16 := filepath.Join(dir, "utf16.txt")
+	os.WriteFile(utf16, []byte("\xff\xfeH\x00i\x00!\x00"), 0644)
+	if doc, err := NewExtractor(nil).Extract(context.Background(), utf16); err != nil || doc.Text() != "Hi!" {
+		t.Errorf("UTF-16 text = %v, %v", doc, err)
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	os.WriteFile(empty, []byte("\n \n"), 0644)
+	if _, err := NewExtractor(nil).Extract(context.Background(), empty); err == nil {
+		t.Error("expected an error for a file without text")
+	}
+}
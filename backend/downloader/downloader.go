@@ -2,61 +2,39 @@ package downloader
 
 import (
 	"Varys/backend/dependency"
+	"Varys/backend/process"
+	"Varys/backend/ytdlp"
 	"bufio"
+	"context"
 	"fmt"
-	"os/exec"
+	"os"
 	"path/filepath"
 	"strings"
 )
 
 type Downloader struct {
-	dep *dependency.Manager
+	dep     *dependency.Manager
+	cookies ytdlp.Cookies
 }
 
 func NewDownloader(dep *dependency.Manager) *Downloader {
 	return &Downloader{dep: dep}
 }
 
-// GetVideoTitle fetches the title of the video
-func (d *Downloader) GetVideoTitle(url string) (string, error) {
-	ytPath := d.dep.GetBinaryPath("yt-dlp")
-	if ytPath == "" {
-		return "", fmt.Errorf("yt-dlp not found")
-	}
-
-	cmd := exec.Command(ytPath, "--get-title", "--cookies-from-browser", "chrome", url)
-	// Output() returns standard output only.
-	out, err := cmd.Output()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return "", fmt.Errorf("failed to get title: %s", string(exitErr.Stderr))
-		}
-		return "", fmt.Errorf("failed to get title: %w", err)
-	}
-	return strings.TrimSpace(string(out)), nil
+// NewDownloaderWithCookies creates a downloader that authenticates with the given cookie source.
+func NewDownloaderWithCookies(dep *dependency.Manager, cookies ytdlp.Cookies) *Downloader {
+	return &Downloader{dep: dep, cookies: cookies}
 }
 
-// GetVideoDescription fetches the description of the video
-func (d *Downloader) GetVideoDescription(url string) (string, error) {
-	ytPath := d.dep.GetBinaryPath("yt-dlp")
-	if ytPath == "" {
-		return "", fmt.Errorf("yt-dlp not found")
-	}
-
-	cmd := exec.Command(ytPath, "--get-description", "--cookies-from-browser", "chrome", url)
-	out, err := cmd.Output()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return "", fmt.Errorf("failed to get description: %s", string(exitErr.Stderr))
-		}
-		return "", fmt.Errorf("failed to get description: %w", err)
-	}
-	return strings.TrimSpace(string(out)), nil
+// args prefixes the shared yt-dlp options (cookies) to args.
+func (d *Downloader) args(args ...string) []string {
+	return ytdlp.Args(d.cookies, args...)
 }
 
 // DownloadMedia downloads the media (audio/video) from the given URL to the output directory.
-// Returns the absolute path to the downloaded file.
-func (d *Downloader) DownloadMedia(url string, outputDir string, audioOnly bool, onProgress func(string)) (string, error) {
+// Returns the absolute path to the downloaded file. Cancelling ctx kills yt-dlp (and the
+// ffmpeg it spawns) and removes partial files.
+func (d *Downloader) DownloadMedia(ctx context.Context, url string, outputDir string, audioOnly bool, onProgress func(string)) (string, error) {
 	ytPath := d.dep.GetBinaryPath("yt-dlp")
 	if ytPath == "" {
 		return "", fmt.Errorf("yt-dlp binary not found")
@@ -69,27 +47,25 @@ func (d *Downloader) DownloadMedia(url string, outputDir string, audioOnly bool,
 	if audioOnly {
 		// Audio Mode: Force m4a
 		outputTemplate = filepath.Join(outputDir, tempBase+".%(ext)s")
-		args = []string{
+		args = d.args(
 			"-x", "--audio-format", "m4a",
-			"--cookies-from-browser", "chrome",
 			"--no-playlist", "--newline",
 			"-o", outputTemplate,
 			url,
-		}
+		)
 	} else {
 		// Video Mode: Best mp4
 		// We use -S "ext" to prefer mp4 container for better compatibility
 		outputTemplate = filepath.Join(outputDir, tempBase+".%(ext)s")
-		args = []string{
+		args = d.args(
 			"-f", "bestvideo[ext=mp4]+bestaudio[ext=m4a]/best[ext=mp4]/best",
-			"--cookies-from-browser", "chrome",
 			"--no-playlist", "--newline",
 			"-o", outputTemplate,
 			url,
-		}
+		)
 	}
 
-	cmd := exec.Command(ytPath, args...)
+	cmd := process.Command(ctx, ytPath, args...)
 
 	// Capture stdout for progress
 	stdout, err := cmd.StdoutPipe()
@@ -118,6 +94,10 @@ func (d *Downloader) DownloadMedia(url string, outputDir string, audioOnly bool,
 	}
 
 	if err := cmd.Wait(); err != nil {
+		removeMatching(filepath.Join(outputDir, tempBase+".*"))
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		return "", fmt.Errorf("download command failed: %w", err)
 	}
 
@@ -132,3 +112,12 @@ func (d *Downloader) DownloadMedia(url string, outputDir string, audioOnly bool,
 	// Return the first match (likely the only one)
 	return files[0], nil
 }
+
+// removeMatching deletes files left behind by an interrupted yt-dlp run
+// (.part, .ytdl, fragments and half-converted outputs).
+func removeMatching(pattern string) {
+	files, _ := filepath.Glob(pattern)
+	for _, f := range files {
+		os.Remove(f)
+	}
+}
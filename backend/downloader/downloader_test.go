@@ -2,11 +2,15 @@ package downloader
 
 import (
 	"Varys/backend/dependency"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDownloadMedia(t *testing.T) {
@@ -69,7 +73,7 @@ func TestDownloadMedia(t *testing.T) {
 	// 4. Run Download
 	// We use tempDir as outputDir
 	// audioOnly = true for this test
-	resultPath, err := dl.DownloadMedia("http://fake.url", tempDir, true, nil)
+	resultPath, err := dl.DownloadMedia(context.Background(), "http://fake.url", tempDir, true, nil)
 	if err != nil {
 		t.Fatalf("DownloadMedia failed: %v", err)
 	}
@@ -84,8 +88,8 @@ func TestDownloadMedia(t *testing.T) {
 	}
 }
 
-func TestGetVideoTitle(t *testing.T) {
-	tempDir, err := os.MkdirTemp("", "title_test")
+func TestGetMediaInfo(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "info_test")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -95,7 +99,14 @@ func TestGetVideoTitle(t *testing.T) {
 	os.MkdirAll(binDir, 0755)
 	mockYtPath := filepath.Join(binDir, "yt-dlp")
 
-	scriptContent := "#!/bin/sh\necho 'Mock Video Title'"
+	// Records how often it was called so we can check metadata needs a single probe
+	counter := filepath.Join(tempDir, "calls")
+	scriptContent := fmt.Sprintf(`#!/bin/sh
+echo call >> "%s"
+cat <<'JSON'
+{"id": "abc", "title": "Mock Video Title", "description": "Mock Video Description", "channel": "Mock Channel", "duration": 754.5, "upload_date": "20240105", "view_count": 1234, "tags": ["go", "testing"], "thumbnail": "https://i.example.com/abc.jpg", "extractor_key": "Youtube", "chapters": [{"title": "Intro", "start_time": 0, "end_time": 60}, {"title": "Main", "start_time": 60, "end_time": 754.5}]}
+JSON
+`, counter)
 	if err := os.WriteFile(mockYtPath, []byte(scriptContent), 0755); err != nil {
 		t.Fatal(err)
 	}
@@ -105,28 +116,57 @@ func TestGetVideoTitle(t *testing.T) {
 	os.Setenv("PATH", binDir+string(os.PathListSeparator)+oldPath)
 
 	dl := NewDownloader(&dependency.Manager{})
-	title, err := dl.GetVideoTitle("http://fake.url")
+	info, err := dl.GetMediaInfo(context.Background(), "http://fake.url")
 	if err != nil {
-		t.Fatalf("GetVideoTitle failed: %v", err)
+		t.Fatalf("GetMediaInfo failed: %v", err)
 	}
-	if title != "Mock Video Title" {
-		t.Errorf("Expected 'Mock Video Title', got '%s'", title)
+
+	if info.Title != "Mock Video Title" || info.Description != "Mock Video Description" {
+		t.Errorf("Unexpected title/description: %q / %q", info.Title, info.Description)
+	}
+	if info.Uploader != "Mock Channel" {
+		t.Errorf("Expected uploader to fall back to channel, got %q", info.Uploader)
+	}
+	if info.Duration != 754500*time.Millisecond {
+		t.Errorf("Unexpected duration: %v", info.Duration)
+	}
+	if info.UploadDate.Format("2006-01-02") != "2024-01-05" {
+		t.Errorf("Unexpected upload date: %v", info.UploadDate)
+	}
+	if info.ViewCount != 1234 || len(info.Tags) != 2 || info.Extractor != "youtube" {
+		t.Errorf("Unexpected fields: %+v", info)
+	}
+	if len(info.Chapters) != 2 || info.Chapters[1].Title != "Main" || info.Chapters[1].Start != time.Minute {
+		t.Errorf("Unexpected chapters: %+v", info.Chapters)
+	}
+
+	calls, _ := os.ReadFile(counter)
+	if n := strings.Count(string(calls), "call"); n != 1 {
+		t.Errorf("Expected a single yt-dlp call, got %d", n)
 	}
 }
 
-func TestGetVideoDescription(t *testing.T) {
-	tempDir, err := os.MkdirTemp("", "desc_test")
-	if err != nil {
-		t.Fatal(err)
+func TestParseMediaInfo_Invalid(t *testing.T) {
+	if _, err := ParseMediaInfo([]byte("not json")); err == nil {
+		t.Error("Expected error for invalid JSON")
 	}
-	defer os.RemoveAll(tempDir)
+	if _, err := ParseMediaInfo([]byte(`{"id": "x"}`)); err == nil {
+		t.Error("Expected error for missing title")
+	}
+}
 
+func TestDownloadMedia_Cancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows mock not implemented")
+	}
+	tempDir := t.TempDir()
 	binDir := filepath.Join(tempDir, "bin")
 	os.MkdirAll(binDir, 0755)
-	mockYtPath := filepath.Join(binDir, "yt-dlp")
 
-	scriptContent := "#!/bin/sh\necho 'Mock Video Description'"
-	if err := os.WriteFile(mockYtPath, []byte(scriptContent), 0755); err != nil {
+	// Mock yt-dlp leaves a partial download behind and hangs
+	partFile := filepath.Join(tempDir, "temp_media.m4a.part")
+	script := fmt.Sprintf("#!/bin/sh\necho partial > \"%s\"\nsleep 30\n", partFile)
+	if err := os.WriteFile(filepath.Join(binDir, "yt-dlp"), []byte(script), 0755); err != nil {
 		t.Fatal(err)
 	}
 
@@ -134,12 +174,27 @@ func TestGetVideoDescription(t *testing.T) {
 	defer os.Setenv("PATH", oldPath)
 	os.Setenv("PATH", binDir+string(os.PathListSeparator)+oldPath)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for i := 0; i < 500; i++ {
+			if _, err := os.Stat(partFile); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+	}()
+
+	start := time.Now()
 	dl := NewDownloader(&dependency.Manager{})
-	desc, err := dl.GetVideoDescription("http://fake.url")
-	if err != nil {
-		t.Fatalf("GetVideoDescription failed: %v", err)
+	_, err := dl.DownloadMedia(ctx, "http://fake.url", tempDir, true, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Download took %v to stop", elapsed)
 	}
-	if desc != "Mock Video Description" {
-		t.Errorf("Expected 'Mock Video Description', got '%s'", desc)
+	if left, _ := filepath.Glob(filepath.Join(tempDir, "temp_media.*")); len(left) > 0 {
+		t.Errorf("Partial files not removed: %v", left)
 	}
 }
@@ -0,0 +1,141 @@
+package downloader
+
+import (
+	"Varys/backend/process"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MediaInfo is the metadata of a single video or audio item.
+type MediaInfo struct {
+	ID          string
+	Title       string
+	Description string
+	Uploader    string
+	WebpageURL  string
+	Thumbnail   string
+	Duration    time.Duration
+	UploadDate  time.Time // zero if unknown
+	ViewCount   int64
+	Tags        []string
+	Chapters    []Chapter
+	Extractor   string // yt-dlp extractor, e.g. "youtube"
+	Language    string // declared language of the media, if any
+
+	SubtitleLangs    []string // manually uploaded subtitle tracks
+	AutoCaptionLangs []string // auto-generated caption tracks (including machine translations)
+}
+
+// Chapter is a named section of the media.
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// GetMediaInfo fetches all metadata with a single yt-dlp --dump-single-json call.
+func (d *Downloader) GetMediaInfo(ctx context.Context, url string) (*MediaInfo, error) {
+	ytPath := d.dep.GetBinaryPath("yt-dlp")
+	if ytPath == "" {
+		return nil, fmt.Errorf("yt-dlp not found")
+	}
+
+	cmd := process.Command(ctx, ytPath, d.args("--dump-single-json", "--no-playlist", url)...)
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to get media info: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to get media info: %w", err)
+	}
+	return ParseMediaInfo(out)
+}
+
+// ParseMediaInfo decodes yt-dlp's JSON output.
+func ParseMediaInfo(data []byte) (*MediaInfo, error) {
+	var raw struct {
+		ID          string                     `json:"id"`
+		Title       string                     `json:"title"`
+		Description string                     `json:"description"`
+		Uploader    string                     `json:"uploader"`
+		Channel     string                     `json:"channel"`
+		WebpageURL  string                     `json:"webpage_url"`
+		Thumbnail   string                     `json:"thumbnail"`
+		Duration    float64                    `json:"duration"`
+		UploadDate  string                     `json:"upload_date"`
+		Timestamp   float64                    `json:"timestamp"`
+		ViewCount   int64                      `json:"view_count"`
+		Tags        []string                   `json:"tags"`
+		Extractor   string                     `json:"extractor_key"`
+		Language    string                     `json:"language"`
+		Subtitles   map[string]json.RawMessage `json:"subtitles"`
+		AutoCaps    map[string]json.RawMessage `json:"automatic_captions"`
+		Chapters    []struct {
+			Title     string  `json:"title"`
+			StartTime float64 `json:"start_time"`
+			EndTime   float64 `json:"end_time"`
+		} `json:"chapters"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid media info: %w", err)
+	}
+	if strings.TrimSpace(raw.Title) == "" {
+		return nil, fmt.Errorf("media info has no title")
+	}
+
+	info := &MediaInfo{
+		ID:          raw.ID,
+		Title:       strings.TrimSpace(raw.Title),
+		Description: strings.TrimSpace(raw.Description),
+		Uploader:    firstNonEmpty(raw.Uploader, raw.Channel),
+		WebpageURL:  raw.WebpageURL,
+		Thumbnail:   raw.Thumbnail,
+		Duration:    seconds(raw.Duration),
+		ViewCount:   raw.ViewCount,
+		Tags:        raw.Tags,
+		Extractor:   strings.ToLower(raw.Extractor),
+		Language:    raw.Language,
+
+		SubtitleLangs:    trackLangs(raw.Subtitles),
+		AutoCaptionLangs: trackLangs(raw.AutoCaps),
+	}
+	if raw.UploadDate != "" {
+		info.UploadDate, _ = time.Parse("20060102", raw.UploadDate)
+	}
+	if info.UploadDate.IsZero() && raw.Timestamp > 0 {
+		info.UploadDate = time.Unix(int64(raw.Timestamp), 0).UTC()
+	}
+	for _, c := range raw.Chapters {
+		info.Chapters = append(info.Chapters, Chapter{
+			Title: c.Title,
+			Start: seconds(c.StartTime),
+			End:   seconds(c.EndTime),
+		})
+	}
+	return info, nil
+}
+
+// trackLangs returns the sorted language codes of a yt-dlp subtitle map.
+func trackLangs(tracks map[string]json.RawMessage) []string {
+	var langs []string
+	for lang := range tracks {
+		if lang == "live_chat" {
+			continue
+		}
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,254 @@
+package downloader
+
+import (
+	"Varys/backend/process"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PlaylistEntry is a single item of a playlist or channel listing.
+type PlaylistEntry struct {
+	ID         string
+	Title      string
+	URL        string
+	UploadDate time.Time // zero when the site does not expose it in flat listings
+	Duration   float64   // seconds, 0 if unknown
+}
+
+// Playlist is the flat listing of a playlist or channel.
+type Playlist struct {
+	ID       string
+	Title    string
+	URL      string
+	Uploader string
+	Entries  []PlaylistEntry
+}
+
+// PlaylistFilter narrows down which entries of a playlist are processed.
+// Zero values disable the corresponding filter.
+type PlaylistFilter struct {
+	After        time.Time      // keep entries uploaded on or after this day
+	Before       time.Time      // keep entries uploaded on or before this day
+	MaxItems     int            // keep at most this many entries (after the other filters)
+	TitlePattern *regexp.Regexp // keep entries whose title matches
+}
+
+// ListPlaylist lists the entries of a playlist or channel without downloading anything
+// (yt-dlp --flat-playlist --dump-json).
+func (d *Downloader) ListPlaylist(ctx context.Context, playlistURL string) (*Playlist, error) {
+	ytPath := d.dep.GetBinaryPath("yt-dlp")
+	if ytPath == "" {
+		return nil, fmt.Errorf("yt-dlp not found")
+	}
+
+	cmd := process.Command(ctx, ytPath, d.args("--flat-playlist", "--dump-json", playlistURL)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start yt-dlp: %w", err)
+	}
+
+	playlist := &Playlist{URL: playlistURL}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var raw flatEntry
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			continue
+		}
+		if playlist.ID == "" {
+			playlist.ID = raw.PlaylistID
+		}
+		if playlist.Title == "" {
+			playlist.Title = raw.PlaylistTitle
+		}
+		if playlist.Uploader == "" {
+			playlist.Uploader = firstNonEmpty(raw.PlaylistUploader, raw.PlaylistChannel, raw.Channel, raw.Uploader)
+		}
+		if entry, ok := raw.entry(); ok {
+			playlist.Entries = append(playlist.Entries, entry)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to list playlist: %s", strings.TrimSpace(stderr.String()))
+	}
+	if len(playlist.Entries) == 0 {
+		return nil, fmt.Errorf("no entries found in playlist")
+	}
+	if playlist.Title == "" {
+		playlist.Title = firstNonEmpty(playlist.Uploader, playlist.ID, "Playlist")
+	}
+	return playlist, nil
+}
+
+// Filter returns the entries that pass f, in playlist order.
+// Entries without an upload date are kept when a date range is set, since flat
+// listings of some sites do not include dates.
+func (p *Playlist) Filter(f PlaylistFilter) []PlaylistEntry {
+	var entries []PlaylistEntry
+	for _, e := range p.Entries {
+		if f.MaxItems > 0 && len(entries) >= f.MaxItems {
+			break
+		}
+		if !e.UploadDate.IsZero() {
+			day := e.UploadDate.Truncate(24 * time.Hour)
+			if !f.After.IsZero() && day.Before(f.After.Truncate(24*time.Hour)) {
+				continue
+			}
+			if !f.Before.IsZero() && day.After(f.Before.Truncate(24*time.Hour)) {
+				continue
+			}
+		}
+		if f.TitlePattern != nil && !f.TitlePattern.MatchString(e.Title) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Undated counts the entries the date range of f could not be applied to, because
+// the listing has no upload date for them. It is 0 when f has no date range.
+func (f PlaylistFilter) Undated(entries []PlaylistEntry) int {
+	if f.After.IsZero() && f.Before.IsZero() {
+		return 0
+	}
+	n := 0
+	for _, e := range entries {
+		if e.UploadDate.IsZero() {
+			n++
+		}
+	}
+	return n
+}
+
+// IsPlaylistURL reports whether the URL points to a playlist or channel rather than a
+// single video. A watch URL that merely carries a list parameter counts as a single video.
+func IsPlaylistURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	path := strings.TrimSuffix(u.Path, "/")
+
+	switch host {
+	case "youtube.com", "music.youtube.com":
+		if path == "/playlist" {
+			return u.Query().Get("list") != ""
+		}
+		return strings.HasPrefix(path, "/@") ||
+			strings.HasPrefix(path, "/channel/") ||
+			strings.HasPrefix(path, "/c/") ||
+			strings.HasPrefix(path, "/user/")
+	case "space.bilibili.com":
+		return true
+	case "bilibili.com":
+		return strings.Contains(path, "/channel/collectiondetail") || strings.Contains(path, "/medialist/")
+	}
+	return false
+}
+
+// flatEntry is the subset of a yt-dlp --flat-playlist JSON line we use.
+type flatEntry struct {
+	ID               string  `json:"id"`
+	Title            string  `json:"title"`
+	URL              string  `json:"url"`
+	WebpageURL       string  `json:"webpage_url"`
+	IEKey            string  `json:"ie_key"`
+	UploadDate       string  `json:"upload_date"`
+	Timestamp        float64 `json:"timestamp"`
+	ReleaseTimestamp float64 `json:"release_timestamp"`
+	Duration         float64 `json:"duration"`
+	Uploader         string  `json:"uploader"`
+	Channel          string  `json:"channel"`
+	PlaylistID       string  `json:"playlist_id"`
+	PlaylistTitle    string  `json:"playlist_title"`
+	PlaylistUploader string  `json:"playlist_uploader"`
+	PlaylistChannel  string  `json:"playlist_channel"`
+}
+
+func (r flatEntry) entry() (PlaylistEntry, bool) {
+	link := firstNonEmpty(r.WebpageURL, r.URL)
+	if link != "" && !strings.Contains(link, "://") && r.IEKey == "Youtube" {
+		link = "https://www.youtube.com/watch?v=" + link
+	}
+	if link == "" {
+		return PlaylistEntry{}, false
+	}
+
+	var uploaded time.Time
+	if r.UploadDate != "" {
+		uploaded, _ = time.Parse("20060102", r.UploadDate)
+	}
+	if uploaded.IsZero() && r.Timestamp > 0 {
+		uploaded = time.Unix(int64(r.Timestamp), 0).UTC()
+	}
+	if uploaded.IsZero() && r.ReleaseTimestamp > 0 {
+		uploaded = time.Unix(int64(r.ReleaseTimestamp), 0).UTC()
+	}
+
+	return PlaylistEntry{
+		ID:         r.ID,
+		Title:      firstNonEmpty(r.Title, r.ID),
+		URL:        link,
+		UploadDate: uploaded,
+		Duration:   r.Duration,
+	}, true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ParsePlaylistFilter builds a filter from user input. Dates use YYYY-MM-DD; empty
+// strings and zero disable the corresponding filter.
+func ParsePlaylistFilter(after, before string, maxItems int, titlePattern string) (PlaylistFilter, error) {
+	var f PlaylistFilter
+	var err error
+	if after != "" {
+		if f.After, err = time.Parse("2006-01-02", after); err != nil {
+			return f, fmt.Errorf("invalid start date %q (use YYYY-MM-DD)", after)
+		}
+	}
+	if before != "" {
+		if f.Before, err = time.Parse("2006-01-02", before); err != nil {
+			return f, fmt.Errorf("invalid end date %q (use YYYY-MM-DD)", before)
+		}
+	}
+	if maxItems < 0 {
+		return f, fmt.Errorf("max items must not be negative")
+	}
+	f.MaxItems = maxItems
+	if titlePattern != "" {
+		if f.TitlePattern, err = regexp.Compile(titlePattern); err != nil {
+			return f, fmt.Errorf("invalid title pattern: %w", err)
+		}
+	}
+	return f, nil
+}
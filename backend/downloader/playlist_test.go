@@ -0,0 +1,145 @@
+package downloader
+
+import (
+	"Varys/backend/dependency"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestListPlaylist(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows mock not implemented")
+	}
+	binDir := t.TempDir()
+	script := `#!/bin/sh
+cat <<'JSON'
+{"id": "a1", "title": "Lesson 1", "url": "https://www.youtube.com/watch?v=a1", "upload_date": "20240105", "playlist_id": "PL1", "playlist_title": "Go Course", "playlist_uploader": "Teacher"}
+not json
+{"id": "a2", "title": "Lesson 2", "url": "a2", "ie_key": "Youtube", "timestamp": 1706745600, "playlist_id": "PL1", "playlist_title": "Go Course"}
+{"id": "a3", "title": "Lesson 3", "url": "https://www.youtube.com/watch?v=a3"}
+JSON
+`
+	if err := os.WriteFile(filepath.Join(binDir, "yt-dlp"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dl := NewDownloader(&dependency.Manager{})
+	playlist, err := dl.ListPlaylist(context.Background(), "https://www.youtube.com/playlist?list=PL1")
+	if err != nil {
+		t.Fatalf("ListPlaylist failed: %v", err)
+	}
+
+	if playlist.Title != "Go Course" || playlist.ID != "PL1" || playlist.Uploader != "Teacher" {
+		t.Errorf("Unexpected playlist metadata: %+v", playlist)
+	}
+	if len(playlist.Entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(playlist.Entries))
+	}
+	if got := playlist.Entries[1].URL; got != "https://www.youtube.com/watch?v=a2" {
+		t.Errorf("Expected bare ID to be expanded, got %s", got)
+	}
+	if got := playlist.Entries[0].UploadDate; !got.Equal(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected upload date: %v", got)
+	}
+	if got := playlist.Entries[1].UploadDate; !got.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected timestamp date: %v", got)
+	}
+}
+
+func TestPlaylistFilter(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	playlist := &Playlist{Entries: []PlaylistEntry{
+		{Title: "Intro", UploadDate: day("2024-01-01")},
+		{Title: "Lesson 1", UploadDate: day("2024-02-01")},
+		{Title: "Lesson 2", UploadDate: day("2024-03-01").Add(15 * time.Hour)},
+		{Title: "Lesson 3"},
+		{Title: "Lesson 4", UploadDate: day("2024-05-01")},
+	}}
+
+	titles := func(entries []PlaylistEntry) []string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.Title)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		filter PlaylistFilter
+		want   []string
+	}{
+		{"none", PlaylistFilter{}, []string{"Intro", "Lesson 1", "Lesson 2", "Lesson 3", "Lesson 4"}},
+		{"date range inclusive, undated kept", PlaylistFilter{After: day("2024-02-01"), Before: day("2024-03-01")}, []string{"Lesson 1", "Lesson 2", "Lesson 3"}},
+		{"title", PlaylistFilter{TitlePattern: regexp.MustCompile(`^Lesson [13]`)}, []string{"Lesson 1", "Lesson 3"}},
+		{"max items after filters", PlaylistFilter{TitlePattern: regexp.MustCompile(`Lesson`), MaxItems: 2}, []string{"Lesson 1", "Lesson 2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := titles(playlist.Filter(tt.filter))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+
+	if n := (PlaylistFilter{After: day("2024-02-01")}).Undated(playlist.Entries); n != 1 {
+		t.Errorf("Expected 1 undated entry, got %d", n)
+	}
+	if n := (PlaylistFilter{MaxItems: 2}).Undated(playlist.Entries); n != 0 {
+		t.Errorf("Expected no undated count without a date range, got %d", n)
+	}
+}
+
+func TestParsePlaylistFilter(t *testing.T) {
+	if _, err := ParsePlaylistFilter("2024-13-01", "", 0, ""); err == nil {
+		t.Error("Expected error for invalid date")
+	}
+	if _, err := ParsePlaylistFilter("", "", 0, "("); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+	if _, err := ParsePlaylistFilter("", "", -1, ""); err == nil {
+		t.Error("Expected error for negative max items")
+	}
+	f, err := ParsePlaylistFilter("2024-01-01", "2024-12-31", 5, "(?i)lesson")
+	if err != nil {
+		t.Fatalf("ParsePlaylistFilter failed: %v", err)
+	}
+	if f.MaxItems != 5 || f.After.Year() != 2024 || !f.TitlePattern.MatchString("LESSON 1") {
+		t.Errorf("Unexpected filter: %+v", f)
+	}
+}
+
+func TestIsPlaylistURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://www.youtube.com/playlist?list=PL123":    true,
+		"https://www.youtube.com/@GoTalks/videos":        true,
+		"https://youtube.com/channel/UC123":              true,
+		"https://www.youtube.com/watch?v=abc&list=PL123": false,
+		"https://www.youtube.com/watch?v=abc":            false,
+		"https://youtu.be/abc":                           false,
+		"https://space.bilibili.com/12345":               true,
+		"https://www.bilibili.com/video/BV1xx":           false,
+		"/Users/me/Movies/lecture.mp4":                   false,
+		"https://example.com/blog/post":                  false,
+	}
+	for url, want := range tests {
+		if got := IsPlaylistURL(url); got != want {
+			t.Errorf("IsPlaylistURL(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package downloader
+
+import (
+	"Varys/backend/process"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Transcript sources, in the order of preference they describe.
+const (
+	SubtitlesManual  = "manual"  // uploaded subtitles, otherwise whisper
+	SubtitlesAuto    = "auto"    // uploaded subtitles, then auto-generated captions, otherwise whisper
+	SubtitlesWhisper = "whisper" // always transcribe locally
+)
+
+// SubtitleTrack identifies a subtitle track offered by the platform.
+type SubtitleTrack struct {
+	Lang string // yt-dlp language code, e.g. "en", "zh-Hans", "en-orig"
+	Auto bool   // auto-generated captions
+}
+
+// Language returns the base language code of the track ("zh-Hans" -> "zh", "en-orig" -> "en").
+func (t SubtitleTrack) Language() string {
+	lang := strings.ToLower(strings.TrimSuffix(t.Lang, "-orig"))
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// SubtitleTrack picks the track in the media's own language for the given preference.
+// Auto-generated captions are only used with SubtitlesAuto, and only when the original
+// language can be told apart from the machine translated tracks.
+func (m *MediaInfo) SubtitleTrack(preference string) (SubtitleTrack, bool) {
+	if preference == SubtitlesWhisper {
+		return SubtitleTrack{}, false
+	}
+
+	if lang, ok := pickTrack(m.SubtitleLangs, m.Language, true); ok {
+		return SubtitleTrack{Lang: lang}, true
+	}
+	if preference != SubtitlesAuto {
+		return SubtitleTrack{}, false
+	}
+	if lang, ok := pickTrack(m.AutoCaptionLangs, m.Language, false); ok {
+		return SubtitleTrack{Lang: lang, Auto: true}, true
+	}
+	return SubtitleTrack{}, false
+}
+
+// pickTrack prefers an "-orig" track, then the declared media language. When the
+// media language is unknown and lonely is true, a single track is accepted as-is;
+// a track in another language than the declared one is a translation.
+func pickTrack(langs []string, mediaLang string, lonely bool) (string, bool) {
+	for _, lang := range langs {
+		if strings.HasSuffix(lang, "-orig") {
+			return lang, true
+		}
+	}
+	if mediaLang != "" {
+		for _, lang := range langs {
+			if strings.EqualFold(lang, mediaLang) {
+				return lang, true
+			}
+		}
+		base := (SubtitleTrack{Lang: mediaLang}).Language()
+		for _, lang := range langs {
+			if (SubtitleTrack{Lang: lang}).Language() == base {
+				return lang, true
+			}
+		}
+	}
+	if lonely && mediaLang == "" && len(langs) == 1 {
+		return langs[0], true
+	}
+	return "", false
+}
+
+// DownloadSubtitles fetches a single subtitle track as WebVTT into outputDir without
+// downloading the media. Returns the path to the .vtt file.
+func (d *Downloader) DownloadSubtitles(ctx context.Context, url, outputDir string, track SubtitleTrack) (string, error) {
+	ytPath := d.dep.GetBinaryPath("yt-dlp")
+	if ytPath == "" {
+		return "", fmt.Errorf("yt-dlp binary not found")
+	}
+
+	tempBase := "temp_subs"
+	args := d.args(
+		"--skip-download",
+		"--sub-format", "vtt",
+		"--sub-langs", track.Lang,
+		"--no-playlist",
+		"-o", filepath.Join(outputDir, tempBase+".%(ext)s"),
+	)
+	if track.Auto {
+		args = append(args, "--write-auto-subs")
+	} else {
+		args = append(args, "--write-subs")
+	}
+	args = append(args, url)
+
+	out, err := process.Command(ctx, ytPath, args...).CombinedOutput()
+	if err != nil {
+		removeMatching(filepath.Join(outputDir, tempBase+".*"))
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("subtitle download failed: %s", strings.TrimSpace(string(out)))
+	}
+
+	files, err := filepath.Glob(filepath.Join(outputDir, tempBase+".*.vtt"))
+	if err != nil || len(files) == 0 {
+		return "", fmt.Errorf("no %s subtitles were written", track.Lang)
+	}
+	return files[0], nil
+}
@@ -0,0 +1,79 @@
+package downloader
+
+import (
+	"Varys/backend/dependency"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSubtitleTrack(t *testing.T) {
+	tests := []struct {
+		name       string
+		media      MediaInfo
+		preference string
+		want       SubtitleTrack
+		ok         bool
+	}{
+		{"manual in media language", MediaInfo{Language: "en", SubtitleLangs: []string{"de", "en-US"}}, SubtitlesManual, SubtitleTrack{Lang: "en-US"}, true},
+		{"single manual track", MediaInfo{SubtitleLangs: []string{"zh-Hans"}}, SubtitlesManual, SubtitleTrack{Lang: "zh-Hans"}, true},
+		{"single manual track in another language", MediaInfo{Language: "de", SubtitleLangs: []string{"en"}}, SubtitlesManual, SubtitleTrack{}, false},
+		{"ambiguous manual tracks", MediaInfo{SubtitleLangs: []string{"de", "fr"}}, SubtitlesManual, SubtitleTrack{}, false},
+		{"auto not allowed", MediaInfo{Language: "en", AutoCaptionLangs: []string{"en"}}, SubtitlesManual, SubtitleTrack{}, false},
+		{"auto original track", MediaInfo{AutoCaptionLangs: []string{"de", "en-orig", "fr"}}, SubtitlesAuto, SubtitleTrack{Lang: "en-orig", Auto: true}, true},
+		{"auto in media language", MediaInfo{Language: "ja", AutoCaptionLangs: []string{"en", "ja"}}, SubtitlesAuto, SubtitleTrack{Lang: "ja", Auto: true}, true},
+		{"manual preferred over auto", MediaInfo{Language: "en", SubtitleLangs: []string{"en"}, AutoCaptionLangs: []string{"en"}}, SubtitlesAuto, SubtitleTrack{Lang: "en"}, true},
+		{"whisper", MediaInfo{Language: "en", SubtitleLangs: []string{"en"}}, SubtitlesWhisper, SubtitleTrack{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.media.SubtitleTrack(tt.preference)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("SubtitleTrack() = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	if lang := (SubtitleTrack{Lang: "zh-Hans"}).Language(); lang != "zh" {
+		t.Errorf("Expected zh, got %s", lang)
+	}
+	if lang := (SubtitleTrack{Lang: "en-orig"}).Language(); lang != "en" {
+		t.Errorf("Expected en, got %s", lang)
+	}
+}
+
+func TestDownloadSubtitles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows mock not implemented")
+	}
+	tempDir := t.TempDir()
+	binDir := filepath.Join(tempDir, "bin")
+	os.MkdirAll(binDir, 0755)
+
+	// Writes the VTT file and records its arguments
+	argsFile := filepath.Join(tempDir, "args")
+	script := "#!/bin/sh\necho \"$@\" > \"" + argsFile + "\"\nprintf 'WEBVTT\\n\\n00:00.000 --> 00:01.000\\nhi\\n' > \"" + filepath.Join(tempDir, "temp_subs.en.vtt") + "\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "yt-dlp"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dl := NewDownloader(&dependency.Manager{})
+	path, err := dl.DownloadSubtitles(context.Background(), "http://fake.url", tempDir, SubtitleTrack{Lang: "en", Auto: true})
+	if err != nil {
+		t.Fatalf("DownloadSubtitles failed: %v", err)
+	}
+	if filepath.Base(path) != "temp_subs.en.vtt" {
+		t.Errorf("Unexpected path: %s", path)
+	}
+
+	args, _ := os.ReadFile(argsFile)
+	for _, want := range []string{"--skip-download", "--write-auto-subs", "--sub-langs en", "--sub-format vtt"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("Expected %q in yt-dlp args: %s", want, args)
+		}
+	}
+}
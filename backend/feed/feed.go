@@ -0,0 +1,469 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Feed is a parsed RSS or Atom feed.
+type Feed struct {
+	Title       string
+	Link        string // website of the feed
+	Description string
+	Items       []Item // as listed in the feed, usually newest first
+}
+
+// Item is one post or episode of a feed.
+type Item struct {
+	GUID        string
+	Title       string
+	Link        string // web page of the item
+	Author      string // the item's author, else the feed's author or title
+	Description string
+	Published   time.Time // zero if unknown
+	Duration    time.Duration
+	Image       string
+	Enclosure   string // audio or video file, podcasts only
+	VideoID     string // YouTube channel feeds link to videos without an enclosure
+}
+
+// IsMedia reports whether the item is an episode to download and transcribe
+// rather than an article to scrape.
+func (it Item) IsMedia() bool {
+	return it.Enclosure != "" || it.VideoID != ""
+}
+
+// URL is what gets ingested: the media file of an episode, else the item's page.
+func (it Item) URL() string {
+	if it.Enclosure != "" {
+		return it.Enclosure
+	}
+	return it.Link
+}
+
+// client bounds each fetch, so a stalled feed host cannot hold up a sync.
+var client = &http.Client{Timeout: time.Minute}
+
+// Fetch downloads and parses the feed at rawURL.
+func Fetch(ctx context.Context, rawURL string) (*Feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status code: %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	f.resolve(rawURL)
+	return f, nil
+}
+
+// Namespaces of the extensions we read.
+const (
+	nsITunes  = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	nsMedia   = "http://search.yahoo.com/mrss/"
+	nsContent = "http://purl.org/rss/1.0/modules/content/"
+	nsDC      = "http://purl.org/dc/elements/1.1/"
+	nsYouTube = "http://www.youtube.com/xml/schemas/2015"
+)
+
+// element is any child element. Extensions reuse plain names ("title",
+// "author", "link"), so fields are picked by namespace afterwards.
+type element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []element  `xml:",any"`
+}
+
+// text returns the trimmed text of the first child named local in one of the
+// namespaces; "" stands for the element's own (RSS or Atom) namespace.
+func (e element) text(local string, spaces ...string) string {
+	if c, ok := e.child(local, spaces...); ok {
+		return strings.TrimSpace(c.Text)
+	}
+	return ""
+}
+
+func (e element) child(local string, spaces ...string) (element, bool) {
+	for _, space := range spaces {
+		if space == "" {
+			space = e.XMLName.Space
+		}
+		for _, c := range e.Children {
+			if c.XMLName.Local == local && c.XMLName.Space == space {
+				return c, true
+			}
+		}
+	}
+	return element{}, false
+}
+
+func (e element) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+// Parse reads an RSS 2.0, RSS 1.0 (RDF) or Atom document.
+func Parse(data []byte) (*Feed, error) {
+	var root element
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	dec.CharsetReader = charsetReader
+	if err := dec.Decode(&root); err != nil {
+		return nil, fmt.Errorf("invalid feed: %w", err)
+	}
+	switch strings.ToLower(root.XMLName.Local) {
+	case "rss":
+		channel, ok := root.child("channel", "")
+		if !ok {
+			return nil, fmt.Errorf("invalid feed: no channel")
+		}
+		return parseRSS(channel, channel.Children), nil
+	case "rdf":
+		// RSS 1.0 lists items next to the channel, both in the RSS namespace
+		for _, c := range root.Children {
+			if c.XMLName.Local == "channel" {
+				return parseRSS(c, root.Children), nil
+			}
+		}
+		return nil, fmt.Errorf("invalid feed: no channel")
+	case "feed":
+		return parseAtom(root), nil
+	}
+	return nil, fmt.Errorf("not an RSS or Atom feed (root element <%s>)", root.XMLName.Local)
+}
+
+func parseRSS(channel element, children []element) *Feed {
+	f := &Feed{
+		Title:       channel.text("title", ""),
+		Link:        channel.text("link", ""),
+		Description: channel.text("description", ""),
+	}
+	author := firstOf(channel.text("author", nsITunes), channel.text("creator", nsDC), f.Title)
+	var image string
+	if img, ok := channel.child("image", ""); ok {
+		image = img.text("url", "")
+	}
+	if img, ok := channel.child("image", nsITunes); ok && image == "" {
+		image = img.attr("href")
+	}
+
+	for _, c := range children {
+		if c.XMLName.Local != "item" {
+			continue
+		}
+		it := Item{
+			GUID:        c.text("guid", ""),
+			Title:       c.text("title", ""),
+			Link:        c.text("link", ""),
+			Author:      firstOf(c.text("author", nsITunes), c.text("creator", nsDC), c.text("author", ""), author),
+			Description: firstOf(c.text("description", ""), c.text("summary", nsITunes), c.text("encoded", nsContent)),
+			Published:   parseDate(firstOf(c.text("pubDate", ""), c.text("date", nsDC))),
+			Duration:    parseDuration(c.text("duration", nsITunes)),
+			Image:       image,
+		}
+		if img, ok := c.child("image", nsITunes); ok && img.attr("href") != "" {
+			it.Image = img.attr("href")
+		}
+		if it.Link == "" {
+			// RSS 1.0 items are identified by their about attribute
+			it.Link = c.attr("about")
+		}
+		for _, e := range c.Children {
+			if e.XMLName.Local == "enclosure" && isMediaType(e.attr("type"), e.attr("url")) {
+				it.Enclosure = e.attr("url")
+				break
+			}
+		}
+		if it.Enclosure == "" {
+			it.Enclosure = mediaContent(c)
+		}
+		f.Items = append(f.Items, it)
+	}
+	f.finish()
+	return f
+}
+
+func parseAtom(root element) *Feed {
+	f := &Feed{
+		Title:       root.text("title", ""),
+		Description: root.text("subtitle", ""),
+		Link:        atomLink(root, "alternate"),
+	}
+	author := f.Title
+	if a, ok := root.child("author", ""); ok {
+		author = firstOf(a.text("name", ""), author)
+	}
+	image := firstOf(root.text("logo", ""), root.text("icon", ""))
+
+	for _, c := range root.Children {
+		if c.XMLName.Local != "entry" {
+			continue
+		}
+		it := Item{
+			GUID:        c.text("id", ""),
+			Title:       c.text("title", ""),
+			Link:        atomLink(c, "alternate"),
+			Author:      author,
+			Description: firstOf(c.text("summary", ""), c.text("content", "")),
+			Published:   parseDate(firstOf(c.text("published", ""), c.text("updated", ""))),
+			Image:       image,
+			VideoID:     c.text("videoId", nsYouTube),
+			Enclosure:   atomLink(c, "enclosure"),
+		}
+		if a, ok := c.child("author", ""); ok {
+			it.Author = firstOf(a.text("name", ""), it.Author)
+		}
+		if group, ok := c.child("group", nsMedia); ok {
+			// YouTube puts the video's description and thumbnail in a media group
+			it.Description = firstOf(it.Description, group.text("description", nsMedia))
+			if thumb, ok := group.child("thumbnail", nsMedia); ok {
+				it.Image = firstOf(thumb.attr("url"), it.Image)
+			}
+		}
+		if it.Enclosure == "" && it.VideoID == "" {
+			it.Enclosure = mediaContent(c)
+		}
+		f.Items = append(f.Items, it)
+	}
+	f.finish()
+	return f
+}
+
+// atomLink returns the href of the first link with rel (links without rel are
+// "alternate"). Enclosures must be audio or video.
+func atomLink(e element, rel string) string {
+	for _, c := range e.Children {
+		if c.XMLName.Local != "link" || c.XMLName.Space != e.XMLName.Space {
+			continue
+		}
+		r := c.attr("rel")
+		if r == "" {
+			r = "alternate"
+		}
+		if r != rel {
+			continue
+		}
+		if rel == "enclosure" && !isMediaType(c.attr("type"), c.attr("href")) {
+			continue
+		}
+		return c.attr("href")
+	}
+	return ""
+}
+
+// mediaContent returns the first audio or video <media:content> URL.
+func mediaContent(e element) string {
+	var found string
+	var walk func(element)
+	walk = func(e element) {
+		for _, c := range e.Children {
+			if found != "" {
+				return
+			}
+			if c.XMLName.Space == nsMedia && c.XMLName.Local == "content" {
+				medium := c.attr("medium")
+				if medium == "audio" || medium == "video" || isMediaType(c.attr("type"), "") {
+					found = c.attr("url")
+				}
+			}
+			if c.XMLName.Space == nsMedia && c.XMLName.Local == "group" {
+				walk(c)
+			}
+		}
+	}
+	walk(e)
+	return found
+}
+
+// Extensions of media files whose enclosures declare no usable type.
+var mediaExtensions = map[string]bool{
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true, ".wav": true, ".flac": true,
+	".mp4": true, ".m4v": true, ".mov": true, ".webm": true, ".mkv": true,
+}
+
+func isMediaType(mediaType, rawURL string) bool {
+	mediaType = strings.ToLower(mediaType)
+	if strings.HasPrefix(mediaType, "audio/") || strings.HasPrefix(mediaType, "video/") {
+		return true
+	}
+	if mediaType != "" && mediaType != "application/octet-stream" {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	return err == nil && mediaExtensions[strings.ToLower(path.Ext(u.Path))]
+}
+
+// finish gives every item a GUID and sorts items newest first. Feeds without
+// GUIDs are identified by the item's link or media file, as most readers do.
+func (f *Feed) finish() {
+	for i := range f.Items {
+		it := &f.Items[i]
+		it.Description = plainText(it.Description)
+		if it.GUID == "" {
+			it.GUID = firstOf(it.Link, it.Enclosure)
+		}
+		if it.GUID == "" {
+			sum := sha256.Sum256([]byte(it.Title + "\n" + it.Published.String() + "\n" + it.Description))
+			it.GUID = "sha256:" + hex.EncodeToString(sum[:8])
+		}
+	}
+	sort.SliceStable(f.Items, func(i, j int) bool {
+		return f.Items[i].Published.After(f.Items[j].Published)
+	})
+}
+
+// resolve makes relative links absolute against the feed URL.
+func (f *Feed) resolve(feedURL string) {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return
+	}
+	abs := func(s string) string {
+		if s == "" {
+			return s
+		}
+		u, err := base.Parse(s)
+		if err != nil {
+			return s
+		}
+		return u.String()
+	}
+	f.Link = abs(f.Link)
+	for i := range f.Items {
+		it := &f.Items[i]
+		it.Link, it.Enclosure, it.Image = abs(it.Link), abs(it.Enclosure), abs(it.Image)
+	}
+}
+
+// Date formats seen in feeds. RSS uses RFC 822 in many variants, Atom RFC 3339.
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func parseDate(s string) time.Time {
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return time.Time{}
+	}
+	// Full month and weekday names are common despite the spec
+	if comma := strings.Index(s, ", "); comma > 3 {
+		s = s[:3] + s[comma:]
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseDuration reads itunes:duration, given as seconds, MM:SS or HH:MM:SS.
+func parseDuration(s string) time.Duration {
+	var total float64
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return time.Duration(total * float64(time.Second))
+}
+
+// charsetReader accepts the Latin-1 encodings older feeds declare; everything
+// else is read as UTF-8. Like browsers, Latin-1 is read as windows-1252, whose
+// 0x80-0x9F range holds curly quotes, dashes and €.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "us-ascii":
+		return charmap.Windows1252.NewDecoder().Reader(input), nil
+	}
+	return input, nil
+}
+
+// plainText turns the HTML that show notes and summaries usually contain into
+// text, one line per paragraph.
+func plainText(s string) string {
+	if !strings.Contains(s, "<") {
+		return strings.TrimSpace(html.UnescapeString(s))
+	}
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			var lines []string
+			for _, line := range strings.Split(b.String(), "\n") {
+				if line = strings.Join(strings.Fields(line), " "); line != "" {
+					lines = append(lines, line)
+				}
+			}
+			return strings.Join(lines, "\n")
+		case html.TextToken:
+			b.Write(z.Text())
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "p", "br", "div", "li", "h1", "h2", "h3", "h4", "h5", "h6", "blockquote", "tr":
+				b.WriteByte('\n')
+			}
+		}
+	}
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,232 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const podcastRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+  <title>Deep Talks</title>
+  <link>https://deeptalks.example</link>
+  <atom:link href="https://deeptalks.example/feed.xml" rel="self"/>
+  <itunes:author>Jane Host</itunes:author>
+  <itunes:image href="https://deeptalks.example/cover.jpg"/>
+  <item>
+    <title>Episode 1: Origins</title>
+    <itunes:title>Origins</itunes:title>
+    <guid isPermaLink="false">dt-001</guid>
+    <pubDate>Mon, 2 Jan 2023 08:00:00 +0000</pubDate>
+    <description><![CDATA[<p>We talk about <b>origins</b>.</p><p>Links &amp; notes</p>]]></description>
+    <enclosure url="https://cdn.example/dt/episode.mp3?id=1" length="1234" type="audio/mpeg"/>
+    <itunes:duration>1:02:03</itunes:duration>
+  </item>
+  <item>
+    <title>Episode 2: Futures</title>
+    <guid>dt-002</guid>
+    <pubDate>Tuesday, 10 Jan 2023 08:00:00 GMT</pubDate>
+    <enclosure url="https://cdn.example/dt/episode2" type="video/mp4"/>
+    <itunes:duration>95</itunes:duration>
+    <itunes:image href="https://deeptalks.example/ep2.jpg"/>
+  </item>
+  <item>
+    <title>Show update</title>
+    <link>/blog/update</link>
+    <pubDate>Wed, 04 Jan 2023 08:00:00 +0000</pubDate>
+    <enclosure url="https://deeptalks.example/transcript.pdf" type="application/pdf"/>
+  </item>
+</channel>
+</rss>`
+
+func TestParse_Podcast(t *testing.T) {
+	f, err := Parse([]byte(podcastRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "Deep Talks" || f.Link != "https://deeptalks.example" || len(f.Items) != 3 {
+		t.Fatalf("feed = %+v", f)
+	}
+
+	// Newest first
+	ep2, update, ep1 := f.Items[0], f.Items[1], f.Items[2]
+	if ep1.Title != "Episode 1: Origins" || ep1.GUID != "dt-001" || ep1.Author != "Jane Host" {
+		t.Errorf("episode 1 = %+v", ep1)
+	}
+	if !ep1.IsMedia() || ep1.URL() != "https://cdn.example/dt/episode.mp3?id=1" || ep1.Duration != time.Hour+2*time.Minute+3*time.Second {
+		t.Errorf("episode 1 media = %+v", ep1)
+	}
+	if ep1.Description != "We talk about origins.\nLinks & notes" || ep1.Image != "https://deeptalks.example/cover.jpg" {
+		t.Errorf("episode 1 description = %q, image = %q", ep1.Description, ep1.Image)
+	}
+	if !ep1.Published.Equal(time.Date(2023, 1, 2, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("episode 1 published = %v", ep1.Published)
+	}
+
+	if ep2.Enclosure != "https://cdn.example/dt/episode2" || ep2.Duration != 95*time.Second || ep2.Image != "https://deeptalks.example/ep2.jpg" {
+		t.Errorf("episode 2 = %+v", ep2)
+	}
+	if ep2.Published.IsZero() {
+		t.Error("episode 2: full weekday names should parse")
+	}
+
+	// A PDF attachment is not an episode; the post is an article identified by its link
+	if update.IsMedia() || update.URL() != "/blog/update" || update.GUID != "/blog/update" {
+		t.Errorf("update = %+v", update)
+	}
+}
+
+func TestParse_AtomYouTube(t *testing.T) {
+	f, err := Parse([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns:media="http://search.yahoo.com/mrss/" xmlns="http://www.w3.org/2005/Atom">
+  <title>Some Channel</title>
+  <link rel="alternate" href="https://www.youtube.com/channel/UC1"/>
+  <author><name>Some Channel</name></author>
+  <entry>
+    <id>yt:video:abc123</id>
+    <yt:videoId>abc123</yt:videoId>
+    <title>A Video</title>
+    <link rel="alternate" href="https://www.youtube.com/watch?v=abc123"/>
+    <published>2024-03-01T10:00:00+00:00</published>
+    <media:group>
+      <media:title>A Video</media:title>
+      <media:thumbnail url="https://i.ytimg.com/vi/abc123/hqdefault.jpg" width="480" height="360"/>
+      <media:description>What the video is about.</media:description>
+    </media:group>
+  </entry>
+  <entry>
+    <id>tag:blog,2024:post-1</id>
+    <title type="html">A &lt;em&gt;Post&lt;/em&gt;</title>
+    <link href="https://blog.example/post-1"/>
+    <link rel="enclosure" href="https://blog.example/talk.m4a" type="audio/mp4"/>
+    <updated>2024-02-01T10:00:00Z</updated>
+    <author><name>Ann</name></author>
+    <summary>Short summary.</summary>
+  </entry>
+</feed>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "Some Channel" || f.Link != "https://www.youtube.com/channel/UC1" || len(f.Items) != 2 {
+		t.Fatalf("feed = %+v", f)
+	}
+	video, post := f.Items[0], f.Items[1]
+	if !video.IsMedia() || video.URL() != "https://www.youtube.com/watch?v=abc123" || video.GUID != "yt:video:abc123" {
+		t.Errorf("video = %+v", video)
+	}
+	if video.Description != "What the video is about." || video.Image != "https://i.ytimg.com/vi/abc123/hqdefault.jpg" || video.Author != "Some Channel" {
+		t.Errorf("video metadata = %+v", video)
+	}
+	if post.URL() != "https://blog.example/talk.m4a" || post.Link != "https://blog.example/post-1" || post.Author != "Ann" || post.Published.IsZero() {
+		t.Errorf("post = %+v", post)
+	}
+}
+
+func TestParse_RDF(t *testing.T) {
+	f, err := Parse([]byte(`<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel rdf:about="https://news.example/"><title>News</title><link>https://news.example/</link></channel>
+  <item rdf:about="https://news.example/1">
+    <title>First</title>
+    <link>https://news.example/1</link>
+    <dc:date>2022-05-01T12:00:00Z</dc:date>
+    <dc:creator>Reporter</dc:creator>
+  </item>
+</rdf:RDF>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "News" || len(f.Items) != 1 {
+		t.Fatalf("feed = %+v", f)
+	}
+	if it := f.Items[0]; it.Link != "https://news.example/1" || it.Author != "Reporter" || it.Published.IsZero() || it.IsMedia() {
+		t.Errorf("item = %+v", it)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, data := range []string{"", "<html><body>Not a feed</body></html>", "<rss version=\"2.0\"></rss>"} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%q) should fail", data)
+		}
+	}
+}
+
+func TestParse_GUIDFallback(t *testing.T) {
+	f, err := Parse([]byte(`<rss><channel><title>T</title>
+<item><title>No link</title><description>Only text</description></item>
+<item><title>Same</title><description>Other text</description></item>
+</channel></rss>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Items[0].GUID == "" || f.Items[0].GUID == f.Items[1].GUID {
+		t.Errorf("items without links need distinct GUIDs: %q, %q", f.Items[0].GUID, f.Items[1].GUID)
+	}
+}
+
+func TestParse_Windows1252(t *testing.T) {
+	data := []byte("<?xml version=\"1.0\" encoding=\"windows-1252\"?>\n<rss><channel><title>Caf\xe9 \x93Talks\x94 \x96 \x80</title></channel></rss>")
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Café “Talks” – €"; f.Title != want {
+		t.Errorf("title = %q, want %q", f.Title, want)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(podcastRSS))
+	}))
+	defer srv.Close()
+
+	f, err := Fetch(context.Background(), srv.URL+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Relative links are resolved against the feed URL
+	if got := f.Items[1].Link; got != srv.URL+"/blog/update" {
+		t.Errorf("link = %q", got)
+	}
+
+	if _, err := Fetch(context.Background(), srv.URL+"/missing.xml"); err == nil {
+		t.Error("expected an error for a 404")
+	}
+
+	// A host that stops responding does not hold up the fetch
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer stalled.Close()
+	saved := client
+	client = &http.Client{Timeout: 50 * time.Millisecond}
+	defer func() { client = saved }()
+	if _, err := Fetch(context.Background(), stalled.URL); err == nil {
+		t.Error("expected a timeout for a stalled host")
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":        0,
+		"95":      95 * time.Second,
+		"12:34":   12*time.Minute + 34*time.Second,
+		"1:00:00": time.Hour,
+		"n/a":     0,
+	}
+	for in, want := range cases {
+		if got := parseDuration(in); got != want {
+			t.Errorf("parseDuration(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Subscription is a followed feed and the items already handed to the pipeline.
+type Subscription struct {
+	URL      string    `json:"url"`
+	Title    string    `json:"title"`
+	AddedAt  time.Time `json:"added_at"`
+	SyncedAt time.Time `json:"synced_at"`
+	Seen     []string  `json:"seen"` // GUIDs
+}
+
+// Unseen returns the items not seen before, oldest first, so notes are written
+// in publication order.
+func (s *Subscription) Unseen(items []Item) []Item {
+	seen := make(map[string]bool, len(s.Seen))
+	for _, guid := range s.Seen {
+		seen[guid] = true
+	}
+	var unseen []Item
+	for i := len(items) - 1; i >= 0; i-- {
+		if !seen[items[i].GUID] {
+			unseen = append(unseen, items[i])
+		}
+	}
+	return unseen
+}
+
+// MarkSeen records that the item with guid was handled.
+func (s *Subscription) MarkSeen(guid string) {
+	for _, g := range s.Seen {
+		if g == guid {
+			return
+		}
+	}
+	s.Seen = append(s.Seen, guid)
+}
+
+// Forget drops GUIDs of items that are no longer in the feed, so the state
+// does not grow with every episode ever published.
+func (s *Subscription) Forget(items []Item) {
+	current := make(map[string]bool, len(items))
+	for _, it := range items {
+		current[it.GUID] = true
+	}
+	kept := s.Seen[:0]
+	for _, guid := range s.Seen {
+		if current[guid] {
+			kept = append(kept, guid)
+		}
+	}
+	s.Seen = kept
+}
+
+// State is the list of subscriptions, stored as JSON in the config directory.
+type State struct {
+	path  string
+	Feeds []*Subscription
+}
+
+// OpenState reads the state file at path. A missing file yields no subscriptions.
+func OpenState(path string) (*State, error) {
+	st := &State{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed state: %w", err)
+	}
+	if err := json.Unmarshal(data, &st.Feeds); err != nil {
+		return nil, fmt.Errorf("failed to parse feed state %s: %w", path, err)
+	}
+	return st, nil
+}
+
+// Save writes the state file.
+func (st *State) Save() error {
+	data, err := json.MarshalIndent(st.Feeds, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(st.path), 0755); err != nil {
+		return err
+	}
+	tmp := st.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, st.path)
+}
+
+// Find returns the subscription to url, or nil.
+func (st *State) Find(url string) *Subscription {
+	for _, s := range st.Feeds {
+		if sameURL(s.URL, url) {
+			return s
+		}
+	}
+	return nil
+}
+
+// Add subscribes to url. Items in seen are not ingested by later syncs.
+func (st *State) Add(url, title string, seen []string) (*Subscription, error) {
+	if st.Find(url) != nil {
+		return nil, fmt.Errorf("already subscribed to %s", url)
+	}
+	s := &Subscription{URL: url, Title: title, AddedAt: time.Now(), Seen: seen}
+	if s.Seen == nil {
+		s.Seen = []string{}
+	}
+	st.Feeds = append(st.Feeds, s)
+	return s, nil
+}
+
+// Remove unsubscribes from url and reports whether it was subscribed.
+func (st *State) Remove(url string) bool {
+	for i, s := range st.Feeds {
+		if sameURL(s.URL, url) {
+			st.Feeds = append(st.Feeds[:i], st.Feeds[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func sameURL(a, b string) bool {
+	trim := func(s string) string { return strings.TrimSuffix(strings.TrimSpace(s), "/") }
+	return strings.EqualFold(trim(a), trim(b))
+}
@@ -0,0 +1,64 @@
+package feed
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestState_SaveAndOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "feeds.json")
+	st, err := OpenState(path)
+	if err != nil || len(st.Feeds) != 0 {
+		t.Fatalf("missing state file: %v, %+v", err, st)
+	}
+
+	if _, err := st.Add("https://example.com/feed.xml", "Example", []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Add("https://example.com/feed.xml/", "Again", nil); err == nil {
+		t.Error("expected an error for a duplicate subscription")
+	}
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err = OpenState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := st.Find("HTTPS://example.com/feed.xml")
+	if sub == nil || sub.Title != "Example" || !reflect.DeepEqual(sub.Seen, []string{"a"}) || sub.AddedAt.IsZero() {
+		t.Fatalf("subscription = %+v", sub)
+	}
+
+	if !st.Remove("https://example.com/feed.xml") || st.Remove("https://example.com/feed.xml") || len(st.Feeds) != 0 {
+		t.Errorf("remove failed: %+v", st.Feeds)
+	}
+
+	os.WriteFile(path, []byte("{broken"), 0644)
+	if _, err := OpenState(path); err == nil {
+		t.Error("expected an error for a corrupt state file")
+	}
+}
+
+func TestSubscription_Unseen(t *testing.T) {
+	items := []Item{{GUID: "3"}, {GUID: "2"}, {GUID: "1"}, {GUID: "0"}} // newest first
+	sub := &Subscription{Seen: []string{"0", "2", "gone"}}
+
+	var order []string
+	for _, it := range sub.Unseen(items) {
+		order = append(order, it.GUID)
+	}
+	if !reflect.DeepEqual(order, []string{"1", "3"}) {
+		t.Errorf("unseen = %v, want oldest first", order)
+	}
+
+	sub.MarkSeen("1")
+	sub.MarkSeen("1")
+	sub.Forget(items)
+	if !reflect.DeepEqual(sub.Seen, []string{"0", "2", "1"}) {
+		t.Errorf("seen = %v", sub.Seen)
+	}
+}
@@ -0,0 +1,25 @@
+// Package process starts external tools (yt-dlp, ffmpeg, whisper) so that cancelling
+// the task context stops them together with everything they spawned.
+package process
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// waitDelay bounds how long Wait blocks on output pipes after the process was killed,
+// in case a grandchild inherited them.
+const waitDelay = 2 * time.Second
+
+// Command is exec.CommandContext with the process started in its own group. When ctx
+// is done the whole group is killed, not only the direct child.
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setGroup(cmd)
+	cmd.Cancel = func() error {
+		return killGroup(cmd)
+	}
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
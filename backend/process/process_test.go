@@ -0,0 +1,87 @@
+//go:build !windows
+
+package process
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCommand_CancelKillsGroup(t *testing.T) {
+	tempDir := t.TempDir()
+	pidFile := filepath.Join(tempDir, "child.pid")
+
+	// The shell starts a long running child, like yt-dlp starting ffmpeg
+	script := filepath.Join(tempDir, "tool")
+	content := "#!/bin/sh\nsleep 30 &\necho $! > \"" + pidFile + "\"\nwait\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := Command(ctx, script)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	childPID := waitForPID(t, pidFile)
+	start := time.Now()
+	cancel()
+	err := cmd.Wait()
+
+	if err == nil {
+		t.Fatal("Expected Wait to fail after cancel")
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Fatalf("Unexpected context state: %v", ctx.Err())
+	}
+	if elapsed := time.Since(start); elapsed > waitDelay+time.Second {
+		t.Errorf("Wait took %v after cancel", elapsed)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for alive(childPID) {
+		if time.Now().After(deadline) {
+			syscall.Kill(childPID, syscall.SIGKILL)
+			t.Fatalf("Child process %d survived cancellation", childPID)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func waitForPID(t *testing.T, path string) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+				return pid
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Child never started")
+	return 0
+}
+
+// alive reports whether pid is running. Zombies count as dead: they are
+// waiting for init to reap them after their parent was killed.
+func alive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
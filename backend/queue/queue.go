@@ -0,0 +1,480 @@
+// Package queue runs processing jobs with a bounded number of workers. Queued and
+// failed jobs are persisted so they survive restarts.
+package queue
+
+import (
+	"Varys/backend/service"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Status is the lifecycle state of a job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusDone      Status = "done"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// DefaultWorkers is the number of jobs processed at the same time when not configured.
+const DefaultWorkers = 2
+
+// maxLogLines bounds the log kept in memory per job.
+const maxLogLines = 2000
+
+// ErrNotFound is returned for unknown job IDs.
+var ErrNotFound = errors.New("job not found")
+
+// PlaylistOptions holds the playlist filter of a job in its user-facing form,
+// so it can be persisted and parsed again when the job runs.
+type PlaylistOptions struct {
+	After        string `json:"after,omitempty"`
+	Before       string `json:"before,omitempty"`
+	MaxItems     int    `json:"max_items,omitempty"`
+	TitlePattern string `json:"title_pattern,omitempty"`
+}
+
+// Job is a single URL (or playlist) to process.
+type Job struct {
+	ID         string           `json:"id"`
+	URL        string           `json:"url"`
+	AudioOnly  bool             `json:"audio_only"`
+	Playlist   *PlaylistOptions `json:"playlist,omitempty"` // set for playlist submissions with filters
+	Status     Status           `json:"status"`
+	Progress   float64          `json:"progress"`
+	Result     string           `json:"result,omitempty"` // path of the saved note (or playlist index)
+	Error      string           `json:"error,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+}
+
+// Event types sent to OnEvent.
+const (
+	EventUpdate   = "update"   // job status, progress or result changed; Job is set
+	EventLog      = "log"      // Message is set
+	EventAnalysis = "analysis" // Message holds a streamed analysis token
+)
+
+// Event is emitted for every change of a job.
+type Event struct {
+	Type    string `json:"type"`
+	JobID   string `json:"job_id"`
+	Job     *Job   `json:"job,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Runner processes one job and returns the path of the saved note.
+type Runner func(ctx context.Context, job Job, logger service.EventLogger) (string, error)
+
+// Queue schedules jobs onto at most Workers concurrent runners.
+type Queue struct {
+	path string
+	run  Runner
+
+	// OnEvent receives job events. It is called without the queue lock held.
+	OnEvent func(Event)
+
+	mu      sync.Mutex
+	ctx     context.Context
+	workers int
+	running int
+	jobs    []*Job
+	logs    map[string][]string
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New creates a queue that persists to path (a JSON file) and processes jobs with run.
+// Jobs are not started until Start is called.
+func New(path string, workers int, run Runner) *Queue {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Queue{
+		path:    path,
+		run:     run,
+		workers: workers,
+		logs:    make(map[string][]string),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// Load restores persisted jobs. Jobs that were running when the app stopped are queued again.
+func (q *Queue) Load() error {
+	data, err := os.ReadFile(q.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read job queue: %w", err)
+	}
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return fmt.Errorf("failed to parse job queue: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, job := range jobs {
+		if job.Status == StatusRunning {
+			job.Status = StatusQueued
+			job.Progress = 0
+		}
+		q.jobs = append(q.jobs, job)
+	}
+	return nil
+}
+
+// Start begins processing. Cancelling ctx stops all running jobs.
+func (q *Queue) Start(ctx context.Context) {
+	q.mu.Lock()
+	q.ctx = ctx
+	q.mu.Unlock()
+	q.dispatch()
+}
+
+// Wait blocks until all running jobs have returned.
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// SetWorkers changes the number of concurrent jobs. Running jobs are not interrupted
+// when the limit is lowered.
+func (q *Queue) SetWorkers(n int) {
+	if n <= 0 {
+		n = DefaultWorkers
+	}
+	q.mu.Lock()
+	q.workers = n
+	q.mu.Unlock()
+	q.dispatch()
+}
+
+// Add queues a job for url.
+func (q *Queue) Add(url string, audioOnly bool, playlist *PlaylistOptions) Job {
+	job := &Job{
+		ID:        newID(),
+		URL:       url,
+		AudioOnly: audioOnly,
+		Playlist:  playlist,
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+	}
+	q.mu.Lock()
+	q.jobs = append(q.jobs, job)
+	q.saveLocked()
+	snapshot := *job
+	q.mu.Unlock()
+
+	q.emit(Event{Type: EventUpdate, JobID: job.ID, Job: &snapshot})
+	q.dispatch()
+	return snapshot
+}
+
+// List returns all jobs in submission order.
+func (q *Queue) List() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, len(q.jobs))
+	for i, job := range q.jobs {
+		jobs[i] = *job
+	}
+	return jobs
+}
+
+// Get returns a single job.
+func (q *Queue) Get(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.findLocked(id)
+	if job == nil {
+		return Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+// Logs returns the log lines of a job from this session.
+func (q *Queue) Logs(id string) ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.findLocked(id) == nil {
+		return nil, ErrNotFound
+	}
+	return append([]string(nil), q.logs[id]...), nil
+}
+
+// Cancel stops a running job or drops a queued one.
+func (q *Queue) Cancel(id string) error {
+	q.mu.Lock()
+	job := q.findLocked(id)
+	if job == nil {
+		q.mu.Unlock()
+		return ErrNotFound
+	}
+	switch job.Status {
+	case StatusRunning:
+		// The worker records the cancellation when the runner returns
+		q.cancels[id]()
+		q.mu.Unlock()
+		return nil
+	case StatusQueued:
+		job.Status = StatusCancelled
+		job.FinishedAt = time.Now()
+		q.saveLocked()
+		snapshot := *job
+		q.mu.Unlock()
+		q.emit(Event{Type: EventUpdate, JobID: id, Job: &snapshot})
+		return nil
+	default:
+		q.mu.Unlock()
+		return fmt.Errorf("job is already %s", job.Status)
+	}
+}
+
+// Retry queues a failed or cancelled job again.
+func (q *Queue) Retry(id string) (Job, error) {
+	q.mu.Lock()
+	job := q.findLocked(id)
+	if job == nil {
+		q.mu.Unlock()
+		return Job{}, ErrNotFound
+	}
+	if job.Status != StatusFailed && job.Status != StatusCancelled {
+		q.mu.Unlock()
+		return Job{}, fmt.Errorf("only failed or cancelled jobs can be retried (job is %s)", job.Status)
+	}
+	job.Status = StatusQueued
+	job.Progress = 0
+	job.Error = ""
+	job.Result = ""
+	job.StartedAt = time.Time{}
+	job.FinishedAt = time.Time{}
+	delete(q.logs, id)
+	q.saveLocked()
+	snapshot := *job
+	q.mu.Unlock()
+
+	q.emit(Event{Type: EventUpdate, JobID: id, Job: &snapshot})
+	q.dispatch()
+	return snapshot, nil
+}
+
+// Remove deletes a job that is not running.
+func (q *Queue) Remove(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, job := range q.jobs {
+		if job.ID != id {
+			continue
+		}
+		if job.Status == StatusRunning {
+			return fmt.Errorf("cancel the job before removing it")
+		}
+		q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+		delete(q.logs, id)
+		q.saveLocked()
+		return nil
+	}
+	return ErrNotFound
+}
+
+// ClearFinished removes all done and cancelled jobs. Failed jobs are kept for retrying.
+func (q *Queue) ClearFinished() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	kept := q.jobs[:0]
+	for _, job := range q.jobs {
+		if job.Status == StatusDone || job.Status == StatusCancelled {
+			delete(q.logs, job.ID)
+			continue
+		}
+		kept = append(kept, job)
+	}
+	q.jobs = kept
+	q.saveLocked()
+}
+
+// dispatch starts queued jobs while workers are free.
+func (q *Queue) dispatch() {
+	var started []Job
+	q.mu.Lock()
+	if q.ctx == nil || q.ctx.Err() != nil {
+		q.mu.Unlock()
+		return
+	}
+	for _, job := range q.jobs {
+		if q.running >= q.workers {
+			break
+		}
+		if job.Status != StatusQueued {
+			continue
+		}
+		ctx, cancel := context.WithCancel(q.ctx)
+		q.cancels[job.ID] = cancel
+		q.running++
+		job.Status = StatusRunning
+		job.StartedAt = time.Now()
+		started = append(started, *job)
+
+		q.wg.Add(1)
+		go q.work(ctx, *job)
+	}
+	if len(started) > 0 {
+		q.saveLocked()
+	}
+	q.mu.Unlock()
+
+	for i := range started {
+		q.emit(Event{Type: EventUpdate, JobID: started[i].ID, Job: &started[i]})
+	}
+}
+
+func (q *Queue) work(ctx context.Context, job Job) {
+	defer q.wg.Done()
+
+	result, err := q.run(ctx, job, &jobLogger{q: q, id: job.ID, ctx: ctx})
+	cancelled := ctx.Err() != nil
+
+	q.mu.Lock()
+	q.cancels[job.ID]()
+	delete(q.cancels, job.ID)
+	q.running--
+
+	stored := q.findLocked(job.ID)
+	if stored == nil {
+		q.mu.Unlock()
+		q.dispatch()
+		return
+	}
+	stored.FinishedAt = time.Now()
+	switch {
+	case q.ctx.Err() != nil:
+		// The app is shutting down: keep the job for the next start
+		stored.Status = StatusQueued
+		stored.Progress = 0
+		stored.FinishedAt = time.Time{}
+	case cancelled:
+		stored.Status = StatusCancelled
+	case err != nil:
+		stored.Status = StatusFailed
+		stored.Error = err.Error()
+	default:
+		stored.Status = StatusDone
+		stored.Progress = 100
+		stored.Result = result
+	}
+	q.saveLocked()
+	snapshot := *stored
+	q.mu.Unlock()
+
+	q.emit(Event{Type: EventUpdate, JobID: job.ID, Job: &snapshot})
+	q.dispatch()
+}
+
+func (q *Queue) findLocked(id string) *Job {
+	for _, job := range q.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// saveLocked writes queued, running and failed jobs to disk. Done and cancelled jobs
+// only live for the current session.
+func (q *Queue) saveLocked() {
+	if q.path == "" {
+		return
+	}
+	persisted := make([]*Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		if job.Status == StatusQueued || job.Status == StatusRunning || job.Status == StatusFailed {
+			persisted = append(persisted, job)
+		}
+	}
+	data, err := json.MarshalIndent(persisted, "", "  ")
+	if err != nil {
+		return
+	}
+	// Write to a temp file first so a crash never leaves a truncated queue
+	os.MkdirAll(filepath.Dir(q.path), 0755)
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	os.Rename(tmp, q.path)
+}
+
+func (q *Queue) emit(e Event) {
+	if q.OnEvent != nil {
+		q.OnEvent(e)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// jobLogger implements service.EventLogger for a single job.
+type jobLogger struct {
+	q   *Queue
+	id  string
+	ctx context.Context
+}
+
+func (l *jobLogger) Log(msg string) {
+	if l.ctx.Err() != nil {
+		return
+	}
+	line := fmt.Sprintf("[%s] %s", time.Now().Format("15:04:05"), msg)
+	l.q.mu.Lock()
+	lines := append(l.q.logs[l.id], line)
+	if len(lines) > maxLogLines {
+		lines = lines[len(lines)-maxLogLines:]
+	}
+	l.q.logs[l.id] = lines
+	l.q.mu.Unlock()
+
+	l.q.emit(Event{Type: EventLog, JobID: l.id, Message: msg})
+}
+
+func (l *jobLogger) Progress(percentage float64) {
+	if l.ctx.Err() != nil {
+		return
+	}
+	l.q.mu.Lock()
+	job := l.q.findLocked(l.id)
+	if job == nil {
+		l.q.mu.Unlock()
+		return
+	}
+	job.Progress = percentage
+	snapshot := *job
+	l.q.mu.Unlock()
+
+	l.q.emit(Event{Type: EventUpdate, JobID: l.id, Job: &snapshot})
+}
+
+func (l *jobLogger) AnalysisChunk(token string) {
+	if l.ctx.Err() == nil {
+		l.q.emit(Event{Type: EventAnalysis, JobID: l.id, Message: token})
+	}
+}
+
+func (l *jobLogger) Error(err error) {
+	l.Log(fmt.Sprintf("Error: %v", err))
+}
@@ -0,0 +1,235 @@
+package queue
+
+import (
+	"Varys/backend/service"
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFor polls until cond holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func statusOf(q *Queue, id string) Status {
+	job, _ := q.Get(id)
+	return job.Status
+}
+
+func TestQueue_WorkerLimit(t *testing.T) {
+	var active, peak int32
+	release := make(chan struct{})
+	run := func(ctx context.Context, job Job, logger service.EventLogger) (string, error) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&active, -1)
+		return "/vault/" + job.URL + ".md", nil
+	}
+
+	q := New(filepath.Join(t.TempDir(), "jobs.json"), 2, run)
+	var ids []string
+	for _, url := range []string{"a", "b", "c", "d", "e"} {
+		ids = append(ids, q.Add(url, true, nil).ID)
+	}
+	q.Start(context.Background())
+
+	waitFor(t, "two running jobs", func() bool { return atomic.LoadInt32(&active) == 2 })
+	if statusOf(q, ids[2]) != StatusQueued {
+		t.Errorf("Third job should wait for a free worker, got %s", statusOf(q, ids[2]))
+	}
+	close(release)
+	q.Wait()
+	waitFor(t, "all jobs done", func() bool { return statusOf(q, ids[4]) == StatusDone })
+
+	if peak != 2 {
+		t.Errorf("Expected at most 2 concurrent jobs, peak was %d", peak)
+	}
+	for _, job := range q.List() {
+		if job.Status != StatusDone || job.Result != "/vault/"+job.URL+".md" {
+			t.Errorf("Unexpected job state: %+v", job)
+		}
+	}
+}
+
+func TestQueue_Cancel(t *testing.T) {
+	started := make(chan struct{})
+	run := func(ctx context.Context, job Job, logger service.EventLogger) (string, error) {
+		logger.Log("started " + job.URL)
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	q := New("", 1, run)
+	running := q.Add("a", true, nil)
+	queued := q.Add("b", true, nil)
+	q.Start(context.Background())
+	<-started
+
+	if err := q.Cancel(queued.ID); err != nil {
+		t.Fatal(err)
+	}
+	if statusOf(q, queued.ID) != StatusCancelled {
+		t.Errorf("Queued job should be cancelled immediately, got %s", statusOf(q, queued.ID))
+	}
+	if err := q.Cancel(running.ID); err != nil {
+		t.Fatal(err)
+	}
+	q.Wait()
+	if statusOf(q, running.ID) != StatusCancelled {
+		t.Errorf("Running job should be cancelled, got %s", statusOf(q, running.ID))
+	}
+
+	logs, err := q.Logs(running.ID)
+	if err != nil || len(logs) != 1 {
+		t.Errorf("Expected one log line, got %v (%v)", logs, err)
+	}
+	if err := q.Cancel("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestQueue_PersistAndRetry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	run := func(ctx context.Context, job Job, logger service.EventLogger) (string, error) {
+		if job.URL == "bad" {
+			return "", errors.New("download failed")
+		}
+		return "/vault/note.md", nil
+	}
+
+	q := New(path, 1, run)
+	good := q.Add("good", true, nil)
+	bad := q.Add("bad", false, &PlaylistOptions{MaxItems: 3})
+	pending := q.Add("later", true, nil)
+	q.Cancel(pending.ID)
+	q.Add("queued", true, nil)
+
+	// Persisted before Start: everything still queued except the cancelled job
+	restored := New(path, 1, run)
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(restored.List()); n != 3 {
+		t.Fatalf("Expected 3 persisted jobs, got %d", n)
+	}
+
+	q.Start(context.Background())
+	waitFor(t, "queue drained", func() bool {
+		for _, job := range q.List() {
+			if job.Status == StatusQueued || job.Status == StatusRunning {
+				return false
+			}
+		}
+		return true
+	})
+	q.Wait()
+
+	// Only the failed job survives a restart
+	restored = New(path, 1, run)
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	jobs := restored.List()
+	if len(jobs) != 1 || jobs[0].ID != bad.ID || jobs[0].Status != StatusFailed || jobs[0].Error != "download failed" {
+		t.Fatalf("Unexpected persisted jobs: %+v", jobs)
+	}
+	if jobs[0].Playlist == nil || jobs[0].Playlist.MaxItems != 3 || jobs[0].AudioOnly {
+		t.Errorf("Job options not persisted: %+v", jobs[0])
+	}
+	if _, err := restored.Retry(good.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Done jobs should not be persisted, got %v", err)
+	}
+
+	retried, err := restored.Retry(bad.ID)
+	if err != nil || retried.Status != StatusQueued || retried.Error != "" {
+		t.Fatalf("Retry failed: %+v (%v)", retried, err)
+	}
+	if _, err := restored.Retry(bad.ID); err == nil {
+		t.Error("Expected error when retrying a queued job")
+	}
+}
+
+func TestQueue_ShutdownRequeuesRunningJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	run := func(ctx context.Context, job Job, logger service.EventLogger) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	q := New(path, 1, run)
+	job := q.Add("a", true, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.Start(ctx)
+	waitFor(t, "job running", func() bool { return statusOf(q, job.ID) == StatusRunning })
+	cancel()
+	q.Wait()
+
+	restored := New(path, 1, run)
+	if err := restored.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if statusOf(restored, job.ID) != StatusQueued {
+		t.Errorf("Interrupted job should be queued after restart, got %s", statusOf(restored, job.ID))
+	}
+}
+
+func TestQueue_Events(t *testing.T) {
+	var mu sync.Mutex
+	var events []Event
+	run := func(ctx context.Context, job Job, logger service.EventLogger) (string, error) {
+		logger.Log("working")
+		logger.Progress(50)
+		logger.AnalysisChunk("tok")
+		return "/vault/note.md", nil
+	}
+	q := New("", 1, run)
+	q.OnEvent = func(e Event) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}
+	job := q.Add("a", true, nil)
+	q.Start(context.Background())
+	waitFor(t, "job done", func() bool { return statusOf(q, job.ID) == StatusDone })
+	q.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	var types []string
+	for _, e := range events {
+		if e.JobID != job.ID {
+			t.Errorf("Event for unexpected job: %+v", e)
+		}
+		types = append(types, e.Type)
+	}
+	want := []string{EventUpdate, EventUpdate, EventLog, EventUpdate, EventAnalysis, EventUpdate}
+	if len(types) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, types)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("Expected events %v, got %v", want, types)
+		}
+	}
+	if last := events[len(events)-1].Job; last.Status != StatusDone || last.Result != "/vault/note.md" {
+		t.Errorf("Unexpected final event: %+v", last)
+	}
+}
@@ -1,16 +1,19 @@
 package search
 
-import "fmt"
+import (
+	"Varys/backend/ytdlp"
+	"fmt"
+)
 
 type SearchManager struct {
 	providers map[string]SearchProvider
 }
 
-func NewSearchManager(tavilyAPIKey string) *SearchManager {
+func NewSearchManager(tavilyAPIKey string, cookies ytdlp.Cookies) *SearchManager {
 	m := &SearchManager{
 		providers: make(map[string]SearchProvider),
 	}
-	m.providers["yt-dlp"] = NewYTDLPSearchProvider()
+	m.providers["yt-dlp"] = NewYTDLPSearchProvider(cookies)
 	if tavilyAPIKey != "" {
 		m.providers["tavily"] = NewTavilySearchProvider(tavilyAPIKey)
 	}
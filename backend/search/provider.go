@@ -1,6 +1,9 @@
 package search
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // ContentType defines the type of content being searched
 type ContentType string
@@ -31,8 +34,9 @@ type SearchResult struct {
 	Type        ContentType `json:"type" yaml:"type"`
 }
 
-// SearchProvider is the interface that search service providers must implement
+// SearchProvider is the interface that search service providers must implement.
+// Search must stop (and release any external process) when ctx is cancelled.
 type SearchProvider interface {
 	GetName() string
-	Search(query string, opts SearchOptions) ([]SearchResult, error)
+	Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error)
 }
@@ -1,11 +1,12 @@
 package search
 
 import (
+	"Varys/backend/ytdlp"
 	"testing"
 )
 
 func TestSearchManager(t *testing.T) {
-	sm := NewSearchManager("") // Empty key means tavily is disabled
+	sm := NewSearchManager("", ytdlp.Cookies{}) // Empty key means tavily is disabled
 	
 	providers := sm.ListProviders()
 	foundYTDLP := false
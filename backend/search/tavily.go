@@ -2,6 +2,7 @@ package search
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -45,7 +46,7 @@ type TavilyResponse struct {
 	Results []TavilyResult `json:"results"`
 }
 
-func (p *TavilySearchProvider) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+func (p *TavilySearchProvider) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
 	if p.APIKey == "" {
 		p.APIKey = os.Getenv("TAVILY_API_KEY")
 	}
@@ -62,7 +63,7 @@ func (p *TavilySearchProvider) Search(query string, opts SearchOptions) ([]Searc
 	}
 
 	jsonBody, _ := json.Marshal(reqBody)
-	req, err := http.NewRequest("POST", "https://api.tavily.com/search", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.tavily.com/search", bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, err
 	}
@@ -1,20 +1,24 @@
 package search
 
 import (
+	"Varys/backend/process"
+	"Varys/backend/ytdlp"
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"time"
 )
 
 type YTDLPSearchProvider struct {
-	Name string
+	Name    string
+	Cookies ytdlp.Cookies
 }
 
-func NewYTDLPSearchProvider() *YTDLPSearchProvider {
+func NewYTDLPSearchProvider(cookies ytdlp.Cookies) *YTDLPSearchProvider {
 	return &YTDLPSearchProvider{
-		Name: "yt-dlp",
+		Name:    "yt-dlp",
+		Cookies: cookies,
 	}
 }
 
@@ -22,7 +26,7 @@ func (p *YTDLPSearchProvider) GetName() string {
 	return p.Name
 }
 
-func (p *YTDLPSearchProvider) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+func (p *YTDLPSearchProvider) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
 	limit := opts.Limit
 	if limit <= 0 {
 		limit = 5
@@ -36,15 +40,14 @@ func (p *YTDLPSearchProvider) Search(query string, opts SearchOptions) ([]Search
 		searchQuery = fmt.Sprintf("ytsearchdate%d:%s", limit, query)
 	}
 
-	args := []string{
+	args := ytdlp.Args(p.Cookies,
 		"--dump-json",
-		"--cookies-from-browser", "chrome",
 		"--quiet",
 		searchQuery,
-	}
+	)
 
 	ytPath := "yt-dlp"
-	cmd := exec.Command(ytPath, args...)
+	cmd := process.Command(ctx, ytPath, args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
@@ -116,6 +119,9 @@ func (p *YTDLPSearchProvider) Search(query string, opts SearchOptions) ([]Search
 	}
 
 	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("yt-dlp search command failed: %w", err)
 	}
 
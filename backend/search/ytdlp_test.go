@@ -1,8 +1,14 @@
 package search
 
 import (
+	"Varys/backend/ytdlp"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -72,7 +78,7 @@ func TestParseYTDLPWithUploadDate(t *testing.T) {
 }
 
 func TestYTDLPSearchCommand(t *testing.T) {
-	p := NewYTDLPSearchProvider()
+	p := NewYTDLPSearchProvider(ytdlp.Cookies{Source: ytdlp.CookiesNone})
 	if p == nil {
 		t.Fatal("Failed to create YTDLPSearchProvider")
 	}
@@ -89,3 +95,36 @@ func TestYTDLPSearchCommand(t *testing.T) {
 		t.Errorf("Expected command to start with ytsearch1:, got %s", cmdStr)
 	}
 }
+
+func TestYTDLPSearch_Cancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows mock not implemented")
+	}
+	binDir := t.TempDir()
+	// Mock yt-dlp returns one result and then stalls
+	script := "#!/bin/sh\necho '{\"id\": \"1\", \"title\": \"First\"}'\nsleep 30\n"
+	if err := os.WriteFile(filepath.Join(binDir, "yt-dlp"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+oldPath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := SearchOptions{
+		Limit: 5,
+		OnProgress: func(current, total int) {
+			cancel() // the user quits after the first result arrives
+		},
+	}
+
+	start := time.Now()
+	p := NewYTDLPSearchProvider(ytdlp.Cookies{Source: ytdlp.CookiesNone})
+	_, err := p.Search(ctx, "AI News", opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Search took %v to stop", elapsed)
+	}
+}
@@ -15,6 +15,15 @@ const (
 	
 	// KeyAccountTavily is the account name for Tavily API Key
 	KeyAccountTavily = "tavily_api_key"
+
+	// KeyAccountCompatible is the account name for the OpenAI-compatible server API Key
+	KeyAccountCompatible = "compatible_api_key"
+
+	// KeyAccountOllamaPassword is the account name for the Ollama reverse proxy basic auth password
+	KeyAccountOllamaPassword = "ollama_password"
+
+	// KeyAccountOllamaToken is the account name for the Ollama reverse proxy bearer token
+	KeyAccountOllamaToken = "ollama_token"
 )
 
 // Internal interface for storage to allow mocking
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"Varys/backend/queue"
+	"fmt"
+	"sync"
+)
+
+// Event types streamed to subscribers.
+const (
+	EventLog      = "log"      // Message is set
+	EventProgress = "progress" // Progress is set (0-100)
+	EventAnalysis = "analysis" // Message holds a streamed analysis token
+	EventStatus   = "status"   // Job is set; the stream ends after a finished status
+)
+
+// Event is one message of a job's event stream.
+type Event struct {
+	Type     string     `json:"type"`
+	Message  string     `json:"message,omitempty"`
+	Progress float64    `json:"progress,omitempty"`
+	Job      *queue.Job `json:"job,omitempty"`
+}
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// events are dropped for it.
+const subscriberBuffer = 512
+
+// Broadcaster is a service.EventLogger that fans the events of one job out to
+// any number of subscribers, such as SSE streams. Slow subscribers lose events
+// instead of holding up the pipeline.
+type Broadcaster struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+// NewBroadcaster returns a broadcaster without subscribers.
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving all events published from now on, and
+// a function that ends the subscription.
+func (b *Broadcaster) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+// Publish sends e to every subscriber.
+func (b *Broadcaster) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (b *Broadcaster) Log(msg string) {
+	b.Publish(Event{Type: EventLog, Message: msg})
+}
+
+func (b *Broadcaster) Progress(percent float64) {
+	b.Publish(Event{Type: EventProgress, Progress: percent})
+}
+
+func (b *Broadcaster) AnalysisChunk(token string) {
+	b.Publish(Event{Type: EventAnalysis, Message: token})
+}
+
+func (b *Broadcaster) Error(err error) {
+	b.Publish(Event{Type: EventLog, Message: fmt.Sprintf("Error: %v", err)})
+}
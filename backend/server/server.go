@@ -0,0 +1,443 @@
+// Package server exposes the processing pipeline over a local REST API, so
+// browser extensions and scripts can submit URLs without the desktop app.
+package server
+
+import (
+	"Varys/backend/downloader"
+	"Varys/backend/queue"
+	"Varys/backend/service"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAddr is the address the API listens on when none is given.
+const DefaultAddr = "127.0.0.1:8765"
+
+// keepAliveInterval spaces the comments sent on idle event streams, so proxies
+// and clients do not time them out.
+const keepAliveInterval = 15 * time.Second
+
+// playlistProcessor is implemented by processors that can expand playlists,
+// such as service.CoreService.
+type playlistProcessor interface {
+	ProcessPlaylist(ctx context.Context, url string, opts service.Options, filter downloader.PlaylistFilter, logger service.EventLogger) (*service.PlaylistResult, error)
+}
+
+// Server runs submitted jobs on a queue and serves their status and events.
+type Server struct {
+	proc  service.Processor
+	opts  service.Options
+	token string
+	queue *queue.Queue
+
+	mu      sync.Mutex
+	streams map[string]*jobStream
+}
+
+// jobStream is the broadcaster of a job and the last status published to it.
+type jobStream struct {
+	*Broadcaster
+	status queue.Status
+}
+
+// New creates a server that processes jobs with p, starting from opts for every
+// task. Requests must carry token. Jobs are persisted to queuePath; an empty
+// path keeps them in memory.
+func New(p service.Processor, opts service.Options, token, queuePath string, workers int) *Server {
+	s := &Server{
+		proc:    p,
+		opts:    opts,
+		token:   token,
+		streams: make(map[string]*jobStream),
+	}
+	s.queue = queue.New(queuePath, workers, s.run)
+	s.queue.OnEvent = s.onQueueEvent
+	return s
+}
+
+// Start restores persisted jobs and starts processing. Cancelling ctx stops
+// running jobs; they are queued again on the next start.
+func (s *Server) Start(ctx context.Context) error {
+	if err := s.queue.Load(); err != nil {
+		return err
+	}
+	s.queue.Start(ctx)
+	return nil
+}
+
+// Wait blocks until running jobs have returned after the Start context was cancelled.
+func (s *Server) Wait() {
+	s.queue.Wait()
+}
+
+// Listen binds addr for Serve. Only loopback addresses are accepted: the API can
+// read and write the vault. Binding before Start means a taken port is reported
+// before any restored job begins.
+func Listen(addr string) (net.Listener, error) {
+	if err := CheckLoopback(addr); err != nil {
+		return nil, err
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Serve serves the API on ln until ctx is cancelled. ln is closed on return.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+// CheckLoopback returns an error unless addr ("host:port") is on localhost.
+func CheckLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("refusing to listen on %s: only localhost addresses (127.0.0.1, ::1) are allowed", addr)
+}
+
+// LoadToken returns the API token stored at path, creating a random one on
+// first use. The file is readable by the owner only.
+func LoadToken(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read API token: %w", err)
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to save API token: %w", err)
+	}
+	return token, nil
+}
+
+// Handler returns the API routes:
+//
+//	POST /api/jobs               submit {"url": ..., "audio_only": true, "playlist": {...}}
+//	GET  /api/jobs               list jobs
+//	GET  /api/jobs/{id}          status, result and log of a job
+//	POST /api/jobs/{id}/cancel   cancel a queued or running job
+//	GET  /api/jobs/{id}/events   server-sent events: status, log, progress, analysis
+//
+// Every request needs "Authorization: Bearer TOKEN". Since browsers cannot set
+// headers on EventSource, "?token=TOKEN" is accepted as well.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/jobs", s.handleSubmit)
+	mux.HandleFunc("GET /api/jobs", s.handleList)
+	mux.HandleFunc("GET /api/jobs/{id}", s.handleGet)
+	mux.HandleFunc("POST /api/jobs/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("GET /api/jobs/{id}/events", s.handleEvents)
+	return s.authenticate(mux)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = strings.TrimSpace(bearer)
+		}
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="varys"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SubmitRequest is the body of POST /api/jobs.
+type SubmitRequest struct {
+	URL       string                 `json:"url"`
+	AudioOnly *bool                  `json:"audio_only,omitempty"` // default true
+	Playlist  *queue.PlaylistOptions `json:"playlist,omitempty"`   // filters for playlist and channel URLs
+}
+
+// JobResponse is a job with the log lines of this session.
+type JobResponse struct {
+	queue.Job
+	Logs []string `json:"logs"`
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req SubmitRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, errors.New("url is required"))
+		return
+	}
+	// Local paths are ingested as files, which would let any client read files
+	// of this machine into the vault and send them to the LLM
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, http.StatusBadRequest, errors.New("url must be an http:// or https:// URL"))
+		return
+	}
+	if p := req.Playlist; p != nil {
+		if _, ok := s.proc.(playlistProcessor); !ok {
+			writeError(w, http.StatusBadRequest, errors.New("playlists are not supported"))
+			return
+		}
+		// Validate now so mistakes are reported before the job is queued
+		if _, err := downloader.ParsePlaylistFilter(p.After, p.Before, p.MaxItems, p.TitlePattern); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	audioOnly := true
+	if req.AudioOnly != nil {
+		audioOnly = *req.AudioOnly
+	}
+	job := s.queue.Add(req.URL, audioOnly, req.Playlist)
+	writeJSON(w, http.StatusCreated, job)
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.queue.List())
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	job, err := s.queue.Get(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	logs, _ := s.queue.Logs(id)
+	if logs == nil {
+		logs = []string{}
+	}
+	writeJSON(w, http.StatusOK, JobResponse{Job: job, Logs: logs})
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.queue.Cancel(id); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, queue.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	// A running job stops asynchronously; its status changes once the task returns
+	job, _ := s.queue.Get(id)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	id := r.PathValue("id")
+	if _, err := s.queue.Get(id); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	// Subscribe before reading the status, so a change in between is not missed
+	events, unsubscribe := s.stream(id).Subscribe()
+	defer func() {
+		unsubscribe()
+		s.dropFinished(id)
+	}()
+	job, err := s.queue.Get(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(e Event) {
+		data, _ := json.Marshal(e)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		flusher.Flush()
+	}
+	send(Event{Type: EventStatus, Job: &job})
+	if finished(job.Status) {
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case e := <-events:
+			send(e)
+			if e.Type == EventStatus && finished(e.Job.Status) {
+				return
+			}
+		}
+	}
+}
+
+// run is the queue.Runner: it processes one job and returns the note path (or
+// playlist index). Events go to the queue's logger and to the job's subscribers.
+func (s *Server) run(ctx context.Context, job queue.Job, logger service.EventLogger) (string, error) {
+	out := tee{logger, s.stream(job.ID)}
+	opts := s.opts
+	opts.AudioOnly = job.AudioOnly
+
+	if pp, ok := s.proc.(playlistProcessor); ok && (job.Playlist != nil || downloader.IsPlaylistURL(job.URL)) {
+		var filter downloader.PlaylistFilter
+		if p := job.Playlist; p != nil {
+			var err error
+			if filter, err = downloader.ParsePlaylistFilter(p.After, p.Before, p.MaxItems, p.TitlePattern); err != nil {
+				return "", err
+			}
+		}
+		res, err := pp.ProcessPlaylist(ctx, job.URL, opts, filter, out)
+		if err != nil {
+			return "", err
+		}
+		return res.IndexPath, nil
+	}
+
+	res, err := s.proc.ProcessTask(ctx, job.URL, opts, out)
+	if err != nil {
+		return "", err
+	}
+	return res.NotePath, nil
+}
+
+// onQueueEvent publishes status changes. Logs and tokens reach subscribers
+// through run's logger, and progress updates are not repeated as statuses.
+func (s *Server) onQueueEvent(e queue.Event) {
+	if e.Type != queue.EventUpdate || e.Job == nil {
+		return
+	}
+	s.mu.Lock()
+	js := s.streamLocked(e.JobID)
+	changed := js.status != e.Job.Status
+	js.status = e.Job.Status
+	if finished(e.Job.Status) {
+		// Later subscribers read the final status from the queue
+		delete(s.streams, e.JobID)
+	}
+	s.mu.Unlock()
+
+	if changed {
+		js.Publish(Event{Type: EventStatus, Job: e.Job})
+	}
+}
+
+// stream returns the broadcaster of job id, creating it when needed.
+func (s *Server) stream(id string) *Broadcaster {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.streamLocked(id).Broadcaster
+}
+
+func (s *Server) streamLocked(id string) *jobStream {
+	js, ok := s.streams[id]
+	if !ok {
+		js = &jobStream{Broadcaster: NewBroadcaster()}
+		s.streams[id] = js
+	}
+	return js
+}
+
+// dropFinished forgets the broadcaster of job id if the job has finished or is
+// gone, e.g. one created by a subscriber that arrived after the last status.
+func (s *Server) dropFinished(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, err := s.queue.Get(id); err != nil || finished(job.Status) {
+		delete(s.streams, id)
+	}
+}
+
+func finished(status queue.Status) bool {
+	return status == queue.StatusDone || status == queue.StatusFailed || status == queue.StatusCancelled
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// tee passes every event to several loggers.
+type tee []service.EventLogger
+
+func (t tee) Log(msg string) {
+	for _, l := range t {
+		l.Log(msg)
+	}
+}
+
+func (t tee) Progress(percent float64) {
+	for _, l := range t {
+		l.Progress(percent)
+	}
+}
+
+func (t tee) AnalysisChunk(token string) {
+	for _, l := range t {
+		l.AnalysisChunk(token)
+	}
+}
+
+func (t tee) Error(err error) {
+	for _, l := range t {
+		l.Error(err)
+	}
+}
@@ -0,0 +1,303 @@
+package server
+
+import (
+	"Varys/backend/queue"
+	"Varys/backend/service"
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "secret"
+
+// fakeProcessor logs, reports progress and streams two tokens. URLs containing
+// "block" wait until the task is cancelled; "hold" waits for release.
+type fakeProcessor struct {
+	release chan struct{}
+}
+
+func (p *fakeProcessor) ProcessTask(ctx context.Context, url string, opts service.Options, logger service.EventLogger) (*service.TaskResult, error) {
+	logger.Log("Downloading " + url)
+	logger.Progress(50)
+	switch {
+	case strings.Contains(url, "block"):
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case strings.Contains(url, "hold"):
+		<-p.release
+	}
+	logger.AnalysisChunk("Sum")
+	logger.AnalysisChunk("mary")
+	return &service.TaskResult{NotePath: "/vault/Note.md"}, nil
+}
+
+func startServer(t *testing.T) (*httptest.Server, *fakeProcessor) {
+	t.Helper()
+	p := &fakeProcessor{release: make(chan struct{})}
+	s := New(p, service.Options{}, testToken, "", 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := s.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		cancel()
+		s.Wait()
+	})
+	return ts, p
+}
+
+func call(t *testing.T, ts *httptest.Server, method, path, body string, out interface{}) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func waitForStatus(t *testing.T, ts *httptest.Server, id string, want queue.Status) JobResponse {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var job JobResponse
+		call(t, ts, "GET", "/api/jobs/"+id, "", &job)
+		if job.Status == want {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s, job is %s", want, job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// readEvents reads an SSE stream until the server ends it.
+func readEvents(t *testing.T, resp *http.Response) []Event {
+	t.Helper()
+	var events []Event
+	var typ string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			typ = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			var e Event
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+				t.Fatalf("bad event data %q: %v", line, err)
+			}
+			if e.Type != typ {
+				t.Errorf("event %q carries type %q", typ, e.Type)
+			}
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func TestAuth(t *testing.T) {
+	ts, _ := startServer(t)
+	for _, tc := range []struct {
+		name, header, query string
+		want                int
+	}{
+		{"missing", "", "", http.StatusUnauthorized},
+		{"wrong", "Bearer nope", "", http.StatusUnauthorized},
+		{"header", "Bearer " + testToken, "", http.StatusOK},
+		{"query", "", "?token=" + testToken, http.StatusOK},
+	} {
+		req, _ := http.NewRequest("GET", ts.URL+"/api/jobs"+tc.query, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
+
+func TestSubmitAndGet(t *testing.T) {
+	ts, _ := startServer(t)
+
+	if code := call(t, ts, "POST", "/api/jobs", `{"url": " "}`, nil); code != http.StatusBadRequest {
+		t.Errorf("empty url: status %d", code)
+	}
+	for _, local := range []string{"/etc/passwd", "~/.ssh/config", "file:///etc/passwd", "https://"} {
+		if code := call(t, ts, "POST", "/api/jobs", `{"url": "`+local+`"}`, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d", local, code)
+		}
+	}
+	if code := call(t, ts, "POST", "/api/jobs", `{"url": "https://example.com/list", "playlist": {}}`, nil); code != http.StatusBadRequest {
+		t.Errorf("playlist without support: status %d", code)
+	}
+
+	var job queue.Job
+	if code := call(t, ts, "POST", "/api/jobs", `{"url": "https://example.com/a"}`, &job); code != http.StatusCreated {
+		t.Fatalf("submit: status %d", code)
+	}
+	if !job.AudioOnly {
+		t.Error("audio_only should default to true")
+	}
+	done := waitForStatus(t, ts, job.ID, queue.StatusDone)
+	if done.Result != "/vault/Note.md" {
+		t.Errorf("result = %q", done.Result)
+	}
+	if len(done.Logs) == 0 || !strings.Contains(done.Logs[0], "Downloading https://example.com/a") {
+		t.Errorf("logs = %v", done.Logs)
+	}
+
+	var jobs []queue.Job
+	call(t, ts, "GET", "/api/jobs", "", &jobs)
+	if len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Errorf("list = %+v", jobs)
+	}
+	if code := call(t, ts, "GET", "/api/jobs/missing", "", nil); code != http.StatusNotFound {
+		t.Errorf("unknown job: status %d", code)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ts, p := startServer(t)
+	var job queue.Job
+	call(t, ts, "POST", "/api/jobs", `{"url": "https://example.com/hold"}`, &job)
+	waitForStatus(t, ts, job.ID, queue.StatusRunning)
+
+	resp, err := http.Get(ts.URL + "/api/jobs/" + job.ID + "/events?token=" + testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	close(p.release)
+	events := readEvents(t, resp)
+
+	var tokens strings.Builder
+	for _, e := range events {
+		if e.Type == EventAnalysis {
+			tokens.WriteString(e.Message)
+		}
+	}
+	if tokens.String() != "Summary" {
+		t.Errorf("analysis tokens = %q", tokens.String())
+	}
+	first, last := events[0], events[len(events)-1]
+	if first.Type != EventStatus || first.Job.Status != queue.StatusRunning {
+		t.Errorf("first event = %+v", first)
+	}
+	if last.Type != EventStatus || last.Job.Status != queue.StatusDone || last.Job.Result != "/vault/Note.md" {
+		t.Errorf("last event = %+v", last)
+	}
+
+	// A finished job's stream only repeats its final status
+	resp, err = http.Get(ts.URL + "/api/jobs/" + job.ID + "/events?token=" + testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if events := readEvents(t, resp); len(events) != 1 || events[0].Job.Status != queue.StatusDone {
+		t.Errorf("events of finished job = %+v", events)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ts, _ := startServer(t)
+	var job queue.Job
+	call(t, ts, "POST", "/api/jobs", `{"url": "https://example.com/block"}`, &job)
+	waitForStatus(t, ts, job.ID, queue.StatusRunning)
+
+	if code := call(t, ts, "POST", "/api/jobs/"+job.ID+"/cancel", "", nil); code != http.StatusAccepted {
+		t.Fatalf("cancel: status %d", code)
+	}
+	waitForStatus(t, ts, job.ID, queue.StatusCancelled)
+	if code := call(t, ts, "POST", "/api/jobs/"+job.ID+"/cancel", "", nil); code != http.StatusConflict {
+		t.Errorf("cancel twice: status %d", code)
+	}
+	if code := call(t, ts, "POST", "/api/jobs/missing/cancel", "", nil); code != http.StatusNotFound {
+		t.Errorf("cancel unknown job: status %d", code)
+	}
+}
+
+func TestListenAndServe(t *testing.T) {
+	if _, err := Listen("0.0.0.0:0"); err == nil {
+		t.Error("expected Listen to refuse a non-loopback address")
+	}
+	ln, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(ln.Addr().String()); err == nil {
+		t.Error("expected an error for a port that is taken")
+	}
+
+	s := New(&fakeProcessor{}, service.Options{}, testToken, "", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+
+	req, _ := http.NewRequest("GET", "http://"+ln.Addr().String()+"/api/jobs", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d", resp.StatusCode)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Serve returned %v after cancel", err)
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		"127.0.0.1:8765": true,
+		"localhost:8765": true,
+		"[::1]:8765":     true,
+		"0.0.0.0:8765":   false,
+		":8765":          false,
+		"192.168.1.2:80": false,
+		"127.0.0.1":      false,
+	} {
+		if err := CheckLoopback(addr); (err == nil) != ok {
+			t.Errorf("CheckLoopback(%q) = %v", addr, err)
+		}
+	}
+}
+
+func TestLoadToken(t *testing.T) {
+	path := t.TempDir() + "/api-token"
+	first, err := LoadToken(path)
+	if err != nil || len(first) < 32 {
+		t.Fatalf("LoadToken = %q, %v", first, err)
+	}
+	again, _ := LoadToken(path)
+	if again != first {
+		t.Errorf("token changed between runs: %q != %q", again, first)
+	}
+}
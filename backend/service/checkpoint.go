@@ -0,0 +1,299 @@
+package service
+
+import (
+	"Varys/backend/analyzer"
+	"Varys/backend/document"
+	"Varys/backend/downloader"
+	"Varys/backend/transcriber"
+	"Varys/backend/translation"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pipeline stages, in execution order. Each completed stage is checkpointed in the
+// task's work directory so an interrupted or failed run can resume after it.
+const (
+	StageMetadata    = "metadata"
+	StageMedia       = "media"
+	StageTranscript  = "transcript"
+	StageTranslation = "translation"
+	StageAnalysis    = "analysis"
+)
+
+// Stages lists the pipeline stages in execution order.
+var Stages = []string{StageMetadata, StageMedia, StageTranscript, StageTranslation, StageAnalysis}
+
+// ParseStage validates a --from-stage value. An empty string means "resume".
+func ParseStage(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || stageIndex(name) >= 0 {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown stage %q (use %s)", name, strings.Join(Stages, ", "))
+}
+
+func stageIndex(stage string) int {
+	for i, s := range Stages {
+		if s == stage {
+			return i
+		}
+	}
+	return -1
+}
+
+// Stage outputs as stored on disk.
+type metadataCheckpoint struct {
+	Title            string                `json:"title"`
+	Description      string                `json:"description"`
+	IsLocalFile      bool                  `json:"is_local_file"`
+	IsArticle        bool                  `json:"is_article"`
+	Media            *downloader.MediaInfo `json:"media,omitempty"`
+	ArticleText      string                `json:"article_text,omitempty"`
+	ArticleLang      string                `json:"article_lang,omitempty"`
+	ArticleAuthor    string                `json:"article_author,omitempty"`
+	ArticlePublished time.Time             `json:"article_published,omitempty"`
+	Document         *document.Document    `json:"document,omitempty"`
+	DocumentPath     string                `json:"document_path,omitempty"` // absolute; points into the vault once the note was saved
+}
+
+type mediaCheckpoint struct {
+	Path string `json:"path"` // absolute; points into the vault once the note was saved
+}
+
+type transcriptCheckpoint struct {
+	Language string                `json:"language"`
+	Text     string                `json:"text"`
+	Segments []transcriber.Segment `json:"segments"`
+}
+
+type translationCheckpoint struct {
+	Pairs      []translation.TranslationPair   `json:"pairs"`
+	Violations []translation.GlossaryViolation `json:"violations,omitempty"`
+}
+
+type analysisCheckpoint struct {
+	Result *analyzer.AnalysisResult `json:"result"`
+}
+
+// checkpointFile wraps stage output with the inputs it was produced from.
+type checkpointFile struct {
+	Key     string          `json:"key"`
+	SavedAt time.Time       `json:"saved_at"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// workDir holds the checkpoints and intermediate files of one URL.
+type workDir struct {
+	Dir       string
+	temporary bool   // removed on close; used when no work root is configured
+	from      int    // stages at or after this index are rerun
+	stale     bool   // an earlier stage ran, so later checkpoints are outdated
+	unlock    func() // releases the directory for other tasks
+}
+
+// workDirLocks holds one lock per work directory. Tasks for the same source (e.g.
+// a URL submitted twice to the job queue) take turns instead of downloading into
+// the same directory and moving each other's files away.
+var (
+	workDirMu     sync.Mutex
+	workDirLocks  = map[string]chan struct{}{}
+	workDirPruned = map[string]bool{} // roots already pruned by this process
+)
+
+// workDirMaxAge is how long the work directory of an abandoned run (e.g. a failed
+// download nobody retried) is kept before it is removed.
+const workDirMaxAge = 14 * 24 * time.Hour
+
+// completeMarker marks a work directory whose note was saved. A rerun of the
+// source starts over (fetching a fresh title, transcript, ...) unless
+// --from-stage asks to reuse the stages before it.
+const completeMarker = "complete"
+
+// openWorkDir returns the work directory for source below root, waiting while
+// another task uses it. With an empty root a temporary directory is used and
+// nothing is resumed. The directory must be closed.
+func openWorkDir(ctx context.Context, root, source, fromStage string, logger EventLogger) (*workDir, error) {
+	if root == "" {
+		dir, err := os.MkdirTemp("", "varys_task_")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp dir: %w", err)
+		}
+		return &workDir{Dir: dir, temporary: true, stale: true}, nil
+	}
+
+	pruneWorkDirs(root, logger)
+
+	sum := sha256.Sum256([]byte(source))
+	dir := filepath.Join(root, hex.EncodeToString(sum[:8]))
+	unlock, err := lockWorkDir(ctx, dir, logger)
+	if err != nil {
+		return nil, err
+	}
+	marker := filepath.Join(dir, completeMarker)
+	if _, err := os.Stat(marker); err == nil {
+		if fromStage == "" {
+			// Only unfinished runs resume
+			os.RemoveAll(dir)
+		} else {
+			// Reused on request; a failure of this run can be resumed again
+			os.Remove(marker)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		unlock()
+		return nil, fmt.Errorf("failed to create work dir: %w", err)
+	}
+	w := &workDir{Dir: dir, from: len(Stages), unlock: unlock}
+	if fromStage != "" {
+		w.from = stageIndex(fromStage)
+	}
+	return w, nil
+}
+
+func lockWorkDir(ctx context.Context, dir string, logger EventLogger) (func(), error) {
+	workDirMu.Lock()
+	lock, ok := workDirLocks[dir]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		workDirLocks[dir] = lock
+	}
+	workDirMu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+	default:
+		logger.Log("Another task is processing this source. Waiting for it to finish...")
+		select {
+		case lock <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return func() { <-lock }, nil
+}
+
+// pruneWorkDirs removes the work directories below root that were not touched for
+// workDirMaxAge, once per process. Directories in use are left alone.
+func pruneWorkDirs(root string, logger EventLogger) {
+	workDirMu.Lock()
+	defer workDirMu.Unlock()
+	if workDirPruned[root] {
+		return
+	}
+	workDirPruned[root] = true
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	removed := 0
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !e.IsDir() || time.Since(info.ModTime()) < workDirMaxAge {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		lock, ok := workDirLocks[dir]
+		if !ok {
+			lock = make(chan struct{}, 1)
+			workDirLocks[dir] = lock
+		}
+		select {
+		case lock <- struct{}{}:
+			if os.RemoveAll(dir) == nil {
+				removed++
+			}
+			<-lock
+		default:
+		}
+	}
+	if removed > 0 {
+		logger.Log(fmt.Sprintf("Removed %d abandoned work directories.", removed))
+	}
+}
+
+// complete marks the run as finished once its note is saved, so the next run of
+// the source starts over instead of resuming.
+func (w *workDir) complete() {
+	if !w.temporary {
+		os.WriteFile(filepath.Join(w.Dir, completeMarker), nil, 0644)
+	}
+}
+
+// close releases the work directory and removes temporary ones. Persistent ones
+// are kept for later reruns.
+func (w *workDir) close() {
+	if w.temporary {
+		os.RemoveAll(w.Dir)
+	}
+	if w.unlock != nil {
+		w.unlock()
+		w.unlock = nil
+	}
+}
+
+// load reads the checkpoint of stage into v. It reports false when the stage has to
+// run: no checkpoint, different inputs (key), forced by --from-stage, or an earlier
+// stage ran in this session. A false result marks all later stages stale as well.
+func (w *workDir) load(stage, key string, v interface{}) bool {
+	if w.stale || stageIndex(stage) >= w.from {
+		w.stale = true
+		return false
+	}
+	data, err := os.ReadFile(w.path(stage))
+	if err != nil {
+		w.stale = true
+		return false
+	}
+	var cp checkpointFile
+	if err := json.Unmarshal(data, &cp); err != nil || cp.Key != key || json.Unmarshal(cp.Data, v) != nil {
+		w.stale = true
+		return false
+	}
+	return true
+}
+
+// invalidate marks the current and all later stages for rerun, e.g. when a
+// checkpoint refers to a file that no longer exists.
+func (w *workDir) invalidate() {
+	w.stale = true
+}
+
+// save writes the checkpoint of stage. Failures are not fatal: the stage simply
+// runs again next time.
+func (w *workDir) save(stage, key string, v interface{}) error {
+	if w.temporary {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(checkpointFile{Key: key, SavedAt: time.Now(), Data: data}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := w.path(stage) + ".tmp"
+	if err := os.WriteFile(tmp, out, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.path(stage))
+}
+
+func (w *workDir) path(stage string) string {
+	return filepath.Join(w.Dir, stage+".json")
+}
+
+// stageKey fingerprints the inputs of a stage so changed settings trigger a rerun.
+func stageKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseStage(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"transcript", StageTranscript, false},
+		{" Analysis ", StageAnalysis, false},
+		{"upload", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStage(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseStage(%q) = %q, %v; want %q (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWorkDir_ResumeAndKeys(t *testing.T) {
+	root := t.TempDir()
+	ctx := context.Background()
+
+	w, err := openWorkDir(ctx, root, "https://example.com/v", "", discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.save(StageMetadata, "", metadataCheckpoint{Title: "Video"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.save(StageTranscript, "k1", transcriptCheckpoint{Text: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	w.close()
+	if _, err := os.Stat(w.Dir); err != nil {
+		t.Fatalf("persistent work dir was removed: %v", err)
+	}
+
+	// Same source maps to the same directory and resumes
+	w, _ = openWorkDir(ctx, root, "https://example.com/v", "", discardLogger{})
+	var meta metadataCheckpoint
+	if !w.load(StageMetadata, "", &meta) || meta.Title != "Video" {
+		t.Fatalf("metadata not resumed: %+v", meta)
+	}
+	var tr transcriptCheckpoint
+	if !w.load(StageTranscript, "k1", &tr) || tr.Text != "hello" {
+		t.Fatalf("transcript not resumed: %+v", tr)
+	}
+
+	w.close()
+
+	// Changed inputs rerun the stage and everything after it
+	w, _ = openWorkDir(ctx, root, "https://example.com/v", "", discardLogger{})
+	if !w.load(StageMetadata, "", &meta) {
+		t.Fatal("metadata should resume")
+	}
+	if w.load(StageTranscript, "k2", &tr) {
+		t.Error("transcript with a different key should rerun")
+	}
+	var an analysisCheckpoint
+	w.save(StageAnalysis, "a", analysisCheckpoint{})
+	if w.load(StageAnalysis, "a", &an) {
+		t.Error("analysis after a rerun stage should rerun")
+	}
+
+	// Other sources use their own directory
+	other, _ := openWorkDir(ctx, root, "https://example.com/other", "", discardLogger{})
+	if other.Dir == w.Dir {
+		t.Error("different sources share a work dir")
+	}
+	if other.load(StageMetadata, "", &meta) {
+		t.Error("new source should not resume")
+	}
+}
+
+func TestWorkDir_FromStage(t *testing.T) {
+	root := t.TempDir()
+	ctx := context.Background()
+	w, _ := openWorkDir(ctx, root, "src", "", discardLogger{})
+	w.save(StageMetadata, "", metadataCheckpoint{Title: "T"})
+	w.save(StageMedia, "m", mediaCheckpoint{Path: "/tmp/x"})
+	w.close()
+
+	w, _ = openWorkDir(ctx, root, "src", StageMedia, discardLogger{})
+	var meta metadataCheckpoint
+	if !w.load(StageMetadata, "", &meta) {
+		t.Error("stage before --from-stage should resume")
+	}
+	var media mediaCheckpoint
+	if w.load(StageMedia, "m", &media) {
+		t.Error("--from-stage should force the stage to rerun")
+	}
+}
+
+func TestWorkDir_Temporary(t *testing.T) {
+	w, err := openWorkDir(context.Background(), "", "src", "", discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.save(StageMetadata, "", metadataCheckpoint{Title: "T"})
+	var meta metadataCheckpoint
+	if w.load(StageMetadata, "", &meta) {
+		t.Error("temporary work dir should not resume")
+	}
+	w.close()
+	if _, err := os.Stat(w.Dir); !os.IsNotExist(err) {
+		t.Error("temporary work dir was not removed")
+	}
+}
+
+func TestWorkDir_SameSourceTakesTurns(t *testing.T) {
+	root := t.TempDir()
+	first, err := openWorkDir(context.Background(), root, "src", "", discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened := make(chan *workDir)
+	go func() {
+		w, _ := openWorkDir(context.Background(), root, "src", "", discardLogger{})
+		opened <- w
+	}()
+	select {
+	case <-opened:
+		t.Fatal("second task got the work dir while the first still uses it")
+	case <-time.After(50 * time.Millisecond):
+	}
+	first.close()
+	(<-opened).close()
+
+	// Waiting stops with the task
+	first, _ = openWorkDir(context.Background(), root, "src", "", discardLogger{})
+	defer first.close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := openWorkDir(ctx, root, "src", "", discardLogger{}); err == nil {
+		t.Error("expected an error for a cancelled task")
+	}
+	// Other sources are not blocked
+	other, err := openWorkDir(ctx, root, "other", "", discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.close()
+}
+
+func TestWorkDir_FinishedRunStartsOver(t *testing.T) {
+	root := t.TempDir()
+	ctx := context.Background()
+	w, _ := openWorkDir(ctx, root, "src", "", discardLogger{})
+	w.save(StageMetadata, "", metadataCheckpoint{Title: "Old title"})
+	w.save(StageAnalysis, "a", analysisCheckpoint{})
+	w.complete()
+	w.close()
+
+	// --from-stage reuses the earlier stages of a finished run
+	w, _ = openWorkDir(ctx, root, "src", StageAnalysis, discardLogger{})
+	var meta metadataCheckpoint
+	if !w.load(StageMetadata, "", &meta) {
+		t.Error("--from-stage should reuse the stages before it")
+	}
+	w.complete()
+	w.close()
+
+	// A plain rerun fetches everything again
+	w, _ = openWorkDir(ctx, root, "src", "", discardLogger{})
+	defer w.close()
+	if w.load(StageMetadata, "", &meta) {
+		t.Error("a finished run should not be resumed")
+	}
+}
+
+func TestPruneWorkDirs(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-workDirMaxAge - time.Hour)
+	for _, name := range []string{"abandoned", "busy", "recent"} {
+		if err := os.Mkdir(root+"/"+name, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if name != "recent" {
+			os.Chtimes(root+"/"+name, old, old)
+		}
+	}
+	unlock, _ := lockWorkDir(context.Background(), root+"/busy", discardLogger{})
+	defer unlock()
+
+	pruneWorkDirs(root, discardLogger{})
+	for name, kept := range map[string]bool{"abandoned": false, "busy": true, "recent": true} {
+		if _, err := os.Stat(root + "/" + name); (err == nil) != kept {
+			t.Errorf("%s: kept = %v, want %v", name, err == nil, kept)
+		}
+	}
+}
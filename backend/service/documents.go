@@ -0,0 +1,82 @@
+package service
+
+import (
+	"Varys/backend/analyzer"
+	"Varys/backend/document"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// extractDocument reads the document at path into the task metadata. source is
+// the URL or file the user submitted. Only PDF and EPUB files keep a
+// DocumentPath: they are attached to the note, while text formats are
+// reproduced in full by the note itself.
+func (s *CoreService) extractDocument(ctx context.Context, path, source string, logger EventLogger) (metadataCheckpoint, error) {
+	logger.Log("Extracting document text...")
+	doc, err := document.NewExtractor(s.depManager).Extract(ctx, path)
+	if err != nil {
+		if ctx.Err() != nil {
+			return metadataCheckpoint{}, ctx.Err()
+		}
+		return metadataCheckpoint{}, fmt.Errorf("document extraction failed: %w", err)
+	}
+
+	meta := metadataCheckpoint{
+		Title:        doc.Title,
+		Description:  doc.Subject,
+		Document:     doc,
+		DocumentPath: path,
+	}
+	if !document.Attachable(doc.Format) {
+		meta.DocumentPath = ""
+	}
+	if meta.Title == "" {
+		meta.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if meta.Description == "" {
+		meta.Description = "Document: " + source
+	}
+	n, unit := doc.Length()
+	if n != 1 {
+		unit += "s"
+	}
+	logger.Log(fmt.Sprintf("Document found: %s (%s, %d %s, %d headings, Language: %s)", meta.Title, doc.Format, n, unit, len(doc.Headings), doc.Language))
+	return meta, nil
+}
+
+// downloadDocument fetches a document URL into dir and extracts it. It reports
+// false when the URL does not serve a document, e.g. because it is a web page.
+func (s *CoreService) downloadDocument(ctx context.Context, url, dir string, logger EventLogger) (metadataCheckpoint, bool, error) {
+	path, err := document.Download(ctx, url, dir)
+	if err != nil {
+		if ctx.Err() != nil {
+			return metadataCheckpoint{}, false, ctx.Err()
+		}
+		if !errors.Is(err, document.ErrNotDocument) {
+			logger.Log(fmt.Sprintf("Document download failed: %v", err))
+		}
+		return metadataCheckpoint{}, false, nil
+	}
+	logger.Log(fmt.Sprintf("Document downloaded: %s", filepath.Base(path)))
+	meta, err := s.extractDocument(ctx, path, url, logger)
+	return meta, true, err
+}
+
+// windowPages describes which pages (or EPUB chapters) each window of a chunked
+// analysis covers, so progress on long documents reads "window 3/9, pages 12-17 of 48".
+func windowPages(doc *document.Document, text, customPrompt string, contextSize int) func(done int, stage string) string {
+	if doc == nil {
+		return func(_ int, stage string) string { return stage }
+	}
+	spans := doc.Locate(analyzer.Windows(text, customPrompt, contextSize))
+	total, _ := doc.Length()
+	return func(done int, stage string) string {
+		if strings.HasPrefix(stage, "window") && done < len(spans) && spans[done].First > 0 && total > 1 {
+			return fmt.Sprintf("%s, %s of %d", stage, spans[done], total)
+		}
+		return stage
+	}
+}
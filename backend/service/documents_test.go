@@ -0,0 +1,83 @@
+package service
+
+import (
+	"Varys/backend/analyzer"
+	"Varys/backend/dependency"
+	"Varys/backend/document"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExtractDocument_TitleFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows mock not implemented")
+	}
+	binDir := t.TempDir()
+	os.WriteFile(filepath.Join(binDir, "pdftotext"), []byte("#!/bin/sh\nprintf 'Results\\nIt works.\\f'\n"), 0755)
+	os.WriteFile(filepath.Join(binDir, "pdfinfo"), []byte("#!/bin/sh\necho 'Title: Microsoft Word - draft.docx'\n"), 0755)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	path := filepath.Join(t.TempDir(), "Quarterly Report.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4\n"), 0644)
+
+	s := NewCoreService(&dependency.Manager{})
+	meta, err := s.extractDocument(context.Background(), path, "/docs/Quarterly Report.pdf", discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "Quarterly Report" {
+		t.Errorf("title = %q, want the file name", meta.Title)
+	}
+	if meta.Description != "Document: /docs/Quarterly Report.pdf" || meta.DocumentPath != path || meta.Document == nil {
+		t.Errorf("meta = %+v", meta)
+	}
+}
+
+func TestExtractDocument_TextNotAttached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	os.WriteFile(path, []byte("# Meeting Notes\n\nWe agreed on the plan.\n"), 0644)
+
+	s := NewCoreService(&dependency.Manager{})
+	meta, err := s.extractDocument(context.Background(), path, path, discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The note holds the whole text, so the file is not copied to the vault
+	if meta.Title != "Meeting Notes" || meta.DocumentPath != "" || meta.Document.Format != document.FormatMarkdown {
+		t.Errorf("meta = %+v", meta)
+	}
+}
+
+func TestWindowPages(t *testing.T) {
+	doc := &document.Document{}
+	for i := 1; i <= 40; i++ {
+		doc.Pages = append(doc.Pages, document.Page{Number: i, Text: fmt.Sprintf("Page %d says %s", i, strings.Repeat("words ", 300))})
+	}
+	text := doc.Text()
+	windows := analyzer.Windows(text, "", 4096)
+	if len(windows) < 3 {
+		t.Fatalf("expected several windows, got %d", len(windows))
+	}
+
+	describe := windowPages(doc, text, "", 4096)
+	if got := describe(0, fmt.Sprintf("window 1/%d", len(windows))); !strings.HasPrefix(got, fmt.Sprintf("window 1/%d, pages 1-", len(windows))) || !strings.HasSuffix(got, " of 40") {
+		t.Errorf("first window = %q", got)
+	}
+	last := len(windows) - 1
+	if got := describe(last, "window"); !strings.Contains(got, "-40 of 40") && !strings.Contains(got, "page 40 of 40") {
+		t.Errorf("last window = %q", got)
+	}
+	if got := describe(len(windows), "merge"); got != "merge" {
+		t.Errorf("merge stage = %q", got)
+	}
+
+	// Transcripts have no pages
+	if got := windowPages(nil, text, "", 4096)(0, "window 1/3"); got != "window 1/3" {
+		t.Errorf("without a document = %q", got)
+	}
+}
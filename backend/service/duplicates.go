@@ -0,0 +1,179 @@
+package service
+
+import (
+	"Varys/backend/storage"
+	"fmt"
+	"os"
+)
+
+// ingestion tracks a task's source in the vault's ledger, so submitting the same
+// source again skips, updates or versions the existing note (Options.OnDuplicate)
+// instead of overwriting it.
+type ingestion struct {
+	sm     *storage.Manager
+	ledger *storage.Ledger // nil if the ledger could not be read
+	key    string
+	mode   string
+	prev   storage.LedgerEntry
+	seen   bool
+	// existing is the note already generated from this source, if it still exists
+	existing string
+}
+
+func openIngestion(sm *storage.Manager, key, source, safeTitle, mode string, logger EventLogger) *ingestion {
+	in := &ingestion{sm: sm, key: key, mode: mode}
+	if in.mode == "" {
+		in.mode = storage.DuplicateUpdate
+	}
+
+	ledger, err := sm.OpenLedger()
+	if err != nil {
+		logger.Log(fmt.Sprintf("Ingestion ledger not available: %v", err))
+	} else {
+		in.ledger = ledger
+		in.prev, in.seen = ledger.Lookup(key)
+	}
+
+	if in.seen {
+		if _, err := os.Stat(sm.NotePath(in.prev.Note)); err == nil {
+			in.existing = in.prev.Note
+		}
+	} else if noteSource := storage.NoteSource(sm.NotePath(safeTitle)); noteSource != "" &&
+		storage.NormalizeURL(noteSource) == storage.NormalizeURL(source) {
+		// Written before the ledger existed
+		in.existing = safeTitle
+	}
+	return in
+}
+
+// skip reports whether the source was ingested before and should be skipped.
+func (in *ingestion) skip() bool {
+	return in.existing != "" && in.mode == storage.DuplicateSkip
+}
+
+// noteName returns the file name (without .md) for the new note.
+func (in *ingestion) noteName(safeTitle string) string {
+	switch {
+	case in.existing != "" && in.mode == storage.DuplicateVersion:
+		return in.sm.VersionName(in.existing)
+	case in.existing != "":
+		return in.existing
+	case in.seen:
+		return in.prev.Note // the note was deleted; write it again under its name
+	default:
+		// A different source may already own a note with this title
+		return in.sm.UniqueName(safeTitle)
+	}
+}
+
+// save writes content as note name and records it in the ledger; see saveWith.
+func (in *ingestion) save(source, safeTitle, name, media, content string, logger EventLogger) (string, string, error) {
+	return in.saveWith(source, safeTitle, name, func(string) (string, string, error) {
+		return content, media, nil
+	}, logger)
+}
+
+// saveWith writes the note returned by prepare as note name and records it in the
+// ledger. The ledger is checked again under its lock first: when another task
+// ingested the same source since openIngestion (e.g. a double submit to the job
+// queue), the duplicate mode is applied to that note instead of overwriting it.
+// prepare runs under the lock once the final name is known, so assets named after
+// the note (media, subtitles) are only created for the note actually written. It
+// returns the note content and the media file. saveWith returns the note path and
+// the name used; skip() reports whether the note was left alone.
+func (in *ingestion) saveWith(source, safeTitle, name string, prepare func(name string) (string, string, error), logger EventLogger) (string, string, error) {
+	if in.ledger == nil {
+		content, _, err := prepare(name)
+		if err != nil {
+			return "", name, err
+		}
+		path, _, err := in.writeNote(name, content, logger)
+		return path, name, err
+	}
+
+	var path string
+	var recorded storage.LedgerEntry
+	err := in.ledger.Update(in.key, func(prev storage.LedgerEntry, seen bool) (*storage.LedgerEntry, error) {
+		if seen && (!in.seen || prev.Note != in.prev.Note) {
+			logger.Log("This source was saved by another task in the meantime.")
+			in.seen = true
+			in.existing = ""
+			if _, err := os.Stat(in.sm.NotePath(prev.Note)); err == nil {
+				in.existing = prev.Note
+			}
+			in.prev = prev
+			if in.skip() {
+				return nil, nil
+			}
+			name = in.noteName(safeTitle)
+		} else if _, err := os.Stat(in.sm.NotePath(name)); err == nil && name != in.existing {
+			// Another task took the name, e.g. a versioned copy or a source with the same title
+			name = in.noteName(safeTitle)
+		}
+		// The latest hash tells whether the note was edited since it was written
+		in.prev = prev
+
+		content, media, err := prepare(name)
+		if err != nil {
+			return nil, err
+		}
+		var written string
+		path, written, err = in.writeNote(name, content, logger)
+		if err != nil {
+			return nil, err
+		}
+		recorded = in.entry(source, name, media, content, written)
+		return &recorded, nil
+	})
+	if path == "" {
+		return "", name, err
+	}
+	if err != nil {
+		// The note is saved; only the ledger could not be updated
+		logger.Log(fmt.Sprintf("Failed to update ingestion ledger: %v", err))
+	} else if in.mode != storage.DuplicateVersion || in.existing == "" {
+		// Saving again (e.g. an index note written as a playlist progresses) updates this note
+		in.prev, in.seen, in.existing = recorded, true, name
+	}
+	return path, name, nil
+}
+
+// writeNote writes content as note name. When updating a note the user edited
+// since the last run, only the generated sections are replaced. It returns the
+// note path and the content written.
+func (in *ingestion) writeNote(name, content string, logger EventLogger) (string, string, error) {
+	path := in.sm.NotePath(name)
+	if name == in.existing && in.mode == storage.DuplicateUpdate {
+		if old, err := os.ReadFile(path); err == nil && (!in.seen || storage.HashNote(string(old)) != in.prev.NoteHash) {
+			content = storage.MergeNote(string(old), content, in.prev.Generated)
+			logger.Log("The existing note was edited. Updated the generated sections and kept your changes.")
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", "", err
+	}
+	return path, content, nil
+}
+
+// entry returns the ledger entry for the written note.
+func (in *ingestion) entry(source, name, media, generated, written string) storage.LedgerEntry {
+	entry := storage.LedgerEntry{
+		Key:       in.key,
+		Source:    source,
+		Note:      name,
+		Media:     media,
+		Versions:  in.prev.Versions,
+		Generated: storage.GeneratedParts(generated),
+		NoteHash:  storage.HashNote(written),
+	}
+	if in.existing != "" && in.mode == storage.DuplicateVersion {
+		// A versioned copy; the ledger keeps pointing at the original note
+		entry = in.prev
+		entry.Key, entry.Source = in.key, source
+		if entry.Note == "" {
+			entry.Note = in.existing
+		}
+		entry.Versions = append(entry.Versions, name)
+	}
+	return entry
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"Varys/backend/storage"
+	"os"
+	"strings"
+	"testing"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Log(string)           {}
+func (discardLogger) Progress(float64)     {}
+func (discardLogger) AnalysisChunk(string) {}
+func (discardLogger) Error(error)          {}
+
+// ingestOnce runs the save step of a task the way ProcessTask does.
+func ingestOnce(t *testing.T, sm *storage.Manager, key, source, title, mode, content string) (string, *ingestion) {
+	t.Helper()
+	in := openIngestion(sm, key, source, title, mode, discardLogger{})
+	if in.skip() {
+		return in.existing, in
+	}
+	_, name, err := in.save(source, title, in.noteName(title), "", content, discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name, in
+}
+
+func TestIngestion_Modes(t *testing.T) {
+	sm := storage.NewManager(t.TempDir())
+	note := "---\nsource: \"https://example.com/v\"\n---\n\n# Talk\n\n## 智能摘要\n\nFirst.\n"
+
+	if name, _ := ingestOnce(t, sm, "youtube:v", "https://example.com/v", "Talk", storage.DuplicateUpdate, note); name != "Talk" {
+		t.Fatalf("first ingestion saved as %q", name)
+	}
+
+	// User edits the note
+	edited := note + "\n## My Notes\n\nMine.\n"
+	os.WriteFile(sm.NotePath("Talk"), []byte(edited), 0644)
+
+	// skip leaves the note alone
+	name, in := ingestOnce(t, sm, "youtube:v", "https://example.com/v", "Talk", storage.DuplicateSkip, strings.Replace(note, "First.", "Second.", 1))
+	if !in.skip() || name != "Talk" {
+		t.Fatalf("skip mode did not skip (name %q)", name)
+	}
+	if data, _ := os.ReadFile(sm.NotePath("Talk")); string(data) != edited {
+		t.Error("skip mode changed the note")
+	}
+
+	// update replaces the generated section and keeps the user's one
+	ingestOnce(t, sm, "youtube:v", "https://example.com/v", "Talk", storage.DuplicateUpdate, strings.Replace(note, "First.", "Second.", 1))
+	data, _ := os.ReadFile(sm.NotePath("Talk"))
+	if !strings.Contains(string(data), "Second.") || !strings.Contains(string(data), "Mine.") || strings.Contains(string(data), "First.") {
+		t.Errorf("updated note:\n%s", data)
+	}
+
+	// version writes a copy and keeps the original
+	if name, _ := ingestOnce(t, sm, "youtube:v", "https://example.com/v", "Talk", storage.DuplicateVersion, note); name != "Talk_v2" {
+		t.Errorf("versioned copy saved as %q", name)
+	}
+	ledger, _ := sm.OpenLedger()
+	if e, _ := ledger.Lookup("youtube:v"); e.Note != "Talk" || len(e.Versions) != 1 || e.Versions[0] != "Talk_v2" {
+		t.Errorf("ledger entry after versioning = %+v", e)
+	}
+}
+
+func TestIngestion_SameTitleDifferentSource(t *testing.T) {
+	sm := storage.NewManager(t.TempDir())
+	ingestOnce(t, sm, "youtube:a", "https://example.com/a", "Intro", storage.DuplicateUpdate, "# Intro A\n")
+
+	name, _ := ingestOnce(t, sm, "youtube:b", "https://example.com/b", "Intro", storage.DuplicateUpdate, "# Intro B\n")
+	if name != "Intro_2" {
+		t.Errorf("second source saved as %q, want Intro_2", name)
+	}
+	if data, _ := os.ReadFile(sm.NotePath("Intro")); string(data) != "# Intro A\n" {
+		t.Error("note of the first source was overwritten")
+	}
+}
+
+func TestIngestion_RecognizesNotesWithoutLedger(t *testing.T) {
+	sm := storage.NewManager(t.TempDir())
+	os.WriteFile(sm.NotePath("Talk"), []byte("---\nsource: \"https://www.example.com/v\"\n---\n\n# Talk\n"), 0644)
+
+	in := openIngestion(sm, "url:https://example.com/v", "https://example.com/v", "Talk", storage.DuplicateSkip, discardLogger{})
+	if !in.skip() {
+		t.Error("existing note of the same source not recognized")
+	}
+}
+
+func TestIngestion_ConcurrentTasksForSameSource(t *testing.T) {
+	sm := storage.NewManager(t.TempDir())
+	first := "---\nsource: \"https://example.com/v\"\n---\n\n# Talk\n\n## 智能摘要\n\nFirst.\n"
+	second := strings.Replace(first, "First.", "Second.", 1)
+
+	// Both tasks start before either has saved its note
+	a := openIngestion(sm, "youtube:v", "https://example.com/v", "Talk", storage.DuplicateVersion, discardLogger{})
+	b := openIngestion(sm, "youtube:v", "https://example.com/v", "Talk", storage.DuplicateVersion, discardLogger{})
+	nameA, nameB := a.noteName("Talk"), b.noteName("Talk")
+
+	if _, name, err := a.save("https://example.com/v", "Talk", nameA, "", first, discardLogger{}); err != nil || name != "Talk" {
+		t.Fatalf("first save: %q, %v", name, err)
+	}
+	_, name, err := b.save("https://example.com/v", "Talk", nameB, "", second, discardLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Talk_v2" {
+		t.Errorf("second task saved as %q, want the versioned copy Talk_v2", name)
+	}
+	if data, _ := os.ReadFile(sm.NotePath("Talk")); !strings.Contains(string(data), "First.") {
+		t.Errorf("first note was overwritten:\n%s", data)
+	}
+
+	// In skip mode the later task leaves the note alone
+	c := openIngestion(sm, "youtube:w", "https://example.com/w", "Other", storage.DuplicateSkip, discardLogger{})
+	d := openIngestion(sm, "youtube:w", "https://example.com/w", "Other", storage.DuplicateSkip, discardLogger{})
+	c.save("https://example.com/w", "Other", c.noteName("Other"), "", "# C\n", discardLogger{})
+	d.save("https://example.com/w", "Other", d.noteName("Other"), "", "# D\n", discardLogger{})
+	if !d.skip() {
+		t.Error("second task in skip mode did not skip")
+	}
+	if data, _ := os.ReadFile(sm.NotePath("Other")); string(data) != "# C\n" {
+		t.Errorf("note = %q", data)
+	}
+}
+
+func TestIngestion_AssetsFollowFinalName(t *testing.T) {
+	sm := storage.NewManager(t.TempDir())
+	var prepared []string
+	prepare := func(name string) (string, string, error) {
+		prepared = append(prepared, name)
+		return "# " + name + "\n", name + ".m4a", nil
+	}
+
+	// Two URLs of the same video both start before either has saved
+	a := openIngestion(sm, "youtube:v", "https://youtu.be/v", "Talk", storage.DuplicateVersion, discardLogger{})
+	b := openIngestion(sm, "youtube:v", "https://www.youtube.com/watch?v=v", "Talk", storage.DuplicateVersion, discardLogger{})
+	a.saveWith("https://youtu.be/v", "Talk", a.noteName("Talk"), prepare, discardLogger{})
+	b.saveWith("https://www.youtube.com/watch?v=v", "Talk", b.noteName("Talk"), prepare, discardLogger{})
+	if strings.Join(prepared, ",") != "Talk,Talk_v2" {
+		t.Errorf("assets prepared for %v, want Talk,Talk_v2", prepared)
+	}
+
+	// A task that ends up skipping creates no assets
+	prepared = nil
+	c := openIngestion(sm, "youtube:w", "https://youtu.be/w", "Other", storage.DuplicateSkip, discardLogger{})
+	d := openIngestion(sm, "youtube:w", "https://www.youtube.com/watch?v=w", "Other", storage.DuplicateSkip, discardLogger{})
+	c.saveWith("https://youtu.be/w", "Other", c.noteName("Other"), prepare, discardLogger{})
+	d.saveWith("https://www.youtube.com/watch?v=w", "Other", d.noteName("Other"), prepare, discardLogger{})
+	if !d.skip() || strings.Join(prepared, ",") != "Other" {
+		t.Errorf("skip = %v, assets prepared for %v", d.skip(), prepared)
+	}
+}
+
+func TestIngestion_RepeatedSaves(t *testing.T) {
+	sm := storage.NewManager(t.TempDir())
+	// A video note that happens to have the playlist's title
+	os.WriteFile(sm.NotePath("Course"), []byte("# Course video\n"), 0644)
+
+	in := openIngestion(sm, "playlist:example.com/list", "https://example.com/list", "Course", storage.DuplicateUpdate, discardLogger{})
+	name := in.noteName("Course")
+	if name != "Course_2" {
+		t.Fatalf("index named %q, want Course_2", name)
+	}
+	in.save("https://example.com/list", "Course", name, "", "---\ntype: playlist\n---\n\n# Course\n1. A ⏳\n", discardLogger{})
+
+	// The user adds a section while the playlist is still running
+	data, _ := os.ReadFile(sm.NotePath(name))
+	os.WriteFile(sm.NotePath(name), append(data, "\n## My Notes\n\nMine.\n"...), 0644)
+
+	_, name, err := in.save("https://example.com/list", "Course", name, "", "---\ntype: playlist\n---\n\n# Course\n1. [[A]]\n", discardLogger{})
+	if err != nil || name != "Course_2" {
+		t.Fatalf("second save: %q, %v", name, err)
+	}
+	data, _ = os.ReadFile(sm.NotePath(name))
+	if !strings.Contains(string(data), "[[A]]") || !strings.Contains(string(data), "Mine.") {
+		t.Errorf("index after update:\n%s", data)
+	}
+	if data, _ := os.ReadFile(sm.NotePath("Course")); string(data) != "# Course video\n" {
+		t.Errorf("note with the same title was changed: %q", data)
+	}
+}
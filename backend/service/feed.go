@@ -0,0 +1,149 @@
+package service
+
+import (
+	"Varys/backend/downloader"
+	"Varys/backend/feed"
+	"Varys/backend/storage"
+	"context"
+	"fmt"
+	"time"
+)
+
+// FeedResult contains the output of a feed sync.
+type FeedResult struct {
+	Title   string
+	New     int           // unseen items found
+	Results []*TaskResult // successfully processed items, oldest first
+	Failed  int
+}
+
+// SyncFeed fetches a subscribed feed and runs ProcessTask for every item not seen
+// before, oldest first and at most maxItems (0 = all). Items are marked seen in
+// st once their note is written, so failed and remaining items are picked up by
+// the next sync.
+func (s *CoreService) SyncFeed(ctx context.Context, st *feed.State, sub *feed.Subscription, opts Options, maxItems int, logger EventLogger) (*FeedResult, error) {
+	if _, err := opts.LoadNoteTemplate(); err != nil {
+		return nil, err
+	}
+
+	logger.Log(fmt.Sprintf("Fetching feed %s...", sub.URL))
+	f, err := feed.Fetch(ctx, sub.URL)
+	if err != nil {
+		return nil, err
+	}
+	if f.Title != "" {
+		sub.Title = f.Title
+	}
+	items := sub.Unseen(f.Items)
+	result := &FeedResult{Title: sub.Title, New: len(items)}
+	if maxItems > 0 && len(items) > maxItems {
+		items = items[:maxItems]
+	}
+	logger.Log(fmt.Sprintf("Feed found: %s (%d items, %d new, %d selected)", sub.Title, len(f.Items), result.New, len(items)))
+
+	// Only GUIDs still in the feed need to be remembered
+	sub.Forget(f.Items)
+	sub.SyncedAt = time.Now()
+	if err := st.Save(); err != nil {
+		return nil, fmt.Errorf("failed to save feed state: %w", err)
+	}
+
+	for i, item := range items {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		logger.Log(fmt.Sprintf("[%d/%d] %s", i+1, len(items), item.Title))
+		if item.URL() == "" {
+			logger.Log(fmt.Sprintf("[%d/%d] Skipped: no link or media file.", i+1, len(items)))
+			sub.MarkSeen(item.GUID)
+			continue
+		}
+
+		itemOpts := opts
+		itemOpts.FeedItem = &item
+		taskResult, err := s.ProcessTask(ctx, item.URL(), itemOpts, logger)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Log(fmt.Sprintf("[%d/%d] Failed: %v", i+1, len(items), err))
+			result.Failed++
+			continue
+		}
+		result.Results = append(result.Results, taskResult)
+		sub.MarkSeen(item.GUID)
+		if err := st.Save(); err != nil {
+			return nil, fmt.Errorf("failed to save feed state: %w", err)
+		}
+	}
+	if err := st.Save(); err != nil {
+		return nil, fmt.Errorf("failed to save feed state: %w", err)
+	}
+	logger.Log(fmt.Sprintf("Feed complete: %d processed, %d failed.", len(result.Results), result.Failed))
+	return result, nil
+}
+
+// feedItemMetadata reads a feed item: episodes through yt-dlp, completed with the
+// feed's metadata, and links straight through the article scraper.
+func (s *CoreService) feedItemMetadata(ctx context.Context, dl *downloader.Downloader, item *feed.Item, logger EventLogger) (metadataCheckpoint, error) {
+	if !item.IsMedia() {
+		logger.Log("Feed article. Scraping...")
+		meta, err := s.scrapeArticle(item.Link, logger)
+		if err != nil {
+			return metadataCheckpoint{}, fmt.Errorf("article ingestion failed: %v", err)
+		}
+		if meta.Title == "" {
+			meta.Title = item.Title
+		}
+		if meta.ArticleAuthor == "" {
+			meta.ArticleAuthor = item.Author
+		}
+		if meta.ArticlePublished.IsZero() {
+			meta.ArticlePublished = item.Published
+		}
+		return meta, nil
+	}
+
+	logger.Log("Fetching media metadata...")
+	info, err := dl.GetMediaInfo(ctx, item.URL())
+	if err != nil {
+		if ctx.Err() != nil {
+			return metadataCheckpoint{}, ctx.Err()
+		}
+		return metadataCheckpoint{}, fmt.Errorf("failed to read episode media: %w", err)
+	}
+	applyFeedItem(info, item)
+	logger.Log(fmt.Sprintf("Media found: %s (%s, %s)", info.Title, info.Uploader, storage.FormatTimestamp(info.Duration)))
+	return metadataCheckpoint{
+		Title:       info.Title,
+		Description: info.Description,
+		Media:       info,
+	}, nil
+}
+
+// applyFeedItem merges feed metadata into info. For media files linked from the
+// feed, yt-dlp only knows the file name, so the feed wins; for video pages it
+// only fills gaps.
+func applyFeedItem(info *downloader.MediaInfo, item *feed.Item) {
+	direct := item.Enclosure != ""
+	set := func(dst *string, v string) {
+		if v != "" && (direct || *dst == "") {
+			*dst = v
+		}
+	}
+	set(&info.Title, item.Title)
+	set(&info.Description, item.Description)
+	set(&info.Uploader, item.Author)
+	set(&info.Thumbnail, item.Image)
+	if !item.Published.IsZero() && (direct || info.UploadDate.IsZero()) {
+		info.UploadDate = item.Published
+	}
+	if info.Duration == 0 {
+		info.Duration = item.Duration
+	}
+	if direct && info.Extractor == "generic" {
+		// Episode files are often named "episode.mp3"; the GUID identifies them
+		info.ID = item.GUID
+		set(&info.WebpageURL, item.Link)
+	}
+}
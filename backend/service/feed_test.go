@@ -0,0 +1,39 @@
+package service
+
+import (
+	"Varys/backend/downloader"
+	"Varys/backend/feed"
+	"testing"
+	"time"
+)
+
+func TestApplyFeedItem(t *testing.T) {
+	published := time.Date(2023, 1, 2, 8, 0, 0, 0, time.UTC)
+
+	// yt-dlp's generic extractor only knows the file of an episode
+	info := &downloader.MediaInfo{ID: "episode", Title: "episode", Extractor: "generic", Duration: time.Minute}
+	applyFeedItem(info, &feed.Item{
+		GUID: "dt-001", Title: "Episode 1", Author: "Jane Host", Description: "Show notes",
+		Published: published, Duration: time.Hour, Link: "https://deeptalks.example/1",
+		Enclosure: "https://cdn.example/episode.mp3",
+	})
+	if info.Title != "Episode 1" || info.Uploader != "Jane Host" || info.Description != "Show notes" || !info.UploadDate.Equal(published) {
+		t.Errorf("episode = %+v", info)
+	}
+	if info.ID != "dt-001" || info.WebpageURL != "https://deeptalks.example/1" || info.Duration != time.Minute {
+		t.Errorf("episode identity = %+v", info)
+	}
+
+	// Video pages keep what the platform reports
+	video := &downloader.MediaInfo{ID: "abc123", Title: "Platform Title", Extractor: "youtube", Uploader: "Channel"}
+	applyFeedItem(video, &feed.Item{
+		GUID: "yt:video:abc123", Title: "Feed Title", Author: "Feed Author", Description: "From the feed",
+		Published: published, Link: "https://www.youtube.com/watch?v=abc123", VideoID: "abc123",
+	})
+	if video.Title != "Platform Title" || video.Uploader != "Channel" || video.ID != "abc123" {
+		t.Errorf("video = %+v", video)
+	}
+	if video.Description != "From the feed" || !video.UploadDate.Equal(published) {
+		t.Errorf("video gaps not filled: %+v", video)
+	}
+}
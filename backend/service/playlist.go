@@ -0,0 +1,104 @@
+package service
+
+import (
+	"Varys/backend/downloader"
+	"Varys/backend/storage"
+	"context"
+	"fmt"
+	"time"
+)
+
+// PlaylistResult contains the output of a processed playlist or channel.
+type PlaylistResult struct {
+	IndexPath string
+	Results   []*TaskResult // successfully processed entries, in playlist order
+	Failed    int
+}
+
+// ProcessPlaylist expands a playlist or channel URL, applies filter and runs
+// ProcessTask for every remaining entry. Failed entries are logged and skipped.
+// Each note links to an index note listing all entries.
+func (s *CoreService) ProcessPlaylist(ctx context.Context, url string, opts Options, filter downloader.PlaylistFilter, logger EventLogger) (*PlaylistResult, error) {
+	if _, err := opts.LoadNoteTemplate(); err != nil {
+		return nil, err
+	}
+
+	dl := downloader.NewDownloaderWithCookies(s.depManager, opts.Cookies)
+
+	logger.Log("Fetching playlist entries...")
+	playlist, err := dl.ListPlaylist(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list playlist: %w", err)
+	}
+	entries := playlist.Filter(filter)
+	logger.Log(fmt.Sprintf("Playlist found: %s (%d entries, %d selected)", playlist.Title, len(playlist.Entries), len(entries)))
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no playlist entries match the filters")
+	}
+	if n := filter.Undated(entries); n > 0 {
+		// Flat listings (e.g. YouTube channels) often carry no upload dates
+		logger.Log(fmt.Sprintf("Warning: %d of the selected entries have no upload date in the listing; the date filter could not be applied to them.", n))
+	}
+
+	sm := storage.NewManager(opts.vaultPath())
+	// The index goes through the ledger like any note: a rerun updates it (keeping
+	// the user's own sections), and a different note with the same title is not touched
+	indexTitle := sm.IndexNoteTitle(playlist.Title)
+	index := openIngestion(sm, "playlist:"+storage.NormalizeURL(url), url, indexTitle, storage.DuplicateUpdate, logger)
+	indexName := index.noteName(indexTitle)
+	opts.Playlist = indexName
+
+	result := &PlaylistResult{}
+	items := make([]storage.IndexItem, len(entries))
+	for i, entry := range entries {
+		items[i] = storage.IndexItem{Title: entry.Title, URL: entry.URL}
+	}
+	created := time.Now().Format("2006-01-02 15:04")
+	// The index is rewritten after every entry, so an interrupted run still leaves
+	// one listing what was done and what is pending
+	saveIndex := func() error {
+		content, err := sm.RenderIndexNote(storage.IndexData{
+			Title:       playlist.Title,
+			URL:         url,
+			Uploader:    playlist.Uploader,
+			CreatedTime: created,
+			Items:       items,
+		})
+		if err == nil {
+			result.IndexPath, indexName, err = index.save(url, indexTitle, indexName, "", content, logger)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save playlist index: %w", err)
+		}
+		return nil
+	}
+	if err := saveIndex(); err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		logger.Log(fmt.Sprintf("[%d/%d] %s", i+1, len(entries), entry.Title))
+
+		taskResult, err := s.ProcessTask(ctx, entry.URL, opts, logger)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Log(fmt.Sprintf("[%d/%d] Failed: %v", i+1, len(entries), err))
+			items[i].Error = err.Error()
+			result.Failed++
+		} else {
+			items[i].Note = taskResult.Title
+			result.Results = append(result.Results, taskResult)
+		}
+		if err := saveIndex(); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Log(fmt.Sprintf("Playlist complete: %d processed, %d failed.", len(result.Results), result.Failed))
+	return result, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"Varys/backend/dependency"
+	"Varys/backend/downloader"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logLines records log messages.
+type logLines struct {
+	discardLogger
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logLines) Log(msg string) {
+	l.mu.Lock()
+	l.lines = append(l.lines, msg)
+	l.mu.Unlock()
+}
+
+func TestProcessPlaylist_WarnsAboutUndatedEntries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows mock not implemented")
+	}
+	// Lists a channel without upload dates; every download fails
+	binDir := t.TempDir()
+	script := `#!/bin/sh
+case "$*" in *--flat-playlist*) ;; *) echo "download disabled" >&2; exit 1 ;; esac
+cat <<'JSON'
+{"id": "a1", "title": "Talk 1", "url": "https://www.youtube.com/watch?v=a1", "playlist_id": "UC1", "playlist_title": "Channel"}
+{"id": "a2", "title": "Talk 2", "url": "https://www.youtube.com/watch?v=a2", "upload_date": "20240105", "playlist_id": "UC1"}
+JSON
+`
+	if err := os.WriteFile(filepath.Join(binDir, "yt-dlp"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	vault := t.TempDir()
+	filter := downloader.PlaylistFilter{After: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	logger := &logLines{}
+	s := NewCoreService(&dependency.Manager{})
+	res, err := s.ProcessPlaylist(context.Background(), "https://www.youtube.com/channel/UC1", Options{VaultPath: vault}, filter, logger)
+	if err != nil {
+		t.Fatalf("ProcessPlaylist failed: %v", err)
+	}
+	if res.Failed != 2 {
+		t.Errorf("Expected both entries to be attempted, got %+v", res)
+	}
+
+	warned := false
+	for _, line := range logger.lines {
+		if strings.Contains(line, "1 of the selected entries have no upload date") {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Errorf("Expected a warning about the undated entry, got %q", logger.lines)
+	}
+}
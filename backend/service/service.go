@@ -3,12 +3,14 @@ package service
 import (
 	"Varys/backend/analyzer"
 	"Varys/backend/dependency"
+	"Varys/backend/document"
 	"Varys/backend/downloader"
 	"Varys/backend/scraper"
 	"Varys/backend/storage"
 	"Varys/backend/transcriber"
 	"Varys/backend/translation"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -32,77 +34,168 @@ func NewCoreService(dm *dependency.Manager) *CoreService {
 }
 
 // ProcessTask runs the full pipeline: download, transcribe, analyze, and save.
+// Stage outputs are checkpointed in a per-URL work directory (see Options.WorkDir),
+// so rerunning an unfinished task skips completed stages unless opts.FromStage
+// forces them. Once the note is saved, a rerun starts over.
 func (s *CoreService) ProcessTask(ctx context.Context, url string, opts Options, logger EventLogger) (*TaskResult, error) {
-	tempDir, err := os.MkdirTemp("", "varys_task_")
+	started := time.Now()
+
+	// Template mistakes are reported before anything is downloaded
+	tmpl, err := opts.LoadNoteTemplate()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+		return nil, err
 	}
-	defer os.RemoveAll(tempDir)
 
 	// Detect if input is a local file
 	isLocalFile := false
+	source := url
 	if info, err := os.Stat(url); err == nil && !info.IsDir() {
 		isLocalFile = true
+		if abs, err := filepath.Abs(url); err == nil {
+			source = abs
+		}
+		// A different file saved under the same name (e.g. a new recording)
+		// must not resume from the old file's checkpoints
+		source += fmt.Sprintf("#%d-%d", info.Size(), info.ModTime().UnixNano())
 		logger.Log("Local file detected: " + url)
 	}
 
-	var videoTitle, videoDescription string
+	work, err := openWorkDir(ctx, opts.WorkDir, source, opts.FromStage, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer work.close()
+	tempDir := work.Dir
+
 	var transcript, sourceLang string
+	var segments []transcriber.Segment
 	var mediaPath string
-	isArticle := false
 
-	dl := downloader.NewDownloader(s.depManager)
+	dl := downloader.NewDownloaderWithCookies(s.depManager, opts.Cookies)
 
-	if isLocalFile {
-		videoTitle = strings.TrimSuffix(filepath.Base(url), filepath.Ext(url))
-		videoDescription = "Local file: " + url
-		logger.Log(fmt.Sprintf("Using filename as title: %s", videoTitle))
-	} else {
-		// Attempt to get media info
-		logger.Log("Fetching media metadata...")
-		title, err := dl.GetVideoTitle(url)
-		if err != nil || title == "" {
-			logger.Log("Media not detected. Attempting to scrape as article...")
-			art, sErr := s.scraper.Scrape(url)
-			if sErr != nil {
-				return nil, fmt.Errorf("content ingestion failed (tried media and article): %v", sErr)
+	// 1. Metadata
+	var meta metadataCheckpoint
+	loaded := work.load(StageMetadata, "", &meta)
+	if loaded && meta.DocumentPath != "" {
+		if _, err := os.Stat(meta.DocumentPath); err != nil {
+			// The document file is gone; read it again
+			work.invalidate()
+			loaded = false
+		}
+	}
+	if loaded {
+		logger.Log(fmt.Sprintf("Resuming: using saved metadata (%s).", meta.Title))
+	} else if isLocalFile && document.Detect(url) != "" {
+		// Documents skip download and transcription. Attached formats are read
+		// from a copy, since the copy is moved into the vault at the end.
+		path := url
+		if document.Attachable(document.Detect(url)) {
+			path = filepath.Join(tempDir, filepath.Base(url))
+			if err := copyFile(url, path); err != nil {
+				return nil, fmt.Errorf("failed to copy local file: %w", err)
 			}
-			isArticle = true
-			videoTitle = art.Title
-			videoDescription = "Article: " + url
-			transcript = art.Content
-			sourceLang = art.Language
-			logger.Log(fmt.Sprintf("Article detected: %s (Language: %s)", videoTitle, sourceLang))
-		} else {
-			videoTitle = title
-			logger.Log(fmt.Sprintf("Media found: %s", videoTitle))
-			videoDescription, _ = dl.GetVideoDescription(url)
 		}
+		meta, err = s.extractDocument(ctx, path, url, logger)
+		if err != nil {
+			return nil, err
+		}
+		meta.IsLocalFile = true
+		work.save(StageMetadata, "", meta)
+	} else if isLocalFile {
+		meta = metadataCheckpoint{
+			Title:       strings.TrimSuffix(filepath.Base(url), filepath.Ext(url)),
+			Description: "Local file: " + url,
+			IsLocalFile: true,
+		}
+		logger.Log(fmt.Sprintf("Using filename as title: %s", meta.Title))
+		work.save(StageMetadata, "", meta)
+	} else if opts.FeedItem != nil {
+		meta, err = s.feedItemMetadata(ctx, dl, opts.FeedItem, logger)
+		if err != nil {
+			return nil, err
+		}
+		work.save(StageMetadata, "", meta)
+	} else {
+		meta, err = s.fetchMetadata(ctx, dl, url, tempDir, logger)
+		if err != nil {
+			return nil, err
+		}
+		work.save(StageMetadata, "", meta)
+	}
+	videoTitle, videoDescription := meta.Title, meta.Description
+	media, isArticle, doc := meta.Media, meta.IsArticle, meta.Document
+	if isArticle {
+		transcript = meta.ArticleText
+		sourceLang = meta.ArticleLang
+	}
+	if doc != nil {
+		transcript = doc.Text()
+		sourceLang = doc.Language
 	}
 
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
+	// Was this source ingested before?
+	vaultPath := opts.vaultPath()
+	sm := storage.NewManager(vaultPath)
+	sm.Template = tmpl
+	safeTitle := sm.SanitizeFilename(videoTitle)
+
+	sourceKey := storage.SourceKey(url, media)
+	if isLocalFile {
+		if key, err := storage.FileKey(url); err == nil {
+			sourceKey = key
+		}
+	}
+	ingest := openIngestion(sm, sourceKey, url, safeTitle, opts.OnDuplicate, logger)
+	if ingest.skip() {
+		logger.Log(fmt.Sprintf("Already ingested as %s. Skipping (duplicate mode: skip).", ingest.existing))
+		work.complete()
+		return &TaskResult{
+			NotePath:  sm.NotePath(ingest.existing),
+			MediaFile: ingest.prev.Media,
+			Title:     ingest.existing,
+			Skipped:   true,
+		}, nil
+	}
+
 	// 2. Download/Prepare Media (Only for non-articles)
-	if !isArticle {
-		if isLocalFile {
-			logger.Log("Preparing local file...")
-			destPath := filepath.Join(tempDir, filepath.Base(url))
-			if err := copyFile(url, destPath); err != nil {
-				return nil, fmt.Errorf("failed to copy local file: %w", err)
+	mediaKey := stageKey(fmt.Sprint(opts.AudioOnly))
+	if !isArticle && doc == nil {
+		var mcp mediaCheckpoint
+		if work.load(StageMedia, mediaKey, &mcp) {
+			if _, err := os.Stat(mcp.Path); err == nil {
+				mediaPath = mcp.Path
+				logger.Log("Resuming: using downloaded media.")
+			} else {
+				work.invalidate()
 			}
-			mediaPath = destPath
-		} else {
-			logger.Log(fmt.Sprintf("Downloading media from %s...", url))
-			mediaPath, err = dl.DownloadMedia(url, tempDir, opts.AudioOnly, func(msg string) {
-				if ctx.Err() == nil {
-					logger.Log("[DL] " + msg)
+		}
+		if mediaPath == "" {
+			if isLocalFile {
+				logger.Log("Preparing local file...")
+				destPath := filepath.Join(tempDir, filepath.Base(url))
+				if err := copyFile(url, destPath); err != nil {
+					return nil, fmt.Errorf("failed to copy local file: %w", err)
+				}
+				mediaPath = destPath
+			} else {
+				logger.Log(fmt.Sprintf("Downloading media from %s...", url))
+				mediaPath, err = dl.DownloadMedia(ctx, url, tempDir, opts.AudioOnly, func(msg string) {
+					if ctx.Err() == nil {
+						logger.Log("[DL] " + msg)
+					}
+				})
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					return nil, fmt.Errorf("download failed: %w", err)
 				}
-			})
-			if err != nil {
-				return nil, fmt.Errorf("download failed: %w", err)
 			}
+			work.save(StageMedia, mediaKey, mediaCheckpoint{Path: mediaPath})
 		}
 		logger.Log(fmt.Sprintf("Media ready: %s", mediaPath))
 
@@ -110,19 +203,39 @@ func (s *CoreService) ProcessTask(ctx context.Context, url string, opts Options,
 			return nil, ctx.Err()
 		}
 
-		// 3. Transcribe (Only for non-articles)
-		logger.Log("Transcribing audio...")
-		tr := transcriber.NewTranscriber(s.depManager)
-		transcript, sourceLang, err = tr.Transcribe(mediaPath, opts.ModelPath, func(msg string) {
-			if ctx.Err() == nil {
-				logger.Log("[Whisper] " + msg)
-			}
-		})
-		if err != nil {
-			logger.Log("Transcription failed. Analysis will be skipped.")
-			transcript = "Transcription failed."
+		// 3. Transcribe (Only for non-articles), preferring the platform's subtitles
+		transcriptKey := stageKey(opts.SubtitleSource, opts.ModelPath)
+		var tcp transcriptCheckpoint
+		if work.load(StageTranscript, transcriptKey, &tcp) {
+			transcript, sourceLang, segments = tcp.Text, tcp.Language, tcp.Segments
+			logger.Log(fmt.Sprintf("Resuming: using saved transcript (Language: %s, %d segments).", sourceLang, len(segments)))
+		} else if subs := platformTranscript(ctx, dl, url, tempDir, media, opts.SubtitleSource, logger); subs != nil {
+			transcript = subs.Text
+			sourceLang = subs.Language
+			segments = subs.Segments
+			logger.Log(fmt.Sprintf("Using platform subtitles (Language: %s, %d segments). Skipping transcription.", sourceLang, len(segments)))
+			work.save(StageTranscript, transcriptKey, transcriptCheckpoint{Language: sourceLang, Text: transcript, Segments: segments})
 		} else {
-			logger.Log(fmt.Sprintf("Transcription complete (Language: %s).", sourceLang))
+			logger.Log("Transcribing audio...")
+			tr := transcriber.NewTranscriber(s.depManager)
+			result, err := tr.TranscribeSegments(ctx, mediaPath, opts.ModelPath, func(msg string) {
+				if ctx.Err() == nil {
+					logger.Log("[Whisper] " + msg)
+				}
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				logger.Log("Transcription failed. Analysis will be skipped.")
+				transcript = "Transcription failed."
+			} else {
+				transcript = result.Text
+				sourceLang = result.Language
+				segments = result.Segments
+				logger.Log(fmt.Sprintf("Transcription complete (Language: %s, %d segments).", sourceLang, len(segments)))
+				work.save(StageTranscript, transcriptKey, transcriptCheckpoint{Language: sourceLang, Text: transcript, Segments: segments})
+			}
 		}
 	}
 
@@ -133,48 +246,81 @@ func (s *CoreService) ProcessTask(ctx context.Context, url string, opts Options,
 	// 4. Translate & Analyze (Common for both)
 	analysis := &analyzer.AnalysisResult{}
 	var translationPairs []translation.TranslationPair
+	var glossary *translation.Glossary
+	var glossaryViolations []translation.GlossaryViolation
 	summary := "No analysis performed."
 
 	if transcript != "Transcription failed." {
 		targetLang := opts.TargetLanguage
 		// Default is now handled globally in backend/config/config.go
 
-		// Smart Translation Logic
-		shouldTranslate := true
-		isChineseSource := sourceLang == "zh"
-		isChineseTarget := strings.Contains(targetLang, "Chinese")
-		isEnglishSource := sourceLang == "en"
-		isEnglishTarget := strings.Contains(targetLang, "English")
-
-		// Only skip if source and target are BOTH Chinese or BOTH English
-		if (isChineseSource && isChineseTarget) || (isEnglishSource && isEnglishTarget) {
-			shouldTranslate = false
-			logger.Log(fmt.Sprintf("Source language (%s) matches target (%s). Skipping translation.", sourceLang, targetLang))
-		}
-
-		if shouldTranslate {
-			logger.Log(fmt.Sprintf("Translating to %s...", targetLang))
-			
-			// Use the configured AI Provider for translation as well
-			translationProvider := analyzer.NewAnalyzer(opts.AIProvider, opts.OpenAIKey, opts.TranslationMod).GetProvider()
-			if opts.AIProvider == "openai" && opts.TranslationMod == "qwen3:0.6b" {
-				// If provider is OpenAI but translation model is default Ollama one, 
-				// fallback to OpenAI default for translation to avoid local connection error
-				translationProvider = analyzer.NewAnalyzer("openai", opts.OpenAIKey, "gpt-4o-mini").GetProvider()
+		translationKey := stageKey(targetLang, opts.AIProvider, opts.TranslationMod, opts.GlossaryPath)
+		var trcp translationCheckpoint
+		if work.load(StageTranslation, translationKey, &trcp) {
+			translationPairs, glossaryViolations = trcp.Pairs, trcp.Violations
+			logger.Log(fmt.Sprintf("Resuming: using saved translation (%d pairs).", len(translationPairs)))
+		} else {
+			// Smart Translation Logic
+			shouldTranslate := true
+			isChineseSource := sourceLang == "zh"
+			isChineseTarget := strings.Contains(targetLang, "Chinese")
+			isEnglishSource := sourceLang == "en"
+			isEnglishTarget := strings.Contains(targetLang, "English")
+
+			// Only skip if source and target are BOTH Chinese or BOTH English
+			if (isChineseSource && isChineseTarget) || (isEnglishSource && isEnglishTarget) {
+				shouldTranslate = false
+				logger.Log(fmt.Sprintf("Source language (%s) matches target (%s). Skipping translation.", sourceLang, targetLang))
+				work.save(StageTranslation, translationKey, translationCheckpoint{})
 			}
 
-			translator := translation.NewTranslator(translationProvider)
-			translationPairs, err = translator.Translate(ctx, transcript, targetLang, opts.ContextSize, func(current, total int) {
-				if ctx.Err() == nil {
-					percent := float64(current+1) / float64(total) * 100
-					logger.Progress(percent)
+			if shouldTranslate {
+				logger.Log(fmt.Sprintf("Translating to %s...", targetLang))
+
+				// Use the configured AI Provider for translation as well
+				translationProvider := analyzer.NewProvider(opts.ProviderConfig(opts.TranslationMod))
+				if opts.AIProvider == "openai" && opts.TranslationMod == "qwen3:0.6b" {
+					// If provider is OpenAI but translation model is default Ollama one,
+					// fallback to OpenAI default for translation to avoid local connection error
+					translationProvider = analyzer.NewAnalyzer("openai", opts.OpenAIKey, "gpt-4o-mini").GetProvider()
+				}
+				if analyzer.IsOpenAICompatible(opts.AIProvider) && opts.TranslationMod == "qwen3:0.6b" {
+					// Same for OpenAI-compatible servers: use the server's configured model
+					translationProvider = analyzer.NewProvider(opts.ProviderConfig(opts.CompatibleModel))
+				}
+
+				if opts.GlossaryPath != "" {
+					glossary, err = translation.LoadGlossary(opts.GlossaryPath)
+					if err != nil {
+						logger.Log(fmt.Sprintf("Glossary not loaded: %v", err))
+					} else {
+						logger.Log(fmt.Sprintf("Loaded glossary with %d terms.", len(glossary.Entries)))
+					}
+				}
+
+				translator := translation.NewTranslatorWithOptions(opts.withFallbacks(translationProvider, logger), translation.Options{
+					Workers:   opts.TranslationWorkers,
+					RateLimit: opts.TranslationRateLimit,
+					Glossary:  glossary,
+				})
+				translationPairs, err = translator.Translate(ctx, transcript, targetLang, opts.ContextSize, func(done, total int) {
+					if ctx.Err() == nil {
+						logger.Progress(float64(done) / float64(total) * 100)
+					}
+				})
+				if err != nil {
+					logger.Log(fmt.Sprintf("Translation failed: %v", err))
+				} else {
+					logger.Progress(100.0)
+					quality := translation.Summarize(translationPairs)
+					logger.Log(fmt.Sprintf("Translation complete. %d/%d aligned, %d retried, %d missing.", quality.Aligned, quality.Total, quality.Retried, quality.Missing))
+
+					glossaryViolations = translation.CheckGlossary(translationPairs, glossary)
+					if len(glossaryViolations) > 0 {
+						logger.Log(fmt.Sprintf("Glossary check: %d sentence(s) did not use the glossary translation.", len(glossaryViolations)))
+					}
+					work.save(StageTranslation, translationKey, translationCheckpoint{Pairs: translationPairs, Violations: glossaryViolations})
 				}
-			})
-			if err != nil {
-				logger.Log(fmt.Sprintf("Translation failed: %v", err))
-			} else {
-				logger.Progress(100.0)
-				logger.Log("Translation complete.")
 			}
 		}
 
@@ -183,35 +329,58 @@ func (s *CoreService) ProcessTask(ctx context.Context, url string, opts Options,
 		}
 
 		// Analysis
-		logger.Log("Analyzing content...")
-		provider := opts.AIProvider
-		apiKey := opts.OpenAIKey
-		model := opts.LLMModel
-		if provider == "openai" {
-			model = opts.OpenAIModel
-		}
-
-		// Log rendered prompt for visibility
-		var displayPrompt string
-		if opts.CustomPrompt != "" {
-			displayPrompt = analyzer.RenderPrompt(opts.CustomPrompt, targetLang, transcript, true)
+		analysisKey := stageKey(targetLang, opts.CustomPrompt, opts.AIProvider, opts.AnalysisModel(), opts.AnalysisMode, fmt.Sprint(opts.ContextSize))
+		var acp analysisCheckpoint
+		if work.load(StageAnalysis, analysisKey, &acp) && acp.Result != nil {
+			analysis = acp.Result
+			summary = analysis.Summary
+			logger.Log(fmt.Sprintf("Resuming: using saved analysis (%s, %s).", analysis.Provider, analysis.Model))
 		} else {
-			displayPrompt = analyzer.RenderPrompt(analyzer.GetDefaultPrompt(), targetLang, transcript, false)
-		}
-		logger.Log(fmt.Sprintf("--- RENDERED PROMPT START ---\n%s\n--- RENDERED PROMPT END ---", displayPrompt))
+			logger.Log("Analyzing content...")
+			az := analyzer.NewAnalyzerWithProvider(opts.withFallbacks(analyzer.NewProvider(opts.ProviderConfig(opts.AnalysisModel())), logger))
+			onToken := func(token string) {
+				if ctx.Err() == nil {
+					logger.AnalysisChunk(token)
+				}
+			}
 
-		az := analyzer.NewAnalyzer(provider, apiKey, model)
-		analysis, err = az.Analyze(ctx, transcript, opts.CustomPrompt, targetLang, opts.ContextSize, func(token string) {
-			if ctx.Err() == nil {
-				logger.AnalysisChunk(token)
+			chunked := opts.AnalysisMode == analyzer.AnalysisModeChunked ||
+				(opts.AnalysisMode != analyzer.AnalysisModeSingle && analyzer.NeedsChunking(transcript, opts.CustomPrompt, opts.ContextSize))
+
+			if chunked {
+				logger.Log(fmt.Sprintf("Content is ~%d tokens (context: %d). Using chunked map-reduce analysis.", analyzer.EstimateTokens(transcript), opts.ContextSize))
+				describe := windowPages(doc, transcript, opts.CustomPrompt, opts.ContextSize)
+				analysis, err = az.AnalyzeChunked(ctx, transcript, opts.CustomPrompt, targetLang, opts.ContextSize, func(done, total int, stage string) {
+					if ctx.Err() == nil {
+						logger.Log(fmt.Sprintf("Chunked analysis: %s", describe(done, stage)))
+						logger.Progress(float64(done) / float64(total) * 100)
+					}
+				}, onToken)
+			} else {
+				// Log rendered prompt for visibility
+				var displayPrompt string
+				if opts.CustomPrompt != "" {
+					displayPrompt = analyzer.RenderPrompt(opts.CustomPrompt, targetLang, transcript, true)
+				} else {
+					displayPrompt = analyzer.RenderPrompt(analyzer.GetDefaultPrompt(), targetLang, transcript, false)
+				}
+				logger.Log(fmt.Sprintf("--- RENDERED PROMPT START ---\n%s\n--- RENDERED PROMPT END ---", displayPrompt))
+
+				analysis, err = az.Analyze(ctx, transcript, opts.CustomPrompt, targetLang, opts.ContextSize, onToken)
+			}
+			if err != nil {
+				var invalid *analyzer.InvalidOutputError
+				if errors.As(err, &invalid) {
+					logger.Log(fmt.Sprintf("Analysis failed: %v\n--- LAST RAW RESPONSE ---\n%s", err, invalid.Raw))
+				} else {
+					logger.Log(fmt.Sprintf("Analysis failed: %v", err))
+				}
+				analysis = &analyzer.AnalysisResult{}
+			} else {
+				summary = analysis.Summary
+				logger.Log(fmt.Sprintf("Analysis complete (%s, %s).", analysis.Provider, analysis.Model))
+				work.save(StageAnalysis, analysisKey, analysisCheckpoint{Result: analysis})
 			}
-		})
-		if err != nil {
-			logger.Log(fmt.Sprintf("Analysis failed: %v", err))
-			analysis = &analyzer.AnalysisResult{}
-		} else {
-			summary = analysis.Summary
-			logger.Log("Analysis complete.")
 		}
 	}
 
@@ -220,52 +389,163 @@ func (s *CoreService) ProcessTask(ctx context.Context, url string, opts Options,
 	}
 
 	// 5. Save to Storage
-	vaultPath := opts.VaultPath
-	if vaultPath == "" {
-		home, _ := os.UserHomeDir()
-		vaultPath = home
+	author, published := meta.ArticleAuthor, meta.ArticlePublished
+	if doc != nil {
+		author, published = doc.Author, doc.Published
 	}
-	sm := storage.NewManager(vaultPath)
-	safeTitle := sm.SanitizeFilename(videoTitle)
 
+	// Assets are named after the note, so they are moved once the ledger has
+	// settled the final name (another task may have saved this source meanwhile)
 	var finalMedia string
-	if !isArticle {
-		finalMedia, err = sm.MoveMedia(mediaPath, safeTitle)
-		if err != nil {
-			return nil, fmt.Errorf("failed to move media: %w", err)
+	prepare := func(noteName string) (string, string, error) {
+		if noteName != safeTitle {
+			logger.Log(fmt.Sprintf("Saving as %s.", noteName))
 		}
-	}
 
-	noteData := storage.NoteData{
-		Title:            safeTitle,
-		URL:              url,
-		Language:         opts.TargetLanguage,
-		Description:      videoDescription,
-		Summary:          summary,
-		KeyPoints:        analysis.KeyPoints,
-		Tags:             analysis.Tags,
-		Assessment:       analysis.Assessment,
-		OriginalText:     transcript,
-		TranslationPairs: translationPairs,
-		AudioFile:        finalMedia,
-		AssetsFolder:     "assets",
-		CreatedTime:      time.Now().Format("2006-01-02 15:04"),
-		AIProvider:       analysis.Provider,
-		AIModel:          analysis.Model,
-	}
-
-	notePath, err := sm.SaveNote(noteData)
+		var err error
+		var subtitleFiles []string
+		if doc != nil {
+			if meta.DocumentPath != "" {
+				finalMedia, err = sm.MoveMedia(meta.DocumentPath, noteName)
+				if err != nil {
+					return "", "", fmt.Errorf("failed to move document: %w", err)
+				}
+				// The document now lives in the vault; point the checkpoint there for later reruns
+				meta.DocumentPath = filepath.Join(vaultPath, "assets", finalMedia)
+				work.save(StageMetadata, "", meta)
+			}
+		} else if !isArticle {
+			finalMedia, err = sm.MoveMedia(mediaPath, noteName)
+			if err != nil {
+				return "", "", fmt.Errorf("failed to move media: %w", err)
+			}
+			// The media now lives in the vault; point the checkpoint there for later reruns
+			work.save(StageMedia, mediaKey, mediaCheckpoint{Path: filepath.Join(vaultPath, "assets", finalMedia)})
+
+			subtitleFiles, err = sm.WriteSubtitles(finalMedia, segments, translationPairs)
+			if err != nil {
+				logger.Log(fmt.Sprintf("Failed to write subtitles: %v", err))
+			} else if len(subtitleFiles) > 0 {
+				logger.Log(fmt.Sprintf("Subtitles written: %s", strings.Join(subtitleFiles, ", ")))
+			}
+		}
+
+		now := time.Now()
+		noteData := storage.NoteData{
+			Title:              safeTitle,
+			URL:                url,
+			Language:           opts.TargetLanguage,
+			Description:        videoDescription,
+			Media:              media,
+			Document:           doc,
+			Summary:            summary,
+			KeyPoints:          analysis.KeyPoints,
+			Tags:               analysis.Tags,
+			Assessment:         analysis.Assessment,
+			OriginalText:       transcript,
+			Segments:           segments,
+			TranslationPairs:   translationPairs,
+			TranslationQuality: translation.Summarize(translationPairs),
+			GlossaryViolations: glossaryViolations,
+			AudioFile:          finalMedia,
+			SubtitleFiles:      subtitleFiles,
+			AssetsFolder:       "assets",
+			Created:            now,
+			CreatedTime:        now.Format("2006-01-02 15:04"),
+			ProcessingTime:     now.Sub(started),
+			Author:             author,
+			Published:          published,
+			AIProvider:         analysis.Provider,
+			AIModel:            analysis.Model,
+			Playlist:           opts.Playlist,
+		}
+		content, err := sm.RenderNote(noteData)
+		return content, finalMedia, err
+	}
+	notePath, noteName, err := ingest.saveWith(url, safeTitle, ingest.noteName(safeTitle), prepare, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save note: %w", err)
 	}
+	if ingest.skip() {
+		logger.Log(fmt.Sprintf("Already ingested as %s. Skipping (duplicate mode: skip).", ingest.existing))
+		work.complete()
+		return &TaskResult{
+			NotePath:  sm.NotePath(ingest.existing),
+			MediaFile: ingest.prev.Media,
+			Title:     ingest.existing,
+			Skipped:   true,
+		}, nil
+	}
 
+	work.complete()
 	return &TaskResult{
 		NotePath:  notePath,
 		MediaFile: finalMedia,
-		Title:     safeTitle,
+		Title:     noteName,
 	}, nil
 }
 
+// fetchMetadata finds out what url points to: a document, media yt-dlp can
+// download, or failing both, an article.
+func (s *CoreService) fetchMetadata(ctx context.Context, dl *downloader.Downloader, url, dir string, logger EventLogger) (metadataCheckpoint, error) {
+	// Links to PDFs skip the media probe
+	triedDocument := document.DetectURL(url) != ""
+	if triedDocument {
+		logger.Log("Document link detected. Downloading...")
+		if meta, ok, err := s.downloadDocument(ctx, url, dir, logger); ok || err != nil {
+			return meta, err
+		}
+	}
+
+	// Attempt to get media info
+	logger.Log("Fetching media metadata...")
+	info, err := dl.GetMediaInfo(ctx, url)
+	if err == nil {
+		logger.Log(fmt.Sprintf("Media found: %s (%s, %s)", info.Title, info.Uploader, storage.FormatTimestamp(info.Duration)))
+		return metadataCheckpoint{
+			Title:       info.Title,
+			Description: info.Description,
+			Media:       info,
+		}, nil
+	}
+	if ctx.Err() != nil {
+		return metadataCheckpoint{}, ctx.Err()
+	}
+
+	// Documents are not always recognizable by their URL
+	if !triedDocument {
+		if meta, ok, err := s.downloadDocument(ctx, url, dir, logger); ok || err != nil {
+			return meta, err
+		}
+	}
+
+	logger.Log("Media not detected. Attempting to scrape as article...")
+	meta, err := s.scrapeArticle(url, logger)
+	if err != nil {
+		return metadataCheckpoint{}, fmt.Errorf("content ingestion failed (tried media and article): %v", err)
+	}
+	return meta, nil
+}
+
+// scrapeArticle reads url as a web article.
+func (s *CoreService) scrapeArticle(url string, logger EventLogger) (metadataCheckpoint, error) {
+	art, err := s.scraper.Scrape(url)
+	if err != nil {
+		return metadataCheckpoint{}, err
+	}
+	meta := metadataCheckpoint{
+		Title:            art.Title,
+		Description:      "Article: " + url,
+		IsArticle:        true,
+		ArticleText:      art.Content,
+		ArticleLang:      art.Language,
+		ArticleAuthor:    art.Author,
+		ArticlePublished: art.PublishedAt,
+	}
+	logger.Log(fmt.Sprintf("Article detected: %s (Language: %s)", meta.Title, meta.ArticleLang))
+	return meta, nil
+}
+
 func copyFile(src, dst string) error {
 	in, err := os.Open(src)
 	if err != nil {
@@ -282,3 +562,76 @@ func copyFile(src, dst string) error {
 	_, err = io.Copy(out, in)
 	return err
 }
+
+func (o Options) vaultPath() string {
+	if o.VaultPath != "" {
+		return o.VaultPath
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// AnalysisModel returns the model name configured for the selected provider.
+func (o Options) AnalysisModel() string {
+	return o.modelFor(o.AIProvider)
+}
+
+func (o Options) modelFor(providerType string) string {
+	switch {
+	case providerType == "openai":
+		return o.OpenAIModel
+	case analyzer.IsOpenAICompatible(providerType):
+		return o.CompatibleModel
+	default:
+		return o.LLMModel
+	}
+}
+
+// ProviderConfig builds the analyzer settings for the selected provider and the given model.
+func (o Options) ProviderConfig(model string) analyzer.ProviderConfig {
+	return o.providerConfigFor(o.AIProvider, model)
+}
+
+func (o Options) providerConfigFor(providerType, model string) analyzer.ProviderConfig {
+	cfg := analyzer.ProviderConfig{Type: providerType, Model: model}
+	switch {
+	case providerType == "openai":
+		cfg.APIKey = o.OpenAIKey
+	case analyzer.IsOpenAICompatible(providerType):
+		cfg.APIKey = o.CompatibleKey
+		cfg.BaseURL = o.CompatibleBaseURL
+		cfg.Headers = o.CompatibleHeaders
+	default:
+		cfg.BaseURL = o.Ollama.Host
+		cfg.Headers = o.Ollama.Headers
+		cfg.Timeout = o.Ollama.Timeout
+		cfg.KeepAlive = o.Ollama.KeepAlive
+	}
+	return cfg
+}
+
+// withFallbacks wraps primary in a FallbackProvider when fallbacks are configured.
+// Switches are reported through logger, and marked in the analysis stream when the
+// failed provider had streamed part of its answer.
+func (o Options) withFallbacks(primary analyzer.LLMProvider, logger EventLogger) analyzer.LLMProvider {
+	if len(o.Fallbacks) == 0 {
+		return primary
+	}
+	providers := []analyzer.LLMProvider{primary}
+	for _, fb := range o.Fallbacks {
+		model := fb.Model
+		if model == "" {
+			model = o.modelFor(fb.Provider)
+		}
+		providers = append(providers, analyzer.NewProvider(o.providerConfigFor(fb.Provider, model)))
+	}
+	chain := analyzer.NewFallbackProvider(providers...)
+	chain.OnFallback = func(failed analyzer.LLMProvider, err error, streamed bool) {
+		logger.Log(fmt.Sprintf("Provider %s (%s) failed: %v. Trying next fallback provider...", failed.Name(), failed.Model(), err))
+		if streamed {
+			// The partial answer stays on screen; mark where the next one starts
+			logger.AnalysisChunk(fmt.Sprintf("\n\n--- %s (%s) failed, starting over with the next provider ---\n\n", failed.Name(), failed.Model()))
+		}
+	}
+	return chain
+}
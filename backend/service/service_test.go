@@ -4,7 +4,10 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 	"Varys/backend/dependency"
+	"Varys/backend/downloader"
+	"Varys/backend/transcriber"
 )
 
 func TestNewCoreService(t *testing.T) {
@@ -42,3 +45,24 @@ func TestCopyFile(t *testing.T) {
 		t.Errorf("Expected content %s, got %s", content, string(got))
 	}
 }
+
+func TestSubtitleProblem(t *testing.T) {
+	media := &downloader.MediaInfo{Duration: 10 * time.Minute}
+	speech := "this sentence is long enough to count as speech"
+
+	tests := []struct {
+		name     string
+		segments []transcriber.Segment
+		ok       bool
+	}{
+		{"empty", nil, false},
+		{"sound tags only", []transcriber.Segment{{Start: 0, End: 10 * time.Minute, Text: "[Music] (Applause)"}}, false},
+		{"short coverage", []transcriber.Segment{{Start: 0, End: time.Minute, Text: speech}}, false},
+		{"good", []transcriber.Segment{{Start: 0, End: time.Minute, Text: speech}, {Start: 8 * time.Minute, End: 9 * time.Minute, Text: speech}}, true},
+	}
+	for _, tt := range tests {
+		if reason := subtitleProblem(tt.segments, media); (reason == "") != tt.ok {
+			t.Errorf("%s: subtitleProblem() = %q, want ok=%v", tt.name, reason, tt.ok)
+		}
+	}
+}
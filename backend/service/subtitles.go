@@ -0,0 +1,90 @@
+package service
+
+import (
+	"Varys/backend/downloader"
+	"Varys/backend/transcriber"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// minSubtitleCoverage is the share of the media duration subtitles must span to replace whisper.
+const minSubtitleCoverage = 0.5
+
+// platformTranscript uses the platform's own subtitles when the preference allows it.
+// It returns nil when no suitable track exists, so the caller falls back to whisper.
+func platformTranscript(ctx context.Context, dl *downloader.Downloader, url, tempDir string, media *downloader.MediaInfo, preference string, logger EventLogger) *transcriber.Transcript {
+	if media == nil {
+		return nil
+	}
+	track, ok := media.SubtitleTrack(preference)
+	if !ok {
+		if preference != downloader.SubtitlesWhisper {
+			logger.Log("No suitable platform subtitles found. Using whisper.")
+		}
+		return nil
+	}
+
+	kind := "manual"
+	if track.Auto {
+		kind = "auto-generated"
+	}
+	logger.Log(fmt.Sprintf("Fetching %s subtitles (%s)...", kind, track.Lang))
+
+	path, err := dl.DownloadSubtitles(ctx, url, tempDir, track)
+	if err != nil {
+		logger.Log(fmt.Sprintf("Subtitles unavailable: %v. Using whisper.", err))
+		return nil
+	}
+	defer os.Remove(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Log(fmt.Sprintf("Subtitles unreadable: %v. Using whisper.", err))
+		return nil
+	}
+	segments, err := transcriber.ParseVTT(string(data))
+	if err != nil {
+		logger.Log(fmt.Sprintf("Subtitles could not be parsed: %v. Using whisper.", err))
+		return nil
+	}
+	if reason := subtitleProblem(segments, media); reason != "" {
+		logger.Log(fmt.Sprintf("Subtitles rejected (%s). Using whisper.", reason))
+		return nil
+	}
+
+	return &transcriber.Transcript{
+		Language: track.Language(),
+		Segments: segments,
+		Text:     transcriber.JoinSegments(segments),
+	}
+}
+
+// soundTagRegex matches non-speech annotations such as [Music] or (Applause).
+var soundTagRegex = regexp.MustCompile(`[\[(（【][^\])）】]*[\])）】]`)
+
+// subtitleProblem returns why the segments are not good enough to skip transcription,
+// or "" if they are.
+func subtitleProblem(segments []transcriber.Segment, media *downloader.MediaInfo) string {
+	if len(segments) == 0 {
+		return "empty track"
+	}
+
+	speech := 0
+	for _, seg := range segments {
+		speech += len([]rune(strings.TrimSpace(soundTagRegex.ReplaceAllString(seg.Text, ""))))
+	}
+	if speech < 20 {
+		return "no speech"
+	}
+
+	if media.Duration > 0 {
+		span := segments[len(segments)-1].End - segments[0].Start
+		if coverage := float64(span) / float64(media.Duration); coverage < minSubtitleCoverage {
+			return fmt.Sprintf("covers only %.0f%% of the media", coverage*100)
+		}
+	}
+	return ""
+}
@@ -1,7 +1,14 @@
 package service
 
 import (
+	"Varys/backend/analyzer"
+	"Varys/backend/config"
+	"Varys/backend/feed"
+	"Varys/backend/storage"
+	"Varys/backend/ytdlp"
 	"context"
+	"fmt"
+	"time"
 )
 
 // EventLogger defines the interface for reporting progress and logs from the core service.
@@ -16,15 +23,95 @@ type EventLogger interface {
 type Options struct {
 	AudioOnly      bool
 	ModelPath      string
+	SubtitleSource string // "manual", "auto" or "whisper" (see downloader.Subtitles*)
+	Cookies        ytdlp.Cookies
 	LLMModel       string
 	TranslationMod string
-	AIProvider     string
-	OpenAIKey      string
-	OpenAIModel    string
+	// TranslationWorkers and TranslationRateLimit (requests/minute) tune batch translation
+	TranslationWorkers   int
+	TranslationRateLimit int
+	GlossaryPath         string // CSV/YAML glossary applied to translation batches
+	AIProvider           string
+	OpenAIKey            string
+	OpenAIModel          string
+	// OpenAI-compatible servers (AIProvider "openai-compatible" or "llamacpp")
+	CompatibleBaseURL string
+	CompatibleKey     string
+	CompatibleModel   string
+	CompatibleHeaders map[string]string
+	// Ollama connection settings (host, timeout, keep-alive, proxy auth)
+	Ollama analyzer.OllamaConfig
+	// Fallbacks are tried in order after the primary provider (see analyzer.FallbackProvider)
+	Fallbacks      []config.ProviderRef
 	TargetLanguage string
 	ContextSize    int
 	CustomPrompt   string
+	AnalysisMode   string // "auto", "single" or "chunked"
 	VaultPath      string
+	Playlist       string // Index note linked from the note, set by ProcessPlaylist
+	// WorkDir keeps stage checkpoints per URL; empty uses a temp dir without resume.
+	// FromStage forces that stage and all later ones to rerun (see Stages).
+	WorkDir   string
+	FromStage string
+	// OnDuplicate decides what happens to sources already in the vault (see storage.Duplicate*)
+	OnDuplicate string
+	// NoteTemplate is a built-in template name or a template file (see storage.LoadTemplate)
+	NoteTemplate string
+	// FeedItem is the feed entry the URL comes from, set by SyncFeed. Episodes
+	// take their metadata from it, and article links skip the media probe.
+	FeedItem *feed.Item
+}
+
+// OptionsFromConfig maps the persisted configuration onto task options.
+// Callers override per-task values (AudioOnly, CLI flags) afterwards.
+func OptionsFromConfig(cfg *config.Config) Options {
+	opts := Options{
+		AudioOnly:            true,
+		ModelPath:            cfg.ModelPath,
+		SubtitleSource:       cfg.SubtitleSource,
+		Cookies:              cfg.YtDlpCookies(),
+		LLMModel:             cfg.LLMModel,
+		TranslationMod:       cfg.TranslationModel,
+		TranslationWorkers:   cfg.TranslationWorkers,
+		TranslationRateLimit: cfg.TranslationRateLimit,
+		GlossaryPath:         cfg.GlossaryPath,
+		AIProvider:           cfg.AIProvider,
+		OpenAIKey:            cfg.OpenAIKey,
+		OpenAIModel:          cfg.OpenAIModel,
+		CompatibleBaseURL:    cfg.CompatibleBaseURL,
+		CompatibleKey:        cfg.CompatibleKey,
+		CompatibleModel:      cfg.CompatibleModel,
+		CompatibleHeaders:    cfg.CompatibleHeaders,
+		Ollama: analyzer.OllamaConfig{
+			Host:      cfg.OllamaHost,
+			Timeout:   time.Duration(cfg.OllamaTimeout) * time.Second,
+			KeepAlive: cfg.OllamaKeepAlive,
+			Headers:   analyzer.OllamaAuthHeaders(cfg.OllamaUsername, cfg.OllamaPassword, cfg.OllamaToken),
+		},
+		Fallbacks:      cfg.FallbackProviders,
+		TargetLanguage: cfg.TargetLanguage,
+		ContextSize:    cfg.ContextSize,
+		CustomPrompt:   cfg.CustomPrompt,
+		AnalysisMode:   cfg.AnalysisMode,
+		VaultPath:      cfg.VaultPath,
+		OnDuplicate:    cfg.DuplicateMode,
+		NoteTemplate:   cfg.NoteTemplate,
+	}
+	if opts.ContextSize == 0 {
+		opts.ContextSize = 8192
+	}
+	opts.WorkDir, _ = config.GetWorkDir()
+	return opts
+}
+
+// LoadNoteTemplate loads and validates the configured note template.
+func (o Options) LoadNoteTemplate() (*storage.NoteTemplate, error) {
+	configDir, _ := config.GetConfigDir()
+	tmpl, err := storage.LoadTemplate(o.NoteTemplate, o.vaultPath(), configDir)
+	if err != nil {
+		return nil, fmt.Errorf("note template: %w", err)
+	}
+	return tmpl, nil
 }
 
 // TaskResult contains the output of a successful processing task.
@@ -32,6 +119,7 @@ type TaskResult struct {
 	NotePath  string
 	MediaFile string
 	Title     string
+	Skipped   bool // the source was ingested before and OnDuplicate is "skip"
 }
 
 // Processor defines the core logic for the Varys pipeline.
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"Varys/backend/analyzer"
+	"Varys/backend/config"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOptionsFromConfig_FallbackChain(t *testing.T) {
+	var gotModel string
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		gotModel, _ = req["model"].(string)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"from backup\"}}]}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer backup.Close()
+	down := httptest.NewServer(nil)
+	down.Close()
+
+	opts := OptionsFromConfig(&config.Config{
+		AIProvider:        "ollama",
+		LLMModel:          "qwen3:8b",
+		OllamaHost:        down.URL,
+		CompatibleBaseURL: backup.URL + "/v1",
+		FallbackProviders: []config.ProviderRef{{Provider: "openai-compatible", Model: "local-model"}},
+	})
+	if len(opts.Fallbacks) != 1 {
+		t.Fatalf("Expected the configured fallback in the options, got %v", opts.Fallbacks)
+	}
+
+	provider := opts.withFallbacks(analyzer.NewProvider(opts.ProviderConfig(opts.AnalysisModel())), discardLogger{})
+	if _, ok := provider.(*analyzer.FallbackProvider); !ok {
+		t.Fatalf("Expected a FallbackProvider, got %T", provider)
+	}
+	resp, err := provider.Chat(context.Background(), "hi", nil, nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp != "from backup" || gotModel != "local-model" {
+		t.Errorf("Expected the fallback to answer with local-model, got %q from %q", resp, gotModel)
+	}
+}
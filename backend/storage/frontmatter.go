@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"Varys/backend/document"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FrontmatterSchema is written as "schema" so tools and later versions can tell
+// which properties a note has. Bump it when properties are renamed or removed.
+const FrontmatterSchema = 2
+
+// Frontmatter holds the YAML properties at the top of a note. It is marshalled
+// with a YAML library, so quotes, colons or newlines in values stay valid YAML.
+type Frontmatter struct {
+	Created        string   `yaml:"created"`
+	Source         string   `yaml:"source"`
+	Type           string   `yaml:"type"`
+	Language       string   `yaml:"language,omitempty"`
+	AIProvider     string   `yaml:"ai_provider,omitempty"`
+	AIModel        string   `yaml:"ai_model,omitempty"`
+	Uploader       string   `yaml:"uploader,omitempty"`
+	Published      string   `yaml:"published,omitempty"` // YYYY-MM-DD
+	Duration       string   `yaml:"duration,omitempty"`  // HH:MM:SS
+	Pages          int      `yaml:"pages,omitempty"`     // documents
+	Chapters       int      `yaml:"chapters,omitempty"`  // EPUBs
+	ViewCount      int64    `yaml:"view_count,omitempty"`
+	Thumbnail      string   `yaml:"thumbnail,omitempty"`
+	Keywords       []string `yaml:"keywords,omitempty"` // platform tags
+	Playlist       string   `yaml:"playlist,omitempty"` // wikilink to the index note
+	WordCount      int      `yaml:"word_count"`
+	ProcessingTime string   `yaml:"processing_time,omitempty"` // e.g. "3m12s"
+	Schema         int      `yaml:"schema"`
+	Tags           []string `yaml:"tags"`
+}
+
+// FrontmatterData returns the note's properties.
+func (d NoteData) FrontmatterData() Frontmatter {
+	fm := Frontmatter{
+		Created:    d.CreatedTime,
+		Source:     d.URL,
+		Type:       "auto_clipper",
+		Language:   d.Language,
+		AIProvider: d.AIProvider,
+		AIModel:    d.AIModel,
+		Uploader:   d.Author,
+		WordCount:  WordCount(d.OriginalText),
+		Schema:     FrontmatterSchema,
+		Tags:       d.Tags,
+	}
+	if fm.Created == "" && !d.Created.IsZero() {
+		fm.Created = d.Created.Format("2006-01-02 15:04")
+	}
+	if !d.Published.IsZero() {
+		fm.Published = d.Published.Format("2006-01-02")
+	}
+	if m := d.Media; m != nil {
+		if fm.Uploader == "" {
+			fm.Uploader = m.Uploader
+		}
+		if fm.Published == "" && !m.UploadDate.IsZero() {
+			fm.Published = m.UploadDate.Format("2006-01-02")
+		}
+		fm.ViewCount = m.ViewCount
+		fm.Thumbnail = m.Thumbnail
+		fm.Keywords = m.Tags
+	}
+	if doc := d.Document; doc != nil {
+		fm.Pages = len(doc.Pages)
+		if doc.Format == document.FormatEPUB {
+			fm.Chapters = len(doc.Chapters)
+		}
+		fm.Keywords = doc.Keywords
+	}
+	if duration := d.Duration(); duration > 0 {
+		fm.Duration = FormatTimestamp(duration)
+	}
+	if d.Playlist != "" {
+		fm.Playlist = "[[" + d.Playlist + "]]"
+	}
+	if d.ProcessingTime > 0 {
+		fm.ProcessingTime = d.ProcessingTime.Round(time.Second).String()
+	}
+	if fm.Tags == nil {
+		fm.Tags = []string{}
+	}
+	return fm
+}
+
+// Frontmatter returns the note's properties as YAML, without the "---" delimiters.
+// Templates use it as {{.Frontmatter}}.
+func (d NoteData) Frontmatter() (string, error) {
+	return MarshalFrontmatter(d.FrontmatterData())
+}
+
+// MarshalFrontmatter renders fm as YAML, ending in a newline.
+func MarshalFrontmatter(fm Frontmatter) (string, error) {
+	return marshalYAML(fm)
+}
+
+func marshalYAML(v interface{}) (string, error) {
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ParseFrontmatter reads the properties of a note written by Varys.
+func ParseFrontmatter(note string) (Frontmatter, error) {
+	var fm Frontmatter
+	front, _ := splitFrontmatter(note)
+	err := yaml.Unmarshal([]byte(front), &fm)
+	return fm, err
+}
+
+// Duration returns the media length, or the end of the transcript when the
+// platform did not report one (local files).
+func (d NoteData) Duration() time.Duration {
+	if d.Media != nil && d.Media.Duration > 0 {
+		return d.Media.Duration
+	}
+	if n := len(d.Segments); n > 0 {
+		return d.Segments[n-1].End
+	}
+	return 0
+}
+
+// WordCount counts words in text. Chinese, Japanese and Korean characters are
+// counted one by one, as these scripts do not separate words with spaces.
+func WordCount(text string) int {
+	count := 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			count++
+			inWord = false
+		case unicode.IsSpace(r) || unicode.IsPunct(r):
+			inWord = false
+		case !inWord:
+			count++
+			inWord = true
+		}
+	}
+	return count
+}
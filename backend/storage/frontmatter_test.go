@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"Varys/backend/downloader"
+	"Varys/backend/transcriber"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Values that broke the old string-templated frontmatter.
+var trickyStrings = []string{
+	`He said "hi"`,
+	`key: value`,
+	`# not a comment`,
+	`- not a list`,
+	`[[not a link]]`,
+	`{not: a map}`,
+	`'single' quotes`,
+	"line one\nline two",
+	`C:\path\to\file`,
+	`yes`,
+	`null`,
+	`2024-01-05`,
+	`@mention`,
+	`中文：标题`,
+	`trailing space `,
+}
+
+func TestFrontmatter_RoundTrip(t *testing.T) {
+	for _, s := range trickyStrings {
+		fm := Frontmatter{
+			Created:        "2024-01-05 10:00",
+			Source:         "https://example.com/watch?v=1&t=" + s,
+			Type:           "auto_clipper",
+			Language:       s,
+			AIProvider:     "ollama",
+			AIModel:        "qwen3:8b",
+			Uploader:       s,
+			Published:      "2024-01-05",
+			Duration:       "01:02:03",
+			ViewCount:      42,
+			Thumbnail:      s,
+			Keywords:       []string{s, "go"},
+			Playlist:       "[[" + s + "]]",
+			WordCount:      7,
+			ProcessingTime: "1m2s",
+			Schema:         FrontmatterSchema,
+			Tags:           []string{s, "tag:with:colons"},
+		}
+		out, err := MarshalFrontmatter(fm)
+		if err != nil {
+			t.Fatalf("marshal %q: %v", s, err)
+		}
+		got, err := ParseFrontmatter("---\n" + out + "---\n\n# Note\n")
+		if err != nil {
+			t.Fatalf("value %q produced invalid YAML: %v\n%s", s, err, out)
+		}
+		if !reflect.DeepEqual(got, fm) {
+			t.Errorf("value %q did not round-trip:\n got %+v\nwant %+v\n%s", s, got, fm, out)
+		}
+	}
+}
+
+func TestFrontmatter_RenderedNotesAreValidYAML(t *testing.T) {
+	for _, name := range BuiltinTemplates() {
+		tmpl, err := LoadTemplate(name, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range trickyStrings {
+			data := sampleVideoNote()
+			data.URL = "https://example.com/?q=" + s
+			data.Tags = []string{s}
+			data.Media.Uploader = s
+			data.Playlist = s
+
+			out, err := tmpl.Render(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			front, _ := splitFrontmatter(out)
+			var generic map[string]interface{}
+			if err := yaml.Unmarshal([]byte(front), &generic); err != nil {
+				t.Fatalf("template %s, value %q: invalid YAML: %v\n%s", name, s, err, front)
+			}
+			fm, _ := ParseFrontmatter(out)
+			if fm.Source != data.URL || fm.Uploader != s || len(fm.Tags) != 1 || fm.Tags[0] != s {
+				t.Errorf("template %s, value %q: got %+v", name, s, fm)
+			}
+		}
+	}
+}
+
+func TestFrontmatterData(t *testing.T) {
+	data := NoteData{
+		URL:            "https://example.com/v",
+		Created:        time.Date(2024, 2, 3, 4, 5, 0, 0, time.UTC),
+		OriginalText:   "Hello world, 你好",
+		ProcessingTime: 95*time.Second + 400*time.Millisecond,
+		Playlist:       "Course",
+		Segments:       []transcriber.Segment{{Start: 0, End: 61 * time.Second, Text: "x"}},
+	}
+	fm := data.FrontmatterData()
+	if fm.Created != "2024-02-03 04:05" {
+		t.Errorf("Created = %q", fm.Created)
+	}
+	if fm.WordCount != 4 {
+		t.Errorf("WordCount = %d, want 4", fm.WordCount)
+	}
+	if fm.ProcessingTime != "1m35s" {
+		t.Errorf("ProcessingTime = %q", fm.ProcessingTime)
+	}
+	if fm.Duration != "00:01:01" {
+		t.Errorf("Duration from segments = %q", fm.Duration)
+	}
+	if fm.Playlist != "[[Course]]" || fm.Schema != FrontmatterSchema || fm.Tags == nil {
+		t.Errorf("unexpected frontmatter: %+v", fm)
+	}
+
+	// Platform metadata wins for videos, article fields for articles
+	data.Media = &downloader.MediaInfo{Uploader: "Channel", Duration: time.Hour, UploadDate: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)}
+	fm = data.FrontmatterData()
+	if fm.Uploader != "Channel" || fm.Duration != "01:00:00" || fm.Published != "2023-01-02" {
+		t.Errorf("video frontmatter: %+v", fm)
+	}
+	article := NoteData{Author: "Jane", Published: time.Date(2022, 5, 6, 0, 0, 0, 0, time.UTC)}
+	if fm := article.FrontmatterData(); fm.Uploader != "Jane" || fm.Published != "2022-05-06" {
+		t.Errorf("article frontmatter: %+v", fm)
+	}
+	if fm := sampleDocumentNote().FrontmatterData(); fm.Pages != 1 || len(fm.Keywords) != 1 || fm.Duration != "" {
+		t.Errorf("document frontmatter: %+v", fm)
+	}
+}
+
+func TestWordCount(t *testing.T) {
+	tests := map[string]int{
+		"":                       0,
+		"one two  three":         3,
+		"don't stop-me now.":     5,
+		"你好世界":                   4,
+		"Go 语言":                  3,
+		"line\nbreaks\tand tabs": 4,
+	}
+	for text, want := range tests {
+		if got := WordCount(text); got != want {
+			t.Errorf("WordCount(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"Varys/backend/downloader"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// What to do when a source that is already in the vault is submitted again.
+const (
+	DuplicateSkip    = "skip"    // keep the existing note, do nothing
+	DuplicateUpdate  = "update"  // regenerate the generated sections, keep the user's own sections
+	DuplicateVersion = "version" // write a new copy next to the existing note (Title_v2.md)
+)
+
+// ParseDuplicateMode validates a duplicate mode. An empty string means DuplicateUpdate.
+func ParseDuplicateMode(mode string) (string, error) {
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case "":
+		return DuplicateUpdate, nil
+	case DuplicateSkip, DuplicateUpdate, DuplicateVersion:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid duplicate mode %q (use skip, update or version)", mode)
+}
+
+// LedgerEntry records one ingested source and the note generated from it.
+type LedgerEntry struct {
+	Key        string    `json:"key"`
+	Source     string    `json:"source"`
+	Note       string    `json:"note"`                // note file name without .md, relative to the vault
+	Media      string    `json:"media,omitempty"`     // file name in the assets folder
+	Versions   []string  `json:"versions,omitempty"`  // versioned copies (DuplicateVersion)
+	Generated  []string  `json:"generated,omitempty"` // frontmatter keys and headings written by Varys
+	NoteHash   string    `json:"note_hash"`           // hash of the note as written, to detect user edits
+	IngestedAt time.Time `json:"ingested_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Ledger is the list of ingested sources of a vault, stored in .varys/ledger.json.
+type Ledger struct {
+	path    string
+	entries map[string]LedgerEntry
+}
+
+// ledgerMu serializes ledger writes of concurrent tasks (e.g. the desktop job queue).
+var ledgerMu sync.Mutex
+
+// OpenLedger reads the ledger of the vault. A missing file yields an empty ledger.
+func (m *Manager) OpenLedger() (*Ledger, error) {
+	l := &Ledger{path: filepath.Join(m.VaultPath, ".varys", "ledger.json")}
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+	if err := l.read(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Ledger) read() error {
+	l.entries = map[string]LedgerEntry{}
+	data, err := os.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read ledger: %w", err)
+	}
+	var list []LedgerEntry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("failed to parse ledger %s: %w", l.path, err)
+	}
+	for _, e := range list {
+		l.entries[e.Key] = e
+	}
+	return nil
+}
+
+// Lookup returns the entry recorded for key.
+func (l *Ledger) Lookup(key string) (LedgerEntry, bool) {
+	e, ok := l.entries[key]
+	return e, ok
+}
+
+// Record stores e and writes the ledger. Entries written by other tasks in the
+// meantime are kept.
+func (l *Ledger) Record(e LedgerEntry) error {
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+	if err := l.read(); err != nil {
+		return err
+	}
+	return l.recordLocked(e)
+}
+
+// Update reads the current entry for key and passes it to fn, which typically
+// writes the note, and records the entry fn returns (nil records nothing). No
+// other task can look up or record an entry in between, so concurrent tasks for
+// the same source see each other's notes.
+func (l *Ledger) Update(key string, fn func(prev LedgerEntry, seen bool) (*LedgerEntry, error)) error {
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+	if err := l.read(); err != nil {
+		return err
+	}
+	prev, seen := l.entries[key]
+	e, err := fn(prev, seen)
+	if err != nil || e == nil {
+		return err
+	}
+	return l.recordLocked(*e)
+}
+
+func (l *Ledger) recordLocked(e LedgerEntry) error {
+	now := time.Now()
+	if prev, ok := l.entries[e.Key]; ok {
+		e.IngestedAt = prev.IngestedAt
+	} else {
+		e.IngestedAt = now
+	}
+	e.UpdatedAt = now
+	l.entries[e.Key] = e
+
+	list := make([]LedgerEntry, 0, len(l.entries))
+	for _, entry := range l.entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// SourceKey identifies a source independent of how its URL was written: the
+// platform video ID when known, otherwise the normalized URL.
+func SourceKey(rawURL string, media *downloader.MediaInfo) string {
+	if media != nil && media.ID != "" && media.Extractor != "" {
+		return strings.ToLower(media.Extractor) + ":" + media.ID
+	}
+	return "url:" + NormalizeURL(rawURL)
+}
+
+// FileKey identifies a local file by the hash of its content, so renamed or
+// moved copies are recognized.
+func FileKey(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// trackingParams are query parameters that do not change the content of a page.
+var trackingParams = map[string]bool{
+	"si": true, "feature": true, "fbclid": true, "gclid": true, "spm_id_from": true, "vd_source": true,
+}
+
+// NormalizeURL lowercases scheme and host, drops "www.", fragments, trailing
+// slashes and tracking parameters, and sorts the remaining query.
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(rawURL)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	query := u.Query()
+	for name := range query {
+		if trackingParams[name] || strings.HasPrefix(name, "utm_") {
+			query.Del(name)
+		}
+	}
+	u.RawQuery = query.Encode() // Encode sorts by key
+	return u.String()
+}
+
+// HashNote returns the content hash stored in LedgerEntry.NoteHash.
+func HashNote(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// NotePath returns the path of the note with the given file name (without .md).
+func (m *Manager) NotePath(name string) string {
+	return filepath.Join(m.VaultPath, name+".md")
+}
+
+// UniqueName returns base, or base_2, base_3, ... if a note or asset of that name
+// already exists, so notes with the same title from different sources do not collide.
+func (m *Manager) UniqueName(base string) string {
+	if !m.nameTaken(base) {
+		return base
+	}
+	return m.nextFree(base + "_")
+}
+
+// VersionName returns the first free base_v2, base_v3, ... name for a versioned copy.
+func (m *Manager) VersionName(base string) string {
+	return m.nextFree(base + "_v")
+}
+
+func (m *Manager) nextFree(prefix string) string {
+	for i := 2; ; i++ {
+		if name := fmt.Sprintf("%s%d", prefix, i); !m.nameTaken(name) {
+			return name
+		}
+	}
+}
+
+func (m *Manager) nameTaken(name string) bool {
+	if _, err := os.Stat(m.NotePath(name)); err == nil {
+		return true
+	}
+	// Names come from SanitizeFilename, which strips glob metacharacters
+	assets, _ := filepath.Glob(filepath.Join(m.VaultPath, "assets", name+".*"))
+	return len(assets) > 0
+}
+
+// NoteSource returns the source URL recorded in the frontmatter of an existing
+// note, so notes written before the ledger existed are recognized.
+func NoteSource(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	front, _ := splitFrontmatter(string(data))
+	for _, line := range strings.Split(front, "\n") {
+		if v, ok := strings.CutPrefix(line, "source:"); ok {
+			return strings.Trim(strings.TrimSpace(v), `"'`)
+		}
+	}
+	return ""
+}
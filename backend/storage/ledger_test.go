@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"Varys/backend/downloader"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"https://www.youtube.com/watch?v=abc&si=xyz", "http://youtube.com/watch?v=abc"},
+		{"https://example.com/post/?utm_source=feed#comments", "https://Example.com/post"},
+		{"https://example.com/a?b=2&a=1", "https://example.com/a?a=1&b=2"},
+	}
+	for _, tt := range tests {
+		if NormalizeURL(tt.a) != NormalizeURL(tt.b) {
+			t.Errorf("NormalizeURL(%q) = %q, NormalizeURL(%q) = %q; want equal", tt.a, NormalizeURL(tt.a), tt.b, NormalizeURL(tt.b))
+		}
+	}
+	if NormalizeURL("https://example.com/a?id=1") == NormalizeURL("https://example.com/a?id=2") {
+		t.Error("different query values must not normalize to the same URL")
+	}
+}
+
+func TestSourceKey(t *testing.T) {
+	media := &downloader.MediaInfo{ID: "abc", Extractor: "Youtube"}
+	if got := SourceKey("https://youtu.be/abc", media); got != "youtube:abc" {
+		t.Errorf("SourceKey with media = %q", got)
+	}
+	if SourceKey("https://youtu.be/abc", media) != SourceKey("https://www.youtube.com/watch?v=abc", media) {
+		t.Error("different URLs of the same video should share a key")
+	}
+	if got := SourceKey("https://example.com/post/", nil); got != "url:https://example.com/post" {
+		t.Errorf("SourceKey without media = %q", got)
+	}
+}
+
+func TestFileKey(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.mp3")
+	b := filepath.Join(dir, "renamed.mp3")
+	os.WriteFile(a, []byte("same audio"), 0644)
+	os.WriteFile(b, []byte("same audio"), 0644)
+
+	ka, err := FileKey(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, _ := FileKey(b)
+	if ka != kb {
+		t.Error("identical content should give the same key")
+	}
+}
+
+func TestLedger_RecordAndReload(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	l, err := mgr.OpenLedger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.Lookup("youtube:abc"); ok {
+		t.Fatal("empty ledger returned an entry")
+	}
+	if err := l.Record(LedgerEntry{Key: "youtube:abc", Note: "Talk", NoteHash: "h1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second ledger (another task) adds its entry without dropping the first
+	other, _ := mgr.OpenLedger()
+	other.Record(LedgerEntry{Key: "url:https://example.com", Note: "Post"})
+	l.Record(LedgerEntry{Key: "youtube:abc", Note: "Talk", NoteHash: "h2"})
+
+	reloaded, _ := mgr.OpenLedger()
+	e, ok := reloaded.Lookup("youtube:abc")
+	if !ok || e.NoteHash != "h2" || e.IngestedAt.IsZero() || e.UpdatedAt.Before(e.IngestedAt) {
+		t.Errorf("entry after update = %+v", e)
+	}
+	if _, ok := reloaded.Lookup("url:https://example.com"); !ok {
+		t.Error("entry recorded by another task was lost")
+	}
+}
+
+func TestUniqueAndVersionName(t *testing.T) {
+	vault := t.TempDir()
+	mgr := NewManager(vault)
+
+	if got := mgr.UniqueName("Talk"); got != "Talk" {
+		t.Errorf("UniqueName on empty vault = %q", got)
+	}
+	os.WriteFile(mgr.NotePath("Talk"), []byte("note"), 0644)
+	if got := mgr.UniqueName("Talk"); got != "Talk_2" {
+		t.Errorf("UniqueName with existing note = %q", got)
+	}
+	// Leftover media also blocks a name
+	os.MkdirAll(filepath.Join(vault, "assets"), 0755)
+	os.WriteFile(filepath.Join(vault, "assets", "Talk_2.m4a"), []byte("audio"), 0644)
+	if got := mgr.UniqueName("Talk"); got != "Talk_3" {
+		t.Errorf("UniqueName with existing asset = %q", got)
+	}
+
+	if got := mgr.VersionName("Talk"); got != "Talk_v2" {
+		t.Errorf("VersionName = %q", got)
+	}
+	os.WriteFile(mgr.NotePath("Talk_v2"), []byte("note"), 0644)
+	if got := mgr.VersionName("Talk"); got != "Talk_v3" {
+		t.Errorf("VersionName with existing version = %q", got)
+	}
+}
+
+func TestNoteSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.md")
+	os.WriteFile(path, []byte("---\ncreated: 2024-01-01\nsource: \"https://example.com/v\"\n---\n\n# Note\n"), 0644)
+	if got := NoteSource(path); got != "https://example.com/v" {
+		t.Errorf("NoteSource = %q", got)
+	}
+	if got := NoteSource(filepath.Join(t.TempDir(), "missing.md")); got != "" {
+		t.Errorf("NoteSource of missing note = %q", got)
+	}
+}
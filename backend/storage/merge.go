@@ -0,0 +1,158 @@
+package storage
+
+import "strings"
+
+// MergeNote updates an existing note with a newly generated one without losing the
+// user's additions. Sections ("## " headings) that the generator writes are replaced
+// by their new version; sections the user added are kept in place. Frontmatter keys
+// are taken from the generated note, and keys the user added are appended.
+// previous lists what the last run generated (see GeneratedParts), so sections it
+// no longer writes are dropped instead of being mistaken for user content.
+func MergeNote(existing, generated string, previous []string) string {
+	oldFront, oldBody := splitFrontmatter(existing)
+	newFront, newBody := splitFrontmatter(generated)
+
+	var b strings.Builder
+	if newFront != "" || oldFront != "" {
+		b.WriteString("---\n")
+		b.WriteString(mergeFrontmatter(oldFront, newFront, previous))
+		b.WriteString("---\n")
+	}
+
+	// The title block before the first section is generated, so only the
+	// sections of the existing note matter
+	_, oldSections := splitSections(oldBody)
+	newPre, newSections := splitSections(newBody)
+
+	// Generated sections by heading, in order (article text may repeat headings)
+	byHeading := map[string][]int{}
+	for i, s := range newSections {
+		byHeading[s.heading] = append(byHeading[s.heading], i)
+	}
+
+	wasGenerated := map[string]bool{}
+	for _, part := range previous {
+		wasGenerated[part] = true
+	}
+
+	b.WriteString(newPre)
+	used := make([]bool, len(newSections))
+	for _, s := range oldSections {
+		if queue := byHeading[s.heading]; len(queue) > 0 {
+			b.WriteString(newSections[queue[0]].text)
+			used[queue[0]] = true
+			byHeading[s.heading] = queue[1:]
+			continue
+		}
+		if _, generated := byHeading[s.heading]; generated || wasGenerated[s.heading] {
+			continue // an old generated section the new note no longer has
+		}
+		b.WriteString(s.text)
+	}
+	// Sections the previous version did not have (e.g. translation now available)
+	for i, s := range newSections {
+		if !used[i] {
+			b.WriteString(s.text)
+		}
+	}
+	return b.String()
+}
+
+// GeneratedParts lists the frontmatter keys and section headings of a generated
+// note, for LedgerEntry.Generated.
+func GeneratedParts(note string) []string {
+	front, body := splitFrontmatter(note)
+	var parts []string
+	for _, block := range frontmatterBlocks(front) {
+		parts = append(parts, block.key)
+	}
+	_, sections := splitSections(body)
+	for _, s := range sections {
+		parts = append(parts, s.heading)
+	}
+	return parts
+}
+
+// splitFrontmatter splits a note into the YAML frontmatter (without the "---"
+// delimiters) and the body. Notes without frontmatter return an empty string.
+func splitFrontmatter(note string) (front, body string) {
+	if !strings.HasPrefix(note, "---\n") {
+		return "", note
+	}
+	rest := note[len("---\n"):]
+	end := strings.Index(rest, "\n---\n")
+	if end < 0 {
+		return "", note
+	}
+	return rest[:end+1], rest[end+len("\n---\n"):]
+}
+
+// mergeFrontmatter returns newFront plus the top-level keys of oldFront that the
+// generator does not write (e.g. properties added by the user in Obsidian).
+func mergeFrontmatter(oldFront, newFront string, previous []string) string {
+	newKeys := map[string]bool{}
+	for _, key := range previous {
+		newKeys[key] = true
+	}
+	for _, block := range frontmatterBlocks(newFront) {
+		newKeys[block.key] = true
+	}
+
+	var b strings.Builder
+	b.WriteString(newFront)
+	for _, block := range frontmatterBlocks(oldFront) {
+		if block.key != "" && !newKeys[block.key] {
+			b.WriteString(block.text)
+		}
+	}
+	return b.String()
+}
+
+type frontmatterBlock struct {
+	key  string
+	text string // the key line plus indented continuation lines (lists, maps)
+}
+
+func frontmatterBlocks(front string) []frontmatterBlock {
+	var blocks []frontmatterBlock
+	for _, line := range strings.SplitAfter(front, "\n") {
+		if line == "" {
+			continue
+		}
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "-")
+		if indented && len(blocks) > 0 {
+			blocks[len(blocks)-1].text += line
+			continue
+		}
+		key, _, _ := strings.Cut(line, ":")
+		blocks = append(blocks, frontmatterBlock{key: strings.TrimSpace(key), text: line})
+	}
+	return blocks
+}
+
+type noteSection struct {
+	heading string // the "## " line, trimmed
+	text    string // heading line and content up to the next section
+}
+
+// splitSections splits a note body at level-2 headings outside code fences.
+func splitSections(body string) (preamble string, sections []noteSection) {
+	var pre strings.Builder
+	inFence := false
+	for _, line := range strings.SplitAfter(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(line, "## ") {
+			sections = append(sections, noteSection{heading: trimmed, text: line})
+			continue
+		}
+		if len(sections) > 0 {
+			sections[len(sections)-1].text += line
+		} else {
+			pre.WriteString(line)
+		}
+	}
+	return pre.String(), sections
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+const oldNote = `---
+created: 2024-01-01 10:00
+source: "https://example.com/v"
+tags:
+  - old
+rating: 5
+aliases:
+  - My Talk
+---
+
+# Talk
+
+## 智能摘要
+
+Old summary.
+
+## My Notes
+
+Keep this.
+
+## 原始内容
+
+Old transcript.
+`
+
+const newNote = `---
+created: 2024-02-01 10:00
+source: "https://example.com/v"
+tags:
+  - new
+---
+
+# Talk
+
+## 智能摘要
+
+New summary.
+
+## 对照翻译
+
+Translated.
+`
+
+func TestMergeNote(t *testing.T) {
+	// What the last run wrote; rating, aliases and My Notes were added by the user
+	previous := []string{"created", "source", "tags", "## 智能摘要", "## 原始内容"}
+	merged := MergeNote(oldNote, newNote, previous)
+
+	for _, want := range []string{
+		"created: 2024-02-01 10:00",
+		"  - new",
+		"rating: 5",
+		"aliases:\n  - My Talk",
+		"New summary.",
+		"## My Notes\n\nKeep this.",
+		"## 对照翻译\n\nTranslated.",
+	} {
+		if !strings.Contains(merged, want) {
+			t.Errorf("merged note missing %q:\n%s", want, merged)
+		}
+	}
+	for _, gone := range []string{"Old summary.", "  - old", "Old transcript.", "created: 2024-01-01"} {
+		if strings.Contains(merged, gone) {
+			t.Errorf("merged note still contains %q:\n%s", gone, merged)
+		}
+	}
+
+	// User sections stay where the user put them
+	if strings.Index(merged, "## My Notes") > strings.Index(merged, "## 对照翻译") {
+		t.Errorf("user section moved:\n%s", merged)
+	}
+}
+
+func TestMergeNote_WithoutHistoryKeepsUnknownSections(t *testing.T) {
+	// Notes written before the ledger existed: unknown sections are kept
+	merged := MergeNote(oldNote, newNote, nil)
+	if !strings.Contains(merged, "Old transcript.") || !strings.Contains(merged, "Keep this.") {
+		t.Errorf("sections not in the new note should be kept:\n%s", merged)
+	}
+	if strings.Contains(merged, "Old summary.") {
+		t.Errorf("generated section not replaced:\n%s", merged)
+	}
+}
+
+func TestMergeNote_IgnoresHeadingsInCodeFences(t *testing.T) {
+	existing := "# T\n\n## 智能摘要\n\nOld.\n\n```\n## not a heading\n```\n"
+	merged := MergeNote(existing, "# T\n\n## 智能摘要\n\nNew.\n", nil)
+	if strings.Contains(merged, "not a heading") || !strings.Contains(merged, "New.") {
+		t.Errorf("code fence treated as a section:\n%s", merged)
+	}
+}
+
+func TestGeneratedParts(t *testing.T) {
+	got := strings.Join(GeneratedParts(newNote), "|")
+	if got != "created|source|tags|## 智能摘要|## 对照翻译" {
+		t.Errorf("GeneratedParts = %q", got)
+	}
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"strings"
+	"text/template"
+)
+
+// IndexData holds the data for a playlist or channel index note.
+type IndexData struct {
+	Title       string
+	URL         string
+	Uploader    string
+	CreatedTime string
+	Items       []IndexItem
+}
+
+// IndexItem is one playlist entry in the index note.
+type IndexItem struct {
+	Title string
+	URL   string
+	Note  string // Title of the generated note, empty if processing failed
+	Error string
+}
+
+// indexFrontmatter holds the properties of an index note.
+type indexFrontmatter struct {
+	Created  string `yaml:"created"`
+	Source   string `yaml:"source"`
+	Type     string `yaml:"type"`
+	Uploader string `yaml:"uploader,omitempty"`
+	Items    int    `yaml:"items"`
+	Schema   int    `yaml:"schema"`
+}
+
+// Frontmatter returns the index note's properties as YAML.
+func (d IndexData) Frontmatter() (string, error) {
+	return marshalYAML(indexFrontmatter{
+		Created:  d.CreatedTime,
+		Source:   d.URL,
+		Type:     "playlist",
+		Uploader: d.Uploader,
+		Items:    len(d.Items),
+		Schema:   FrontmatterSchema,
+	})
+}
+
+// IndexNoteTitle returns the note title used for a playlist index, so item notes
+// can link to it before it is written.
+func (m *Manager) IndexNoteTitle(playlistTitle string) string {
+	return m.SanitizeFilename(playlistTitle)
+}
+
+// RenderIndexNote renders the playlist index note linking to every processed
+// item. Entries without a note or error are listed as pending.
+func (m *Manager) RenderIndexNote(data IndexData) (string, error) {
+	tmplStr := `---
+{{.Frontmatter}}---
+
+# {{.Title}}
+
+{{- range $i, $item := .Items}}
+{{inc $i}}. {{if $item.Note}}[[{{$item.Note}}]]{{else if $item.Error}}{{$item.Title}} ❌ {{$item.Error}}{{else}}{{$item.Title}} ⏳{{end}} ([source]({{$item.URL}}))
+{{- end}}
+`
+
+	funcMap := template.FuncMap{
+		"inc": func(i int) int {
+			return i + 1
+		},
+	}
+
+	tt, err := template.New("index").Funcs(funcMap).Parse(tmplStr)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tt.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRenderIndexNote(t *testing.T) {
+	vaultDir := t.TempDir()
+	mgr := NewManager(vaultDir)
+
+	content, err := mgr.RenderIndexNote(IndexData{
+		Title:    "Go Course: Basics",
+		URL:      "https://www.youtube.com/playlist?list=PL1",
+		Uploader: "Teacher",
+		Items: []IndexItem{
+			{Title: "Lesson 1", URL: "https://example.com/1", Note: "Lesson_1"},
+			{Title: "Lesson 2", URL: "https://example.com/2", Error: "download failed"},
+			{Title: "Lesson 3", URL: "https://example.com/3"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RenderIndexNote failed: %v", err)
+	}
+
+	for _, want := range []string{"type: playlist", "1. [[Lesson_1]] ([source](https://example.com/1))", "2. Lesson 2 ❌ download failed", "3. Lesson 3 ⏳"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Index note missing %q:\n%s", want, content)
+		}
+	}
+
+	notePath, err := mgr.SaveNote(NoteData{Title: "Lesson 1", Playlist: mgr.IndexNoteTitle("Go Course: Basics")})
+	if err != nil {
+		t.Fatalf("SaveNote failed: %v", err)
+	}
+	noteBytes, _ := os.ReadFile(notePath)
+	if fm, err := ParseFrontmatter(string(noteBytes)); err != nil || fm.Playlist != "[[Go_Course_Basics]]" {
+		t.Errorf("Note does not link to the index (%v):\n%s", err, noteBytes)
+	}
+}
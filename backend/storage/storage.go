@@ -1,37 +1,54 @@
 package storage
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
-	"text/template"
+	"time"
 
+	"Varys/backend/document"
+	"Varys/backend/downloader"
+	"Varys/backend/subtitle"
+	"Varys/backend/transcriber"
 	"Varys/backend/translation"
 )
 
 // NoteData holds the data for the markdown note
 type NoteData struct {
-	Title            string
-	URL              string
-	Language         string
-	Description      string
-	Summary          string
-	KeyPoints        []string
-	Tags             []string
-	Assessment       map[string]string
-	OriginalText     string
-	TranslationPairs []translation.TranslationPair
-	AudioFile        string
-	CreatedTime      string
-	AssetsFolder     string
-	AIProvider       string
-	AIModel          string
+	Title              string
+	URL                string
+	Language           string
+	Description        string
+	Media              *downloader.MediaInfo // nil for articles and local files
+	Document           *document.Document    // nil unless the source is a document such as a PDF
+	Summary            string
+	KeyPoints          []string
+	Tags               []string
+	Assessment         map[string]string
+	OriginalText       string
+	Segments           []transcriber.Segment
+	TranslationPairs   []translation.TranslationPair
+	TranslationQuality translation.Quality // How many sentences needed a retry or are missing
+	GlossaryViolations []translation.GlossaryViolation
+	AudioFile          string
+	SubtitleFiles      []string
+	Created            time.Time
+	CreatedTime        string        // Created as "2006-01-02 15:04"
+	ProcessingTime     time.Duration // How long the task took
+	Author             string        // Article or document author; videos use Media.Uploader
+	Published          time.Time     // Article or document publication date; videos use Media.UploadDate
+	AssetsFolder       string
+	AIProvider         string
+	AIModel            string
+	Playlist           string // Title of the playlist index note, if the item came from a playlist
 }
 
 type Manager struct {
 	VaultPath string
+	Template  *NoteTemplate // nil uses DefaultTemplate
 }
 
 func NewManager(vaultPath string) *Manager {
@@ -69,7 +86,8 @@ func (m *Manager) SanitizeFilename(name string) string {
 	return name
 }
 
-// MoveMedia moves the media file (audio/video) to vault/assets
+// MoveMedia moves the media file (audio/video) to vault/assets. Files that already
+// are in the assets folder (media of an earlier note) are copied, not moved.
 func (m *Manager) MoveMedia(sourcePath string, targetName string) (string, error) {
 	// Default assets folder name
 	assetsDir := filepath.Join(m.VaultPath, "assets")
@@ -81,28 +99,29 @@ func (m *Manager) MoveMedia(sourcePath string, targetName string) (string, error
 	finalName := targetName + ext
 	destPath := filepath.Join(assetsDir, finalName)
 
+	// Already in place, e.g. a resumed task reusing media saved by an earlier run
+	srcAbs, _ := filepath.Abs(sourcePath)
+	destAbs, _ := filepath.Abs(destPath)
+	if srcAbs == destAbs {
+		return finalName, nil
+	}
+
 	// Remove dest if exists
 	if _, err := os.Stat(destPath); err == nil {
 		os.Remove(destPath)
 	}
 
-	// Try Rename first
-	err := os.Rename(sourcePath, destPath)
-	if err != nil {
-		// Fallback to Copy
-		src, err := os.Open(sourcePath)
-		if err != nil {
-			return "", err
-		}
-		defer src.Close()
-
-		dst, err := os.Create(destPath)
-		if err != nil {
+	// Media of another note stays where it is
+	if filepath.Dir(srcAbs) == filepath.Dir(destAbs) {
+		if err := copyMedia(sourcePath, destPath); err != nil {
 			return "", err
 		}
-		defer dst.Close()
+		return finalName, nil
+	}
 
-		if _, err := io.Copy(dst, src); err != nil {
+	// Try Rename first, fall back to Copy
+	if err := os.Rename(sourcePath, destPath); err != nil {
+		if err := copyMedia(sourcePath, destPath); err != nil {
 			return "", err
 		}
 		os.Remove(sourcePath)
@@ -111,109 +130,89 @@ func (m *Manager) MoveMedia(sourcePath string, targetName string) (string, error
 	return finalName, nil
 }
 
-func (m *Manager) SaveNote(data NoteData) (string, error) {
-	safeTitle := m.SanitizeFilename(data.Title)
-	filename := safeTitle + ".md"
-	filePath := filepath.Join(m.VaultPath, filename)
-
-	tmplStr := `---
-created: {{.CreatedTime}}
-source: "{{.URL}}"
-type: auto_clipper
-language: {{.Language}}
-ai_provider: {{.AIProvider}}
-ai_model: {{.AIModel}}
-tags:
-{{- range .Tags}}
-  - {{.}}
-{{- end}}
----
-
-# {{.Title}}
-
-{{if .Description}}
-## 视频简介
-
-{{.Description}}
-{{end}}
-
-## 智能摘要
-
-{{.Summary}}
-
-### 核心观点
-
-{{- range .KeyPoints}}
-- {{.}}
-{{- end}}
-
-### 智能评估
-| 维度 | 评估内容 |
-| :--- | :--- |
-| **真实性** | {{index .Assessment "authenticity"}} |
-| **有效性** | {{index .Assessment "effectiveness"}} |
-| **实时性** | {{index .Assessment "timeliness"}} |
-| **替代策略** | {{index .Assessment "alternatives"}} |
-
----
-
-## 媒体回放
-![[{{.AssetsFolder}}/{{.AudioFile}}]]
-
----
-{{if .TranslationPairs}}
-## 对照翻译
-
-<table width="100%">
-  <colgroup>
-    <col width="50%" />
-    <col width="50%" />
-  </colgroup>
-  <thead>
-    <tr>
-      <th>原文</th>
-      <th>译文</th>
-    </tr>
-  </thead>
-  <tbody>
-    {{- range .TranslationPairs}}
-    <tr>
-      <td>{{tableSafe .Original}}</td>
-      <td>{{tableSafe .Translated}}</td>
-    </tr>
-    {{- end}}
-  </tbody>
-</table>
-
----
-{{else}}
-## 原始内容
-
-{{.OriginalText}}
-{{end}}
-`
-
-	funcMap := template.FuncMap{
-		"tableSafe": func(s string) string {
-			// Replace newlines with <br> to keep table structure valid
-			return strings.ReplaceAll(s, "\n", "<br>")
-		},
-	}
-
-	tt, err := template.New("note").Funcs(funcMap).Parse(tmplStr)
+func copyMedia(sourcePath, destPath string) error {
+	src, err := os.Open(sourcePath)
 	if err != nil {
-		return "", err
+		return err
 	}
+	defer src.Close()
 
-	f, err := os.Create(filePath)
+	dst, err := os.Create(destPath)
 	if err != nil {
-		return "", err
+		return err
 	}
-	defer f.Close()
+	defer dst.Close()
 
-	if err := tt.Execute(f, data); err != nil {
-		return "", err
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// WriteSubtitles writes .srt and .vtt sidecars next to a media file in vault/assets.
+// When translation pairs are available, bilingual variants (.bilingual.srt/.vtt)
+// are written as well. Returns the created file names relative to the assets folder.
+func (m *Manager) WriteSubtitles(mediaName string, segments []transcriber.Segment, pairs []translation.TranslationPair) ([]string, error) {
+	if len(segments) == 0 {
+		return nil, nil
+	}
+
+	assetsDir := filepath.Join(m.VaultPath, "assets")
+	if err := os.MkdirAll(assetsDir, 0755); err != nil {
+		return nil, err
+	}
+	base := strings.TrimSuffix(mediaName, filepath.Ext(mediaName))
+
+	files := map[string]string{}
+	cues := subtitle.FromSegments(segments)
+	files[base+".srt"] = subtitle.SRT(cues)
+	files[base+".vtt"] = subtitle.VTT(cues)
+
+	if bilingual := subtitle.Bilingual(segments, pairs); len(bilingual) > 0 {
+		files[base+".bilingual.srt"] = subtitle.SRT(bilingual)
+		files[base+".bilingual.vtt"] = subtitle.VTT(bilingual)
 	}
 
+	var written []string
+	for _, name := range []string{base + ".srt", base + ".vtt", base + ".bilingual.srt", base + ".bilingual.vtt"} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(assetsDir, name), []byte(content), 0644); err != nil {
+			return written, err
+		}
+		written = append(written, name)
+	}
+	return written, nil
+}
+
+// SaveNote renders the note and writes it to the vault, named after its title.
+func (m *Manager) SaveNote(data NoteData) (string, error) {
+	content, err := m.RenderNote(data)
+	if err != nil {
+		return "", err
+	}
+	filePath := m.NotePath(m.SanitizeFilename(data.Title))
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		return "", err
+	}
 	return filePath, nil
-}
\ No newline at end of file
+}
+
+// RenderNote returns the markdown of a note without writing it, using the
+// manager's template or the default one.
+func (m *Manager) RenderNote(data NoteData) (string, error) {
+	tmpl := m.Template
+	if tmpl == nil {
+		var err error
+		if tmpl, err = LoadTemplate(DefaultTemplate, "", ""); err != nil {
+			return "", err
+		}
+	}
+	return tmpl.Render(data)
+}
+
+// FormatTimestamp renders a media offset as HH:MM:SS.
+func FormatTimestamp(d time.Duration) string {
+	total := int64(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", total/3600, (total/60)%60, total%60)
+}
@@ -5,6 +5,11 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"Varys/backend/downloader"
+	"Varys/backend/transcriber"
+	"Varys/backend/translation"
 )
 
 func TestSanitizeFilename(t *testing.T) {
@@ -89,6 +94,53 @@ func TestSaveNote(t *testing.T) {
 	}
 }
 
+func TestSaveNoteMediaInfo(t *testing.T) {
+	vaultDir := t.TempDir()
+	mgr := NewManager(vaultDir)
+
+	path, err := mgr.SaveNote(NoteData{
+		Title:        "Media",
+		AssetsFolder: "assets",
+		AudioFile:    "Media.m4a",
+		Media: &downloader.MediaInfo{
+			Title:      "Media",
+			Uploader:   `The "Go" Channel`,
+			Duration:   754 * time.Second,
+			UploadDate: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+			ViewCount:  1234,
+			Thumbnail:  "https://i.example.com/abc.jpg",
+			Tags:       []string{"go"},
+			Chapters: []downloader.Chapter{
+				{Title: "Intro", Start: 0},
+				{Title: "Main", Start: 90 * time.Second},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SaveNote failed: %v", err)
+	}
+	contentBytes, _ := os.ReadFile(path)
+	content := string(contentBytes)
+
+	fm, err := ParseFrontmatter(content)
+	if err != nil {
+		t.Fatalf("frontmatter is not valid YAML: %v\n%s", err, content)
+	}
+	if fm.Uploader != `The "Go" Channel` || fm.Published != "2024-01-05" || fm.Duration != "00:12:34" ||
+		fm.ViewCount != 1234 || fm.Thumbnail != "https://i.example.com/abc.jpg" || len(fm.Keywords) != 1 || fm.Keywords[0] != "go" {
+		t.Errorf("unexpected frontmatter: %+v", fm)
+	}
+
+	for _, want := range []string{
+		"## 章节",
+		"- [[assets/Media.m4a#t=90|00:01:30]] Main",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Note missing %q:\n%s", want, content)
+		}
+	}
+}
+
 func TestMoveMedia(t *testing.T) {
 	// Setup temp vault and source dir
 	tempDir, _ := os.MkdirTemp("", "source")
@@ -122,3 +174,135 @@ func TestMoveMedia(t *testing.T) {
 		t.Error("Source file still exists")
 	}
 }
+
+func TestMoveMedia_KeepsOtherNotesAssets(t *testing.T) {
+	vaultDir := t.TempDir()
+	mgr := NewManager(vaultDir)
+	assets := filepath.Join(vaultDir, "assets")
+	os.MkdirAll(assets, 0755)
+	original := filepath.Join(assets, "Talk.m4a")
+	os.WriteFile(original, []byte("audio"), 0644)
+
+	// Same file again: nothing to do
+	if name, err := mgr.MoveMedia(original, "Talk"); err != nil || name != "Talk.m4a" {
+		t.Fatalf("MoveMedia in place = %q, %v", name, err)
+	}
+	if _, err := os.Stat(original); err != nil {
+		t.Fatal("media moved onto itself was removed")
+	}
+
+	// Versioned copy: the original note keeps its media
+	if _, err := mgr.MoveMedia(original, "Talk_v2"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Talk.m4a", "Talk_v2.m4a"} {
+		if _, err := os.Stat(filepath.Join(assets, name)); err != nil {
+			t.Errorf("%s missing: %v", name, err)
+		}
+	}
+}
+
+func TestSaveNoteWithSegments(t *testing.T) {
+	vaultDir := t.TempDir()
+	mgr := NewManager(vaultDir)
+
+	data := NoteData{
+		Title:        "Segmented",
+		Summary:      "Summary",
+		AssetsFolder: "assets",
+		AudioFile:    "Segmented.m4a",
+		Segments: []transcriber.Segment{
+			{Start: 0, End: 2 * time.Second, Text: "Intro"},
+			{Start: 83 * time.Second, End: 90 * time.Second, Text: "Main topic"},
+		},
+	}
+
+	path, err := mgr.SaveNote(data)
+	if err != nil {
+		t.Fatalf("SaveNote failed: %v", err)
+	}
+	contentBytes, _ := os.ReadFile(path)
+	content := string(contentBytes)
+
+	if !strings.Contains(content, "- [[assets/Segmented.m4a#t=83|00:01:23]] Main topic") {
+		t.Errorf("Timestamp link not found in note:\n%s", content)
+	}
+}
+
+func TestSaveNoteTranslationQuality(t *testing.T) {
+	vaultDir := t.TempDir()
+	mgr := NewManager(vaultDir)
+
+	pairs := []translation.TranslationPair{
+		{Original: "a", Translated: "A"},
+		{Original: "b", Translated: "B", Status: translation.StatusRetried},
+		{Original: "c", Translated: "(Translation missing)", Status: translation.StatusMissing},
+	}
+	path, err := mgr.SaveNote(NoteData{
+		Title:              "Quality",
+		TranslationPairs:   pairs,
+		TranslationQuality: translation.Summarize(pairs),
+	})
+	if err != nil {
+		t.Fatalf("SaveNote failed: %v", err)
+	}
+	contentBytes, _ := os.ReadFile(path)
+	content := string(contentBytes)
+
+	if !strings.Contains(content, "> 翻译质量: 1/3 句一次对齐 · 1 句拆分重试后对齐 · 1 句缺失") {
+		t.Errorf("Translation quality line not found in note:\n%s", content)
+	}
+}
+
+func TestSaveNoteGlossaryViolations(t *testing.T) {
+	vaultDir := t.TempDir()
+	mgr := NewManager(vaultDir)
+
+	path, err := mgr.SaveNote(NoteData{
+		Title:            "Glossary",
+		TranslationPairs: []translation.TranslationPair{{Original: "Ethereum is up", Translated: "以太币上涨"}},
+		GlossaryViolations: []translation.GlossaryViolation{
+			{Index: 0, Term: "Ethereum", Expected: "以太坊"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SaveNote failed: %v", err)
+	}
+	contentBytes, _ := os.ReadFile(path)
+	content := string(contentBytes)
+
+	if !strings.Contains(content, "> - 第 1 句: Ethereum → 应为「以太坊」") {
+		t.Errorf("Glossary violation not found in note:\n%s", content)
+	}
+}
+
+func TestWriteSubtitles(t *testing.T) {
+	vaultDir := t.TempDir()
+	mgr := NewManager(vaultDir)
+
+	segments := []transcriber.Segment{{Start: 0, End: time.Second, Text: "Hello."}}
+	pairs := []translation.TranslationPair{{Original: "Hello.", Translated: "你好。"}}
+
+	files, err := mgr.WriteSubtitles("Lecture.m4a", segments, pairs)
+	if err != nil {
+		t.Fatalf("WriteSubtitles failed: %v", err)
+	}
+
+	expected := []string{"Lecture.srt", "Lecture.vtt", "Lecture.bilingual.srt", "Lecture.bilingual.vtt"}
+	if len(files) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, files)
+	}
+	for i, name := range expected {
+		if files[i] != name {
+			t.Errorf("Expected %s at index %d, got %s", name, i, files[i])
+		}
+		if _, err := os.Stat(filepath.Join(vaultDir, "assets", name)); err != nil {
+			t.Errorf("Subtitle file %s not written: %v", name, err)
+		}
+	}
+
+	bilingual, _ := os.ReadFile(filepath.Join(vaultDir, "assets", "Lecture.bilingual.srt"))
+	if !strings.Contains(string(bilingual), "Hello.\n你好。") {
+		t.Errorf("Bilingual subtitle missing translation:\n%s", bilingual)
+	}
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"Varys/backend/document"
+	"Varys/backend/downloader"
+	"Varys/backend/transcriber"
+	"Varys/backend/translation"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTemplate is the built-in note layout used when none is configured.
+const DefaultTemplate = "default"
+
+//go:embed templates/*.md
+var builtinFS embed.FS
+
+// NoteTemplate is a parsed and validated note layout. See docs/templates.md for
+// the data model and the available functions.
+type NoteTemplate struct {
+	Name string
+	tmpl *template.Template
+}
+
+// BuiltinTemplates lists the names of the templates shipped with Varys.
+func BuiltinTemplates() []string {
+	entries, _ := builtinFS.ReadDir("templates")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".md"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTemplate resolves spec to a note template: empty for the default, the name of
+// a built-in template, or a file path. Relative paths are looked up in the vault
+// first, then in the config directory.
+func LoadTemplate(spec, vaultPath, configDir string) (*NoteTemplate, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		spec = DefaultTemplate
+	}
+	if data, err := builtinFS.ReadFile("templates/" + spec + ".md"); err == nil {
+		return ParseTemplate(spec, string(data))
+	}
+
+	candidates := []string{spec}
+	if !filepath.IsAbs(spec) {
+		candidates = nil
+		for _, dir := range []string{vaultPath, configDir} {
+			if dir != "" {
+				candidates = append(candidates, filepath.Join(dir, spec))
+			}
+		}
+	}
+	for _, path := range candidates {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return ParseTemplate(path, string(data))
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("note template %q not found (built-in: %s; files are looked up in the vault and the config directory)",
+		spec, strings.Join(BuiltinTemplates(), ", "))
+}
+
+// ParseTemplate parses text and renders it with sample data, so mistakes such as
+// unknown fields are reported before a task runs instead of after it finished.
+func ParseTemplate(name, text string) (*NoteTemplate, error) {
+	tmpl, err := template.New(filepath.Base(name)).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid note template: %w", err)
+	}
+	t := &NoteTemplate{Name: name, tmpl: tmpl}
+
+	samples := []struct {
+		kind string
+		data NoteData
+	}{
+		{"video", sampleVideoNote()},
+		{"article", sampleArticleNote()},
+		{"document", sampleDocumentNote()},
+	}
+	for _, sample := range samples {
+		if _, err := t.Render(sample.data); err != nil {
+			return nil, fmt.Errorf("invalid note template (%s note): %w", sample.kind, err)
+		}
+	}
+	return t, nil
+}
+
+// Render executes the template.
+func (t *NoteTemplate) Render(data NoteData) (string, error) {
+	var buf strings.Builder
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+var templateFuncs = template.FuncMap{
+	"tableSafe": func(s string) string {
+		// Replace newlines with <br> to keep table structure valid
+		return strings.ReplaceAll(s, "\n", "<br>")
+	},
+	"timestamp": FormatTimestamp,
+	"inc": func(i int) int {
+		return i + 1
+	},
+	"seconds": func(d time.Duration) string {
+		// Media fragment offset used by Obsidian (#t=SECONDS)
+		return fmt.Sprintf("%d", int64(d/time.Second))
+	},
+	"date": func(layout string, t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(layout)
+	},
+	"wikilink": func(target string, alias ...string) string {
+		if len(alias) > 0 && alias[0] != "" {
+			return "[[" + target + "|" + alias[0] + "]]"
+		}
+		return "[[" + target + "]]"
+	},
+	"yaml":     yamlString,
+	"timelink": timeLink,
+	"pagelink": pageLink,
+	"indent": func(level int) string {
+		// Nests outline entries as Markdown list items
+		if level <= 1 {
+			return ""
+		}
+		return strings.Repeat("  ", level-1)
+	},
+}
+
+// yamlString quotes s as a YAML double-quoted scalar, safe for any content.
+func yamlString(s string) string {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s) // JSON strings are valid YAML double-quoted scalars
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// timeLink links to offset d in the note's media, or returns the plain timestamp
+// when the note has no media.
+func timeLink(data NoteData, d time.Duration) string {
+	if data.AudioFile == "" {
+		return FormatTimestamp(d)
+	}
+	return fmt.Sprintf("[[%s/%s#t=%d|%s]]", data.AssetsFolder, data.AudioFile, int64(d/time.Second), FormatTimestamp(d))
+}
+
+// pageLink links to a page of the note's document, or returns "p. N" when the
+// note has no document file.
+func pageLink(data NoteData, page int) string {
+	if data.AudioFile == "" {
+		return fmt.Sprintf("p. %d", page)
+	}
+	return fmt.Sprintf("[[%s/%s#page=%d|p. %d]]", data.AssetsFolder, data.AudioFile, page, page)
+}
+
+func sampleVideoNote() NoteData {
+	created := time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)
+	return NoteData{
+		Title:       "Sample",
+		URL:         "https://example.com/watch?v=1",
+		Language:    "English",
+		Description: "Description",
+		Media: &downloader.MediaInfo{
+			ID: "1", Title: "Sample", Uploader: "Uploader", Duration: 90 * time.Second,
+			UploadDate: created, ViewCount: 1, Tags: []string{"tag"}, Thumbnail: "https://example.com/t.jpg",
+			Chapters: []downloader.Chapter{{Title: "Intro", Start: 0, End: 30 * time.Second}},
+		},
+		Summary:            "Summary",
+		KeyPoints:          []string{"Point"},
+		Tags:               []string{"tag"},
+		Assessment:         map[string]string{"authenticity": "a", "effectiveness": "b", "timeliness": "c", "alternatives": "d"},
+		OriginalText:       "Text",
+		Segments:           []transcriber.Segment{{Start: 0, End: time.Second, Text: "Text"}},
+		TranslationPairs:   []translation.TranslationPair{{Original: "Text", Translated: "Text"}},
+		TranslationQuality: translation.Quality{Total: 1, Aligned: 1},
+		GlossaryViolations: []translation.GlossaryViolation{{Index: 0, Term: "term", Expected: "term"}},
+		AudioFile:          "Sample.m4a",
+		SubtitleFiles:      []string{"Sample.srt"},
+		Created:            created,
+		CreatedTime:        created.Format("2006-01-02 15:04"),
+		AssetsFolder:       "assets",
+		AIProvider:         "ollama",
+		AIModel:            "model",
+		Playlist:           "Playlist",
+	}
+}
+
+func sampleArticleNote() NoteData {
+	created := time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)
+	return NoteData{
+		Title:        "Sample",
+		URL:          "https://example.com/post",
+		Language:     "English",
+		Summary:      "Summary",
+		OriginalText: "Text",
+		Created:      created,
+		CreatedTime:  created.Format("2006-01-02 15:04"),
+		AssetsFolder: "assets",
+	}
+}
+
+func sampleDocumentNote() NoteData {
+	note := sampleArticleNote()
+	note.URL = "/papers/sample.pdf"
+	note.AudioFile = "Sample.pdf"
+	note.Document = &document.Document{
+		Format: document.FormatPDF, Title: "Sample", Author: "Author", Keywords: []string{"keyword"},
+		Pages:    []document.Page{{Number: 1, Text: "Text"}},
+		Headings: []document.Heading{{Level: 1, Text: "1 Introduction", Page: 1}},
+	}
+	return note
+}
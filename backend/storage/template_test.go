@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"Varys/backend/document"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuiltinTemplates(t *testing.T) {
+	names := BuiltinTemplates()
+	for _, want := range []string{"default", "english", "minimal"} {
+		found := false
+		for _, n := range names {
+			found = found || n == want
+		}
+		if !found {
+			t.Errorf("built-in template %q missing from %v", want, names)
+		}
+	}
+
+	// Every built-in must pass validation
+	for _, name := range names {
+		if _, err := LoadTemplate(name, "", ""); err != nil {
+			t.Errorf("built-in template %q: %v", name, err)
+		}
+	}
+}
+
+func TestEnglishTemplate(t *testing.T) {
+	tmpl, err := LoadTemplate("english", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := tmpl.Render(sampleVideoNote())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## Summary", "## Timeline", "## Translation", "[[assets/Sample.m4a#t=0|00:00:00]] Intro"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("english note missing %q", want)
+		}
+	}
+	if strings.Contains(out, "智能摘要") {
+		t.Error("english note contains Chinese headings")
+	}
+}
+
+func TestDocumentOutline(t *testing.T) {
+	data := sampleDocumentNote()
+	data.Document.Headings = append(data.Document.Headings, document.Heading{Level: 2, Text: "1.1 Scope", Page: 3})
+	for name, heading := range map[string]string{"default": "## 目录", "english": "## Outline"} {
+		tmpl, err := LoadTemplate(name, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := tmpl.Render(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{heading, "\n- [[assets/Sample.pdf#page=1|p. 1]] 1 Introduction", "\n  - [[assets/Sample.pdf#page=3|p. 3]] 1.1 Scope", "![[assets/Sample.pdf]]"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: document note missing %q", name, want)
+			}
+		}
+	}
+
+	// Markdown and text files have no pages and are not attached
+	text := sampleDocumentNote()
+	text.AudioFile = ""
+	text.Document = &document.Document{
+		Format:   document.FormatMarkdown,
+		Chapters: []document.Chapter{{Text: "# Notes"}},
+		Headings: []document.Heading{{Level: 1, Text: "Notes"}},
+	}
+	for _, name := range []string{"default", "english"} {
+		tmpl, _ := LoadTemplate(name, "", "")
+		out, err := tmpl.Render(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "\n- Notes\n") || strings.Contains(out, "![[") {
+			t.Errorf("%s: text document note:\n%s", name, out)
+		}
+	}
+}
+
+func TestLoadTemplate_Files(t *testing.T) {
+	vault := t.TempDir()
+	configDir := t.TempDir()
+	os.WriteFile(filepath.Join(configDir, "note.md"), []byte("config {{.Title}}"), 0644)
+
+	tmpl, err := LoadTemplate("note.md", vault, configDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := tmpl.Render(NoteData{Title: "T"}); out != "config T" {
+		t.Errorf("config dir template rendered %q", out)
+	}
+
+	// The vault takes precedence
+	os.WriteFile(filepath.Join(vault, "note.md"), []byte("vault {{.Title}}"), 0644)
+	tmpl, _ = LoadTemplate("note.md", vault, configDir)
+	if out, _ := tmpl.Render(NoteData{Title: "T"}); out != "vault T" {
+		t.Errorf("vault template rendered %q", out)
+	}
+
+	// Absolute paths
+	abs := filepath.Join(t.TempDir(), "abs.md")
+	os.WriteFile(abs, []byte("abs"), 0644)
+	if _, err := LoadTemplate(abs, vault, configDir); err != nil {
+		t.Errorf("absolute path: %v", err)
+	}
+
+	if _, err := LoadTemplate("missing.md", vault, configDir); err == nil {
+		t.Error("missing template should fail")
+	}
+}
+
+func TestParseTemplate_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"syntax", "{{if .Title}}unclosed"},
+		{"unknown field", "{{.Titel}}"},
+		{"unknown function", "{{upper .Title}}"},
+		{"nil media", "{{.Media.Uploader}}"}, // fails for articles
+	}
+	for _, tt := range tests {
+		if _, err := ParseTemplate("note.md", tt.text); err == nil {
+			t.Errorf("%s: expected a validation error", tt.name)
+		}
+	}
+
+	if _, err := ParseTemplate("note.md", "{{with .Media}}{{.Uploader}}{{end}}"); err != nil {
+		t.Errorf("guarded media access: %v", err)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	text := `{{date "2006-01-02" .Created}}|{{wikilink .Playlist}}|{{wikilink "a" "b"}}|{{yaml .Title}}|{{range .Segments}}{{timelink $ .Start}}{{end}}`
+	tmpl, err := ParseTemplate("funcs", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := NoteData{
+		Title:        `He said: "hi"`,
+		Playlist:     "List",
+		Created:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		AssetsFolder: "assets",
+		AudioFile:    "a.m4a",
+	}
+	data.Segments = sampleVideoNote().Segments
+	data.Segments[0].Start = 75 * time.Second
+
+	out, err := tmpl.Render(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `2024-05-01|[[List]]|[[a|b]]|"He said: \"hi\""|[[assets/a.m4a#t=75|00:01:15]]`
+	if out != want {
+		t.Errorf("got  %s\nwant %s", out, want)
+	}
+
+	data.AudioFile = ""
+	if out, _ := tmpl.Render(data); !strings.HasSuffix(out, "|00:01:15") {
+		t.Errorf("timelink without media = %q", out)
+	}
+}
@@ -0,0 +1,126 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"Varys/backend/transcriber"
+	"Varys/backend/translation"
+)
+
+// Cue is a single subtitle entry.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Lines []string
+}
+
+// FromSegments builds one cue per transcript segment.
+func FromSegments(segments []transcriber.Segment) []Cue {
+	cues := make([]Cue, 0, len(segments))
+	for _, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		cues = append(cues, Cue{Start: seg.Start, End: seg.End, Lines: []string{text}})
+	}
+	return cues
+}
+
+// Bilingual builds cues that show each original sentence above its translation.
+// Translation pairs are split differently from whisper segments, so each pair is
+// placed on the timeline by its character offset within the segment stream and
+// the time is interpolated inside the segment it falls into.
+func Bilingual(segments []transcriber.Segment, pairs []translation.TranslationPair) []Cue {
+	if len(segments) == 0 || len(pairs) == 0 {
+		return nil
+	}
+
+	// Cumulative character offsets at the start of each segment
+	offsets := make([]int, len(segments)+1)
+	for i, seg := range segments {
+		offsets[i+1] = offsets[i] + textLen(seg.Text)
+	}
+	total := offsets[len(segments)]
+
+	timeAt := func(pos int) time.Duration {
+		if pos >= total {
+			return segments[len(segments)-1].End
+		}
+		for i, seg := range segments {
+			if pos < offsets[i+1] {
+				span := offsets[i+1] - offsets[i]
+				if span == 0 {
+					return seg.Start
+				}
+				frac := float64(pos-offsets[i]) / float64(span)
+				return seg.Start + time.Duration(frac*float64(seg.End-seg.Start))
+			}
+		}
+		return segments[len(segments)-1].End
+	}
+
+	cues := make([]Cue, 0, len(pairs))
+	pos := 0
+	for _, pair := range pairs {
+		n := textLen(pair.Original)
+		start, end := timeAt(pos), timeAt(pos+n)
+		pos += n
+		if end <= start {
+			end = start + time.Second
+		}
+		lines := []string{strings.TrimSpace(pair.Original)}
+		if translated := strings.TrimSpace(pair.Translated); translated != "" {
+			lines = append(lines, translated)
+		}
+		cues = append(cues, Cue{Start: start, End: end, Lines: lines})
+	}
+	return cues
+}
+
+// SRT renders cues as a SubRip document.
+func SRT(cues []Cue) string {
+	var sb strings.Builder
+	for i, cue := range cues {
+		fmt.Fprintf(&sb, "%d\n%s --> %s\n%s\n\n", i+1, clock(cue.Start, ","), clock(cue.End, ","), strings.Join(cue.Lines, "\n"))
+	}
+	return sb.String()
+}
+
+// VTT renders cues as a WebVTT document.
+func VTT(cues []Cue) string {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		lines := make([]string, len(cue.Lines))
+		for i, line := range cue.Lines {
+			// "-->" is not allowed in cue payloads
+			lines[i] = strings.ReplaceAll(line, "-->", "->")
+		}
+		fmt.Fprintf(&sb, "%s --> %s\n%s\n\n", clock(cue.Start, "."), clock(cue.End, "."), strings.Join(lines, "\n"))
+	}
+	return sb.String()
+}
+
+// clock formats a duration as HH:MM:SS<sep>mmm.
+func clock(d time.Duration, sep string) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := int64(d / time.Millisecond)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, (ms/60000)%60, (ms/1000)%60, sep, ms%1000)
+}
+
+// textLen counts non-space runes so that re-segmented text maps onto the same offsets.
+func textLen(s string) int {
+	n := 0
+	for _, r := range s {
+		if !unicode.IsSpace(r) {
+			n++
+		}
+	}
+	return n
+}
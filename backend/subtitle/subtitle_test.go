@@ -0,0 +1,61 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"Varys/backend/transcriber"
+	"Varys/backend/translation"
+)
+
+func TestSRTAndVTT(t *testing.T) {
+	segments := []transcriber.Segment{
+		{Start: 0, End: 1500 * time.Millisecond, Text: "Hello."},
+		{Start: 61 * time.Second, End: 62*time.Second + 250*time.Millisecond, Text: "Goodbye."},
+	}
+	cues := FromSegments(segments)
+
+	srt := SRT(cues)
+	if !strings.Contains(srt, "1\n00:00:00,000 --> 00:00:01,500\nHello.\n") {
+		t.Errorf("Unexpected SRT output:\n%s", srt)
+	}
+	if !strings.Contains(srt, "2\n00:01:01,000 --> 00:01:02,250\nGoodbye.\n") {
+		t.Errorf("Unexpected SRT output:\n%s", srt)
+	}
+
+	vtt := VTT(cues)
+	if !strings.HasPrefix(vtt, "WEBVTT\n\n") {
+		t.Error("VTT header missing")
+	}
+	if !strings.Contains(vtt, "00:01:01.000 --> 00:01:02.250\nGoodbye.\n") {
+		t.Errorf("Unexpected VTT output:\n%s", vtt)
+	}
+}
+
+func TestBilingual(t *testing.T) {
+	segments := []transcriber.Segment{
+		{Start: 0, End: 4 * time.Second, Text: "One two. Three four."},
+		{Start: 4 * time.Second, End: 6 * time.Second, Text: "Five."},
+	}
+	pairs := []translation.TranslationPair{
+		{Original: "One two.", Translated: "一二。"},
+		{Original: "Three four.", Translated: "三四。"},
+		{Original: "Five.", Translated: "五。"},
+	}
+
+	cues := Bilingual(segments, pairs)
+	if len(cues) != 3 {
+		t.Fatalf("Expected 3 cues, got %d", len(cues))
+	}
+	// "One two." is 7 of the 17 non-space chars of the first segment
+	if cues[0].Start != 0 || cues[1].Start <= cues[0].Start || cues[1].End != 4*time.Second {
+		t.Errorf("Unexpected interpolated timing: %+v", cues)
+	}
+	if cues[2].Start != 4*time.Second || cues[2].End != 6*time.Second {
+		t.Errorf("Unexpected timing for last cue: %+v", cues[2])
+	}
+	if len(cues[2].Lines) != 2 || cues[2].Lines[1] != "五。" {
+		t.Errorf("Expected original and translation lines, got %v", cues[2].Lines)
+	}
+}
@@ -4,6 +4,7 @@ package integration
 import (
 	"Varys/backend/dependency"
 	"Varys/backend/downloader"
+	"context"
 	"os"
 	"testing"
 )
@@ -24,14 +25,17 @@ func TestDownloaderIntegration(t *testing.T) {
 	testURL := "https://www.youtube.com/watch?v=BaW_jenozKc" 
 
 	t.Run("GetMetadata", func(t *testing.T) {
-		title, err := dl.GetVideoTitle(testURL)
+		info, err := dl.GetMediaInfo(context.Background(), testURL)
 		if err != nil {
-			t.Logf("Note: Skipping title check, likely network or binary missing: %v", err)
+			t.Logf("Note: Skipping metadata check, likely network or binary missing: %v", err)
 			return
 		}
-		if title == "" {
+		if info.Title == "" {
 			t.Error("Expected non-empty title")
 		}
+		if info.Duration == 0 {
+			t.Error("Expected duration to be set")
+		}
 	})
 
 	t.Run("DownloadAudioOnly", func(t *testing.T) {
@@ -41,7 +45,7 @@ func TestDownloaderIntegration(t *testing.T) {
 		}
 		defer os.RemoveAll(tempDir)
 
-		path, err := dl.DownloadMedia(testURL, tempDir, true, nil)
+		path, err := dl.DownloadMedia(context.Background(), testURL, tempDir, true, nil)
 		if err != nil {
 			t.Logf("Note: Skipping download, likely network or binary missing: %v", err)
 			return
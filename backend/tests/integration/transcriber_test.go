@@ -4,6 +4,7 @@ package integration_test
 import (
 	"Varys/backend/dependency"
 	"Varys/backend/transcriber"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -90,7 +91,7 @@ func TestIntegrationTranscribe(t *testing.T) {
 
 	// 5. Run Transcriber
 	tr := transcriber.NewTranscriber(depMgr)
-	text, _, err := tr.Transcribe(audioFile, modelFile, nil)
+	text, _, err := tr.Transcribe(context.Background(), audioFile, modelFile, nil)
 	if err != nil {
 		t.Fatalf("Transcribe failed: %v", err)
 	}
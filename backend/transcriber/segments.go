@@ -0,0 +1,236 @@
+package transcriber
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment is a single timed span of a transcript.
+type Segment struct {
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	Text  string        `json:"text"`
+	// AvgLogprob is the mean token log probability reported (or derived) from whisper.
+	// Zero means whisper did not provide one.
+	AvgLogprob float64 `json:"avg_logprob,omitempty"`
+}
+
+// Transcript is the structured result of a timestamped transcription.
+type Transcript struct {
+	Language string
+	Segments []Segment
+	// Text is the cleaned, flattened transcript used for translation and analysis.
+	Text string
+}
+
+// JoinSegments flattens segments into newline separated text.
+func JoinSegments(segments []Segment) string {
+	lines := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		if text := strings.TrimSpace(seg.Text); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// whisperJSON covers both whisper.cpp (-oj/-ojf) and openai-whisper JSON layouts.
+type whisperJSON struct {
+	// whisper.cpp
+	Result struct {
+		Language string `json:"language"`
+	} `json:"result"`
+	Transcription []struct {
+		Offsets struct {
+			From int64 `json:"from"`
+			To   int64 `json:"to"`
+		} `json:"offsets"`
+		Text   string `json:"text"`
+		Tokens []struct {
+			Text string  `json:"text"`
+			P    float64 `json:"p"`
+		} `json:"tokens"`
+	} `json:"transcription"`
+
+	// openai-whisper
+	Language string `json:"language"`
+	Segments []struct {
+		Start      float64  `json:"start"`
+		End        float64  `json:"end"`
+		Text       string   `json:"text"`
+		AvgLogprob *float64 `json:"avg_logprob"`
+	} `json:"segments"`
+}
+
+// ParseWhisperJSON parses a whisper JSON transcript into segments and returns
+// the language recorded in the file, if any.
+func ParseWhisperJSON(data []byte) ([]Segment, string, error) {
+	var raw whisperJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, "", err
+	}
+
+	var segments []Segment
+	for _, item := range raw.Transcription {
+		seg := Segment{
+			Start: time.Duration(item.Offsets.From) * time.Millisecond,
+			End:   time.Duration(item.Offsets.To) * time.Millisecond,
+			Text:  strings.TrimSpace(item.Text),
+		}
+
+		// Derive avg logprob from token probabilities, skipping special tokens like [_BEG_]
+		var sum float64
+		var count int
+		for _, tok := range item.Tokens {
+			if strings.HasPrefix(tok.Text, "[_") || tok.P <= 0 {
+				continue
+			}
+			sum += math.Log(tok.P)
+			count++
+		}
+		if count > 0 {
+			seg.AvgLogprob = sum / float64(count)
+		}
+		segments = append(segments, seg)
+	}
+
+	for _, item := range raw.Segments {
+		seg := Segment{
+			Start: time.Duration(item.Start * float64(time.Second)),
+			End:   time.Duration(item.End * float64(time.Second)),
+			Text:  strings.TrimSpace(item.Text),
+		}
+		if item.AvgLogprob != nil {
+			seg.AvgLogprob = *item.AvgLogprob
+		}
+		segments = append(segments, seg)
+	}
+
+	lang := raw.Result.Language
+	if lang == "" {
+		lang = raw.Language
+	}
+	return segments, lang, nil
+}
+
+// Regex to match "00:00:01,000 --> 00:00:04,500" (also accepts "." as the millisecond separator)
+var srtTimingRegex = regexp.MustCompile(`(\d+):(\d{2}):(\d{2})[,.](\d{3})\s*-->\s*(\d+):(\d{2}):(\d{2})[,.](\d{3})`)
+
+// ParseSRT parses an SRT subtitle document into segments.
+func ParseSRT(content string) ([]Segment, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	blocks := regexp.MustCompile(`\n\s*\n`).Split(strings.TrimSpace(content), -1)
+
+	var segments []Segment
+	for _, block := range blocks {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		timingIdx := -1
+		for i, line := range lines {
+			if srtTimingRegex.MatchString(line) {
+				timingIdx = i
+				break
+			}
+		}
+		if timingIdx == -1 {
+			continue
+		}
+
+		m := srtTimingRegex.FindStringSubmatch(lines[timingIdx])
+		start, err := parseClock(m[1], m[2], m[3], m[4])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(m[5], m[6], m[7], m[8])
+		if err != nil {
+			return nil, err
+		}
+
+		text := strings.TrimSpace(strings.Join(lines[timingIdx+1:], " "))
+		segments = append(segments, Segment{Start: start, End: end, Text: text})
+	}
+	return segments, nil
+}
+
+func parseClock(h, m, s, ms string) (time.Duration, error) {
+	parts := []string{h, m, s, ms}
+	values := make([]int, len(parts))
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp component %q: %w", p, err)
+		}
+		values[i] = v
+	}
+	return time.Duration(values[0])*time.Hour +
+		time.Duration(values[1])*time.Minute +
+		time.Duration(values[2])*time.Second +
+		time.Duration(values[3])*time.Millisecond, nil
+}
+
+// Regex to match WebVTT cue timings; the hour component is optional ("01:02.500 --> 01:04.000")
+var vttTimingRegex = regexp.MustCompile(`(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})\s*-->\s*(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})`)
+
+// vttTagRegex matches inline markup such as <c>, </c>, <i> and karaoke timestamps <00:00:01.500>
+var vttTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// ParseVTT parses a WebVTT subtitle document into segments. Rolling auto-generated
+// captions (YouTube repeats the previous line in every cue) are reduced to the new text.
+func ParseVTT(content string) ([]Segment, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	// Only truly empty lines end a cue; YouTube cues contain whitespace-only lines
+	blocks := regexp.MustCompile(`\n\n+`).Split(strings.TrimSpace(content), -1)
+
+	var segments []Segment
+	var lastLine string
+	for _, block := range blocks {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		timingIdx := -1
+		for i, line := range lines {
+			if vttTimingRegex.MatchString(line) {
+				timingIdx = i
+				break
+			}
+		}
+		if timingIdx == -1 {
+			continue // header, NOTE, STYLE or REGION block
+		}
+
+		m := vttTimingRegex.FindStringSubmatch(lines[timingIdx])
+		start, err := parseClock(orZero(m[1]), m[2], m[3], m[4])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(orZero(m[5]), m[6], m[7], m[8])
+		if err != nil {
+			return nil, err
+		}
+
+		var text []string
+		for _, line := range lines[timingIdx+1:] {
+			line = strings.TrimSpace(html.UnescapeString(vttTagRegex.ReplaceAllString(line, "")))
+			if line == "" || line == lastLine {
+				continue
+			}
+			text = append(text, line)
+			lastLine = line
+		}
+		if len(text) == 0 {
+			continue
+		}
+		segments = append(segments, Segment{Start: start, End: end, Text: strings.Join(text, " ")})
+	}
+	return segments, nil
+}
+
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
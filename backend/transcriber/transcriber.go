@@ -2,10 +2,11 @@ package transcriber
 
 import (
 	"Varys/backend/dependency"
+	"Varys/backend/process"
 	"bufio"
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"regexp"
 	"strings"
 )
@@ -18,41 +19,28 @@ func NewTranscriber(dep *dependency.Manager) *Transcriber {
 	return &Transcriber{dep: dep}
 }
 
-func (t *Transcriber) Transcribe(audioPath, modelPath string, onProgress func(string)) (string, string, error) {
-	// 1. Find binary
-	candidates := []string{"whisper-cli", "whisper-cpp", "whisper-main", "whisper", "main"}
-	var binPath string
-	for _, name := range candidates {
-		if p, found := t.dep.CheckSystemDependency(name); found {
-			binPath = p
-			break
-		}
-	}
-
-	if binPath == "" {
-		return "", "", fmt.Errorf("whisper binary not found in PATH. Please install whisper.cpp")
-	}
-
-	// 2. Check model
-	if modelPath == "" {
-		return "", "", fmt.Errorf("whisper model path not configured")
-	}
-	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
-		return "", "", fmt.Errorf("model file not found at %s", modelPath)
+// Transcribe returns the plain transcript and detected language. Cancelling ctx kills
+// ffmpeg/whisper and removes their intermediate files.
+func (t *Transcriber) Transcribe(ctx context.Context, audioPath, modelPath string, onProgress func(string)) (string, string, error) {
+	binPath, err := t.prepare(modelPath)
+	if err != nil {
+		return "", "", err
 	}
 
 	// 3. Convert to WAV (16kHz, Mono)
 	wavPath := audioPath + ".wav"
-	if err := t.convertToWav(audioPath, wavPath); err != nil {
+	if err := t.convertToWav(ctx, audioPath, wavPath); err != nil {
 		return "", "", err
 	}
 	defer os.Remove(wavPath)
+	resultFile := wavPath + ".txt"
+	defer os.Remove(resultFile)
 
 	// 4. Run Whisper
 	// Use --no-timestamps to reduce VRAM usage and prevent OOM on M-series chips for long files.
 	// Use --print-progress to maintain a heartbeat in the logs.
 	// Added --entropy-thold and --logprob-thold to suppress hallucinations/looping.
-	cmd := exec.Command(binPath,
+	detectedLang, err := t.runWhisper(ctx, binPath, onProgress,
 		"-m", modelPath,
 		"-f", wavPath,
 		"--output-txt",
@@ -62,16 +50,124 @@ func (t *Transcriber) Transcribe(audioPath, modelPath string, onProgress func(st
 		"--entropy-thold", "2.4",
 		"--logprob-thold", "-1.0",
 	)
+	if err != nil {
+		return "", "", err
+	}
+
+	content, err := os.ReadFile(resultFile)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read transcript file %s: %w", resultFile, err)
+	}
+
+	cleanedContent := t.cleanTimestamps(string(content))
+	cleanedContent = t.cleanHallucinations(cleanedContent)
+	return cleanedContent, detectedLang, nil
+}
+
+// TranscribeSegments runs whisper with timestamps enabled and returns the transcript
+// as timed segments. Whisper's JSON output is preferred; SRT is used as a fallback
+// for builds that cannot write JSON.
+func (t *Transcriber) TranscribeSegments(ctx context.Context, audioPath, modelPath string, onProgress func(string)) (*Transcript, error) {
+	binPath, err := t.prepare(modelPath)
+	if err != nil {
+		return nil, err
+	}
+
+	wavPath := audioPath + ".wav"
+	if err := t.convertToWav(ctx, audioPath, wavPath); err != nil {
+		return nil, err
+	}
+	defer os.Remove(wavPath)
+	jsonFile := wavPath + ".json"
+	srtFile := wavPath + ".srt"
+	defer os.Remove(jsonFile)
+	defer os.Remove(srtFile)
+
+	// Timestamps are required here, so --no-timestamps is intentionally omitted.
+	// --output-json-full includes per-token probabilities used for avg logprob.
+	detectedLang, err := t.runWhisper(ctx, binPath, onProgress,
+		"-m", modelPath,
+		"-f", wavPath,
+		"--output-json-full",
+		"--output-srt",
+		"--print-progress",
+		"--language", "auto",
+		"--entropy-thold", "2.4",
+		"--logprob-thold", "-1.0",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []Segment
+	if data, err := os.ReadFile(jsonFile); err == nil {
+		var lang string
+		segments, lang, err = ParseWhisperJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse transcript file %s: %w", jsonFile, err)
+		}
+		if detectedLang == "" {
+			detectedLang = lang
+		}
+	} else if data, err := os.ReadFile(srtFile); err == nil {
+		segments, err = ParseSRT(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse transcript file %s: %w", srtFile, err)
+		}
+	} else {
+		return nil, fmt.Errorf("whisper produced neither %s nor %s", jsonFile, srtFile)
+	}
+
+	// Keep one line per segment so downstream sentence splitting (translation,
+	// subtitles) stays aligned with the segment timeline.
+	segments = t.cleanSegments(segments)
+	return &Transcript{
+		Language: detectedLang,
+		Segments: segments,
+		Text:     JoinSegments(segments),
+	}, nil
+}
+
+// prepare locates the whisper binary and validates the model path.
+func (t *Transcriber) prepare(modelPath string) (string, error) {
+	// 1. Find binary
+	candidates := []string{"whisper-cli", "whisper-cpp", "whisper-main", "whisper", "main"}
+	var binPath string
+	for _, name := range candidates {
+		if p, found := t.dep.CheckSystemDependency(name); found {
+			binPath = p
+			break
+		}
+	}
+
+	if binPath == "" {
+		return "", fmt.Errorf("whisper binary not found in PATH. Please install whisper.cpp")
+	}
+
+	// 2. Check model
+	if modelPath == "" {
+		return "", fmt.Errorf("whisper model path not configured")
+	}
+	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
+		return "", fmt.Errorf("model file not found at %s", modelPath)
+	}
+	return binPath, nil
+}
+
+// runWhisper executes whisper, streams its output to onProgress and returns
+// the auto-detected language (if reported).
+func (t *Transcriber) runWhisper(ctx context.Context, binPath string, onProgress func(string), args ...string) (string, error) {
+	cmd := process.Command(ctx, binPath, args...)
 
 	// Stream output
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return "", "", err
+		return "", err
 	}
 	cmd.Stderr = cmd.Stdout
 
 	if err := cmd.Start(); err != nil {
-		return "", "", fmt.Errorf("failed to start whisper: %w", err)
+		return "", fmt.Errorf("failed to start whisper: %w", err)
 	}
 
 	var detectedLang string
@@ -96,19 +192,44 @@ func (t *Transcriber) Transcribe(audioPath, modelPath string, onProgress func(st
 	}
 
 	if err := cmd.Wait(); err != nil {
-		return "", "", fmt.Errorf("whisper execution failed: %w", err)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("whisper execution failed: %w", err)
 	}
+	return detectedLang, nil
+}
 
-	resultFile := wavPath + ".txt"
-	content, err := os.ReadFile(resultFile)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read transcript file %s: %w", resultFile, err)
-	}
-	defer os.Remove(resultFile)
+// cleanSegments drops empty segments and collapses hallucination loops
+// (the same line repeated back to back) while keeping segment timing intact.
+func (t *Transcriber) cleanSegments(segments []Segment) []Segment {
+	cleaned := make([]Segment, 0, len(segments))
+	lastText := ""
+	dupCount := 0
 
-	cleanedContent := t.cleanTimestamps(string(content))
-	cleanedContent = t.cleanHallucinations(cleanedContent)
-	return cleanedContent, detectedLang, nil
+	reThanks := regexp.MustCompile(`(?i)(Thank you\.?(\s*)){2,}`)
+
+	for _, seg := range segments {
+		seg.Text = strings.TrimSpace(reThanks.ReplaceAllString(seg.Text, "Thank you."))
+		if seg.Text == "" {
+			continue
+		}
+
+		normalized := strings.ToLower(seg.Text)
+		if normalized == lastText {
+			dupCount++
+			if dupCount >= 2 { // Allow max 2 repetitions
+				// Extend the previous segment so the timeline stays continuous
+				cleaned[len(cleaned)-1].End = seg.End
+				continue
+			}
+		} else {
+			lastText = normalized
+			dupCount = 0
+		}
+		cleaned = append(cleaned, seg)
+	}
+	return cleaned
 }
 
 func (t *Transcriber) cleanHallucinations(text string) string {
@@ -168,7 +289,7 @@ func (t *Transcriber) cleanTimestamps(text string) string {
 	return re.ReplaceAllString(text, "")
 }
 
-func (t *Transcriber) convertToWav(input, output string) error {
+func (t *Transcriber) convertToWav(ctx context.Context, input, output string) error {
 	// Use embedded ffmpeg if available, else system
 	ffmpegPath := t.dep.GetBinaryPath("ffmpeg")
 	if _, err := os.Stat(ffmpegPath); os.IsNotExist(err) {
@@ -179,9 +300,13 @@ func (t *Transcriber) convertToWav(input, output string) error {
 		}
 	}
 
-	cmd := exec.Command(ffmpegPath, "-i", input, "-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le", "-y", output)
+	cmd := process.Command(ctx, ffmpegPath, "-i", input, "-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le", "-y", output)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
+		os.Remove(output)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("ffmpeg conversion failed: %s, %w", string(out), err)
 	}
 	return nil
@@ -2,10 +2,14 @@ package transcriber
 
 import (
 	"Varys/backend/dependency"
+	"context"
+	"errors"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestTranscribe(t *testing.T) {
@@ -71,7 +75,7 @@ fi
 	audioPath := filepath.Join(tempDir, "test_audio.m4a")
 	os.WriteFile(audioPath, []byte("audio"), 0644)
 
-	text, _, err := tr.Transcribe(audioPath, modelPath, nil)
+	text, _, err := tr.Transcribe(context.Background(), audioPath, modelPath, nil)
 	if err != nil {
 		t.Fatalf("Transcribe failed: %v", err)
 	}
@@ -80,3 +84,234 @@ fi
 		t.Errorf("Unexpected transcript: %q", text)
 	}
 }
+
+func TestTranscribeSegments(t *testing.T) {
+	tempDir := t.TempDir()
+	binDir := filepath.Join(tempDir, "bin")
+	os.MkdirAll(binDir, 0755)
+
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+oldPath)
+
+	// Mock whisper writes a whisper.cpp style JSON next to the input
+	script := `#!/bin/sh
+NAME=$(basename "$0")
+if [ "$NAME" = "ffmpeg" ]; then
+    eval LAST=\${$#}
+    touch "$LAST"
+elif [ "$NAME" = "whisper-cli" ]; then
+    INPUT=""
+    while [ $# -gt 0 ]; do
+        if [ "$1" = "-f" ]; then
+            INPUT="$2"
+            break
+        fi
+        shift
+    done
+    echo "auto-detected language: en (p = 0.98)"
+    cat > "${INPUT}.json" <<'JSON'
+{"result": {"language": "en"}, "transcription": [
+  {"offsets": {"from": 0, "to": 2500}, "text": " Hello there."},
+  {"offsets": {"from": 2500, "to": 5000}, "text": " General Kenobi."}
+]}
+JSON
+fi
+`
+	for _, name := range []string{"whisper-cli", "ffmpeg"} {
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	modelPath := filepath.Join(tempDir, "model.bin")
+	os.WriteFile(modelPath, []byte("data"), 0644)
+	audioPath := filepath.Join(tempDir, "test_audio.m4a")
+	os.WriteFile(audioPath, []byte("audio"), 0644)
+
+	tr := NewTranscriber(&dependency.Manager{})
+	result, err := tr.TranscribeSegments(context.Background(), audioPath, modelPath, nil)
+	if err != nil {
+		t.Fatalf("TranscribeSegments failed: %v", err)
+	}
+
+	if result.Language != "en" {
+		t.Errorf("Expected language en, got %q", result.Language)
+	}
+	if len(result.Segments) != 2 {
+		t.Fatalf("Expected 2 segments, got %d", len(result.Segments))
+	}
+	if result.Segments[1].Start != 2500*time.Millisecond || result.Segments[1].Text != "General Kenobi." {
+		t.Errorf("Unexpected second segment: %+v", result.Segments[1])
+	}
+	if !strings.Contains(result.Text, "Hello there") || !strings.Contains(result.Text, "General Kenobi") {
+		t.Errorf("Unexpected transcript text: %q", result.Text)
+	}
+	if _, err := os.Stat(audioPath + ".wav.json"); !os.IsNotExist(err) {
+		t.Error("Whisper JSON output was not cleaned up")
+	}
+}
+
+func TestTranscribeSegments_Cancel(t *testing.T) {
+	tempDir := t.TempDir()
+	binDir := filepath.Join(tempDir, "bin")
+	os.MkdirAll(binDir, 0755)
+
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+oldPath)
+
+	// Mock ffmpeg writes part of the WAV and hangs
+	script := `#!/bin/sh
+eval LAST=\${$#}
+echo partial > "$LAST"
+sleep 30
+`
+	for _, name := range []string{"whisper-cli", "ffmpeg"} {
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	modelPath := filepath.Join(tempDir, "model.bin")
+	os.WriteFile(modelPath, []byte("data"), 0644)
+	audioPath := filepath.Join(tempDir, "test_audio.m4a")
+	os.WriteFile(audioPath, []byte("audio"), 0644)
+	wavPath := audioPath + ".wav"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for i := 0; i < 500; i++ {
+			if _, err := os.Stat(wavPath); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+	}()
+
+	start := time.Now()
+	tr := NewTranscriber(&dependency.Manager{})
+	_, err := tr.TranscribeSegments(ctx, audioPath, modelPath, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Transcription took %v to stop", elapsed)
+	}
+	if _, err := os.Stat(wavPath); !os.IsNotExist(err) {
+		t.Error("Partial WAV was not removed")
+	}
+}
+
+func TestParseWhisperJSON(t *testing.T) {
+	t.Run("whisper.cpp full", func(t *testing.T) {
+		data := `{"result": {"language": "zh"}, "transcription": [
+			{"offsets": {"from": 1000, "to": 3000}, "text": " 你好",
+			 "tokens": [{"text": "[_BEG_]", "p": 0.1}, {"text": "你", "p": 0.5}, {"text": "好", "p": 0.5}]}
+		]}`
+		segs, lang, err := ParseWhisperJSON([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lang != "zh" || len(segs) != 1 {
+			t.Fatalf("Unexpected parse result: lang=%q segs=%+v", lang, segs)
+		}
+		if segs[0].Start != time.Second || segs[0].End != 3*time.Second || segs[0].Text != "你好" {
+			t.Errorf("Unexpected segment: %+v", segs[0])
+		}
+		if diff := segs[0].AvgLogprob - math.Log(0.5); diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Expected avg logprob %f, got %f", math.Log(0.5), segs[0].AvgLogprob)
+		}
+	})
+
+	t.Run("openai-whisper", func(t *testing.T) {
+		data := `{"language": "en", "segments": [{"start": 0.5, "end": 1.25, "text": " Hi", "avg_logprob": -0.3}]}`
+		segs, lang, err := ParseWhisperJSON([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lang != "en" || len(segs) != 1 {
+			t.Fatalf("Unexpected parse result: lang=%q segs=%+v", lang, segs)
+		}
+		if segs[0].Start != 500*time.Millisecond || segs[0].End != 1250*time.Millisecond || segs[0].AvgLogprob != -0.3 {
+			t.Errorf("Unexpected segment: %+v", segs[0])
+		}
+	})
+}
+
+func TestParseSRT(t *testing.T) {
+	srt := "1\r\n00:00:00,000 --> 00:00:02,000\r\nFirst line\r\n\r\n2\r\n00:01:02,500 --> 00:01:04,000\r\nSecond\r\nline\r\n"
+	segs, err := ParseSRT(srt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != 2 {
+		t.Fatalf("Expected 2 segments, got %d", len(segs))
+	}
+	if segs[1].Start != time.Minute+2500*time.Millisecond || segs[1].Text != "Second line" {
+		t.Errorf("Unexpected segment: %+v", segs[1])
+	}
+}
+
+func TestParseVTT(t *testing.T) {
+	// Shape of YouTube auto-generated captions: every cue repeats the previous line
+	vtt := `WEBVTT
+Kind: captions
+Language: en
+
+00:00:00.000 --> 00:00:02.000 align:start position:0%
+ 
+hello<00:00:00.500><c> world</c>
+
+00:00:02.000 --> 00:00:02.010 align:start position:0%
+hello world
+ 
+
+00:00:02.010 --> 00:00:04.500 align:start position:0%
+hello world
+how<00:00:02.800><c> are</c><00:00:03.000><c> you</c>
+
+NOTE a comment
+
+01:02.500 --> 01:04.000
+<i>Tom &amp; Jerry</i>
+`
+	segs, err := ParseVTT(vtt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"hello world", "how are you", "Tom & Jerry"}
+	if len(segs) != len(want) {
+		t.Fatalf("Expected %d segments, got %+v", len(want), segs)
+	}
+	for i, w := range want {
+		if segs[i].Text != w {
+			t.Errorf("Segment %d: expected %q, got %q", i, w, segs[i].Text)
+		}
+	}
+	if segs[1].Start != 2010*time.Millisecond || segs[1].End != 4500*time.Millisecond {
+		t.Errorf("Unexpected timing: %+v", segs[1])
+	}
+	if segs[2].Start != time.Minute+2500*time.Millisecond {
+		t.Errorf("Expected hour-less timestamp to parse, got %v", segs[2].Start)
+	}
+}
+
+func TestCleanSegments(t *testing.T) {
+	tr := NewTranscriber(&dependency.Manager{})
+	segs := []Segment{
+		{Start: 0, End: time.Second, Text: "Thank you."},
+		{Start: time.Second, End: 2 * time.Second, Text: "thank you."},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "Thank you."},
+		{Start: 3 * time.Second, End: 4 * time.Second, Text: "  "},
+		{Start: 4 * time.Second, End: 5 * time.Second, Text: "Bye."},
+	}
+	cleaned := tr.cleanSegments(segs)
+	if len(cleaned) != 3 {
+		t.Fatalf("Expected 3 segments, got %d: %+v", len(cleaned), cleaned)
+	}
+	if cleaned[1].End != 3*time.Second {
+		t.Errorf("Expected collapsed duplicate to extend end time, got %v", cleaned[1].End)
+	}
+}
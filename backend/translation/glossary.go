@@ -0,0 +1,223 @@
+package translation
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GlossaryEntry maps a source term to its required translation.
+// An empty Translation means the term must be kept unchanged (product names, tickers).
+type GlossaryEntry struct {
+	Term        string `yaml:"term"`
+	Translation string `yaml:"translation"`
+}
+
+// Expected returns the text that must appear in the translation.
+func (e GlossaryEntry) Expected() string {
+	if e.Translation != "" {
+		return e.Translation
+	}
+	return e.Term
+}
+
+// Glossary is a user supplied list of terms with fixed translations.
+type Glossary struct {
+	Entries []GlossaryEntry
+}
+
+// GlossaryViolation records a sentence whose translation did not use the glossary term.
+type GlossaryViolation struct {
+	Index      int // position in the translation pairs (0-based)
+	Term       string
+	Expected   string
+	Original   string
+	Translated string
+}
+
+// LoadGlossary reads a glossary from a .csv ("term,translation" per row, optional header)
+// or .yaml/.yml file (a "term: translation" map or a list of {term, translation}).
+func LoadGlossary(path string) (*Glossary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glossary: %w", err)
+	}
+
+	var entries []GlossaryEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		entries, err = parseGlossaryCSV(string(data))
+	case ".yaml", ".yml":
+		entries, err = parseGlossaryYAML(data)
+	default:
+		return nil, fmt.Errorf("unsupported glossary format %q (use .csv, .yaml or .yml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse glossary %s: %w", filepath.Base(path), err)
+	}
+	return newGlossary(entries), nil
+}
+
+func newGlossary(entries []GlossaryEntry) *Glossary {
+	g := &Glossary{}
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		e.Term = strings.TrimSpace(e.Term)
+		e.Translation = strings.TrimSpace(e.Translation)
+		key := strings.ToLower(e.Term)
+		if e.Term == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		g.Entries = append(g.Entries, e)
+	}
+	// Longer terms first so "Ethereum Classic" is listed before "Ethereum"
+	sort.SliceStable(g.Entries, func(i, j int) bool {
+		return len(g.Entries[i].Term) > len(g.Entries[j].Term)
+	})
+	return g
+}
+
+func parseGlossaryCSV(content string) ([]GlossaryEntry, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+
+	var entries []GlossaryEntry
+	for first := true; ; first = false {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 0 {
+			continue
+		}
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "term") {
+			continue // header row
+		}
+		entry := GlossaryEntry{Term: record[0]}
+		if len(record) > 1 {
+			entry.Translation = record[1]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseGlossaryYAML(data []byte) ([]GlossaryEntry, error) {
+	var asMap map[string]string
+	if err := yaml.Unmarshal(data, &asMap); err == nil {
+		entries := make([]GlossaryEntry, 0, len(asMap))
+		for term, translation := range asMap {
+			entries = append(entries, GlossaryEntry{Term: term, Translation: translation})
+		}
+		return entries, nil
+	}
+
+	var asList []GlossaryEntry
+	if err := yaml.Unmarshal(data, &asList); err != nil {
+		return nil, err
+	}
+	return asList, nil
+}
+
+// Match returns the entries whose term occurs in any of the given texts.
+func (g *Glossary) Match(texts []string) []GlossaryEntry {
+	if g == nil {
+		return nil
+	}
+	var matched []GlossaryEntry
+	for _, e := range g.Entries {
+		for _, text := range texts {
+			if containsTerm(text, e.Term) {
+				matched = append(matched, e)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// CheckGlossary reports translated sentences that do not contain the glossary translation
+// of a term used in the original sentence. Missing translations are not reported.
+func CheckGlossary(pairs []TranslationPair, g *Glossary) []GlossaryViolation {
+	if g == nil {
+		return nil
+	}
+	var violations []GlossaryViolation
+	for i, pair := range pairs {
+		if pair.Status == StatusMissing {
+			continue
+		}
+		for _, e := range g.Entries {
+			if !containsTerm(pair.Original, e.Term) || containsTerm(pair.Translated, e.Expected()) {
+				continue
+			}
+			violations = append(violations, GlossaryViolation{
+				Index:      i,
+				Term:       e.Term,
+				Expected:   e.Expected(),
+				Original:   pair.Original,
+				Translated: pair.Translated,
+			})
+		}
+	}
+	return violations
+}
+
+// glossaryPrompt renders the terms for one batch, or "" when none apply.
+func glossaryPrompt(entries []GlossaryEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Glossary (always use these translations; terms marked \"keep\" must stay unchanged):\n")
+	for _, e := range entries {
+		if e.Translation == "" {
+			fmt.Fprintf(&sb, "- %s => keep\n", e.Term)
+		} else {
+			fmt.Fprintf(&sb, "- %s => %s\n", e.Term, e.Translation)
+		}
+	}
+	return sb.String()
+}
+
+// containsTerm does a case-insensitive search for term. When the term starts or ends
+// with an ASCII letter or digit, the match must not be part of a longer ASCII word,
+// so "ETH" does not match "Ethereum" while CJK text around a term is fine.
+func containsTerm(text, term string) bool {
+	if term == "" {
+		return false
+	}
+	lowerText := strings.ToLower(text)
+	lowerTerm := strings.ToLower(term)
+
+	for offset := 0; ; {
+		idx := strings.Index(lowerText[offset:], lowerTerm)
+		if idx < 0 {
+			return false
+		}
+		start := offset + idx
+		end := start + len(lowerTerm)
+		beforeOK := start == 0 || !isASCIIWordByte(lowerTerm[0]) || !isASCIIWordByte(lowerText[start-1])
+		afterOK := end == len(lowerText) || !isASCIIWordByte(lowerTerm[len(lowerTerm)-1]) || !isASCIIWordByte(lowerText[end])
+		if beforeOK && afterOK {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isASCIIWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9'
+}
@@ -0,0 +1,132 @@
+package translation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeGlossary(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadGlossary(t *testing.T) {
+	tests := map[string]string{
+		"glossary.csv":  "term,translation\nEthereum,以太坊\n# comment\nLoRA\n\"staking, liquid\",流动性质押\n",
+		"glossary.yaml": "Ethereum: 以太坊\nLoRA:\n\"staking, liquid\": 流动性质押\n",
+		"glossary.yml":  "- term: Ethereum\n  translation: 以太坊\n- term: LoRA\n- term: staking, liquid\n  translation: 流动性质押\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			g, err := LoadGlossary(writeGlossary(t, name, content))
+			if err != nil {
+				t.Fatalf("LoadGlossary failed: %v", err)
+			}
+			got := map[string]string{}
+			for _, e := range g.Entries {
+				got[e.Term] = e.Translation
+			}
+			want := map[string]string{"Ethereum": "以太坊", "LoRA": "", "staking, liquid": "流动性质押"}
+			if len(got) != len(want) {
+				t.Fatalf("Expected %v, got %v", want, got)
+			}
+			for term, tr := range want {
+				if got[term] != tr {
+					t.Errorf("%s: expected %q, got %q", term, tr, got[term])
+				}
+			}
+		})
+	}
+
+	if _, err := LoadGlossary(writeGlossary(t, "glossary.txt", "x")); err == nil {
+		t.Error("Expected error for unsupported extension")
+	}
+}
+
+func TestContainsTerm(t *testing.T) {
+	tests := []struct {
+		text, term string
+		want       bool
+	}{
+		{"We use LoRA adapters", "lora", true},
+		{"Ethereum rallied", "ETH", false},
+		{"ETH rallied", "ETH", true},
+		{"使用LoRA微调", "LoRA", true},
+		{"以太坊上涨", "以太坊", true},
+		{"C++ and Go", "C++", true},
+		{"", "x", false},
+	}
+	for _, tt := range tests {
+		if got := containsTerm(tt.text, tt.term); got != tt.want {
+			t.Errorf("containsTerm(%q, %q) = %v, want %v", tt.text, tt.term, got, tt.want)
+		}
+	}
+}
+
+// PromptRecorder records prompts and answers with a fixed translation per line.
+type PromptRecorder struct {
+	MockProvider
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *PromptRecorder) Chat(ctx context.Context, prompt string, opts map[string]interface{}, cb func(string)) (string, error) {
+	p.mu.Lock()
+	p.prompts = append(p.prompts, prompt)
+	p.mu.Unlock()
+
+	var out strings.Builder
+	for _, m := range inputLine.FindAllStringSubmatch(prompt[strings.Index(prompt, "Input:"):], -1) {
+		out.WriteString(m[1] + ". " + strings.ReplaceAll(m[2], "Ethereum", "以太币") + "\n")
+	}
+	return out.String(), nil
+}
+
+func TestTranslate_GlossaryPerBatch(t *testing.T) {
+	g := newGlossary([]GlossaryEntry{
+		{Term: "Ethereum", Translation: "以太坊"},
+		{Term: "LoRA"},
+		{Term: "Solana", Translation: "索拉纳"},
+	})
+	provider := &PromptRecorder{}
+	tr := NewTranslatorWithOptions(provider, Options{Glossary: g})
+
+	text := "Ethereum is up\nLoRA is cheap\nb\nc\nd\ne\nf\nSolana is fast"
+	pairs, err := tr.Translate(context.Background(), text, "Simplified Chinese", 4096, nil)
+	if err != nil {
+		t.Fatalf("Translate failed: %v", err)
+	}
+
+	if len(provider.prompts) != 2 {
+		t.Fatalf("Expected 2 batches, got %d", len(provider.prompts))
+	}
+	first, second := provider.prompts[0], provider.prompts[1]
+	if !strings.Contains(first, "- Ethereum => 以太坊") || !strings.Contains(first, "- LoRA => keep") {
+		t.Errorf("First batch prompt missing glossary terms:\n%s", first)
+	}
+	if strings.Contains(first, "Solana") {
+		t.Errorf("First batch prompt should not include unused terms:\n%s", first)
+	}
+	if !strings.Contains(second, "- Solana => 索拉纳") || strings.Contains(second, "Ethereum") {
+		t.Errorf("Second batch prompt has wrong glossary terms:\n%s", second)
+	}
+
+	violations := CheckGlossary(pairs, g)
+	if len(violations) != 2 {
+		t.Fatalf("Expected 2 violations, got %+v", violations)
+	}
+	if violations[0].Index != 0 || violations[0].Expected != "以太坊" {
+		t.Errorf("Unexpected first violation: %+v", violations[0])
+	}
+	if violations[1].Term != "Solana" || violations[1].Index != 7 {
+		t.Errorf("Unexpected second violation: %+v", violations[1])
+	}
+}
@@ -5,24 +5,90 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 type Translator struct {
 	provider analyzer.LLMProvider
+	opts     Options
+	limiter  *rateLimiter
+}
+
+// Options controls how batches are dispatched to the provider.
+type Options struct {
+	// Workers is the number of batches translated in parallel (default 1).
+	Workers int
+	// RateLimit caps requests per minute across all workers (0 = unlimited).
+	RateLimit int
+	// Glossary terms found in a batch are added to that batch's prompt.
+	Glossary *Glossary
 }
 
 func NewTranslator(provider analyzer.LLMProvider) *Translator {
+	return NewTranslatorWithOptions(provider, Options{})
+}
+
+// NewTranslatorWithOptions creates a Translator with a worker pool and rate limit.
+func NewTranslatorWithOptions(provider analyzer.LLMProvider, opts Options) *Translator {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
 	return &Translator{
 		provider: provider,
+		opts:     opts,
+		limiter:  newRateLimiter(opts.RateLimit),
 	}
 }
 
 type TranslationPair struct {
 	Original   string `json:"original"`
 	Translated string `json:"translated"`
+	// Status is empty when the first batch came back aligned, otherwise StatusRetried or StatusMissing.
+	Status string `json:"status,omitempty"`
 }
 
+// Pair statuses for translation quality reporting.
+const (
+	StatusRetried = "retried" // aligned only after re-sending in a smaller batch
+	StatusMissing = "missing" // no usable translation even as a single sentence
+)
+
+// missingTranslation is the placeholder for sentences the model never translated.
+const missingTranslation = "(Translation missing)"
+
+// Quality counts how each sentence of a translation was obtained.
+type Quality struct {
+	Total   int
+	Aligned int
+	Retried int
+	Missing int
+}
+
+// Summarize counts pair statuses.
+func Summarize(pairs []TranslationPair) Quality {
+	q := Quality{Total: len(pairs)}
+	for _, p := range pairs {
+		switch p.Status {
+		case StatusRetried:
+			q.Retried++
+		case StatusMissing:
+			q.Missing++
+		default:
+			q.Aligned++
+		}
+	}
+	return q
+}
+
+// batchSize is the number of numbered sentences sent per request.
+const batchSize = 7
+
+// Translate splits text into sentences and translates them in numbered batches.
+// Batches run on a pool of Options.Workers goroutines and are reassembled in the
+// original order. onProgress receives (completed batches, total batches) and never
+// goes backwards. The first failing batch cancels all in-flight requests.
 func (t *Translator) Translate(ctx context.Context, text string, targetLang string, contextSize int, onProgress func(int, int)) ([]TranslationPair, error) {
 	if targetLang == "" {
 		targetLang = "Simplified Chinese"
@@ -38,64 +104,84 @@ func (t *Translator) Translate(ctx context.Context, text string, targetLang stri
 	}
 
 	// 2. Process in small numbered batches
-	const batchSize = 7
-	var allPairs []TranslationPair
-
-	totalBatches := (len(sentences) + batchSize - 1) / batchSize
-
+	var batches [][]string
 	for i := 0; i < len(sentences); i += batchSize {
-		batchIdx := i / batchSize
-		if onProgress != nil {
-			onProgress(batchIdx, totalBatches)
-		}
-
 		end := i + batchSize
 		if end > len(sentences) {
 			end = len(sentences)
 		}
-		currentBatch := sentences[i:end]
+		batches = append(batches, sentences[i:end])
+	}
+	totalBatches := len(batches)
 
-		// Prepare numbered input
-		var inputBuilder strings.Builder
-		for j, s := range currentBatch {
-			inputBuilder.WriteString(fmt.Sprintf("%d. %s\n", j+1, s))
-		}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-		prompt := fmt.Sprintf(`You are a professional translator.
-Task: Translate the following numbered sentences into %s.
-Rules:
-1. Output exactly %d translated sentences.
-2. Use the same numbering format: "1. [translation]\n2. [translation]..."
-3. Do not include any introductory text, notes, or explanations.
-4. If a line is just punctuation, keep it as is.
+	results := make([][]TranslationPair, totalBatches)
+	jobs := make(chan int)
 
-Input:
-%s`, targetLang, len(currentBatch), inputBuilder.String())
+	var (
+		mu       sync.Mutex
+		done     int
+		firstErr error
+		wg       sync.WaitGroup
+	)
 
-		options := map[string]interface{}{
-			"num_ctx":     contextSize,
-			"num_predict": 2048,
-			"temperature": 0.1,
-		}
+	if onProgress != nil {
+		onProgress(0, totalBatches)
+	}
+
+	workers := t.opts.Workers
+	if workers > totalBatches {
+		workers = totalBatches
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				pairs, err := t.translateBatch(ctx, batches[idx], targetLang, contextSize)
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+					return
+				}
+				results[idx] = pairs
+				done++
+				if onProgress != nil {
+					onProgress(done, totalBatches)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
 
-		responseText, err := t.provider.Chat(ctx, prompt, options, nil)
-		if err != nil {
-			return nil, err
+dispatch:
+	for idx := range batches {
+		select {
+		case jobs <- idx:
+		case <-ctx.Done():
+			break dispatch
 		}
+	}
+	close(jobs)
+	wg.Wait()
 
-		// 3. Parse Numbered Output
-		translatedLines := t.parseNumberedOutput(responseText, len(currentBatch))
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-		for j := 0; j < len(currentBatch); j++ {
-			trans := "(Translation missing)"
-			if j < len(translatedLines) {
-				trans = translatedLines[j]
-			}
-			allPairs = append(allPairs, TranslationPair{
-				Original:   currentBatch[j],
-				Translated: trans,
-			})
-		}
+	var allPairs []TranslationPair
+	for _, pairs := range results {
+		allPairs = append(allPairs, pairs...)
 	}
 
 	if len(allPairs) == 0 && len(text) > 0 {
@@ -105,33 +191,129 @@ Input:
 	return allPairs, nil
 }
 
-// parseNumberedOutput extracts text from lines starting with "1. ", "2. ", etc.
-func (t *Translator) parseNumberedOutput(output string, expectedCount int) []string {
-	lines := strings.Split(output, "\n")
-	results := make([]string, 0, expectedCount)
+// translateBatch translates one numbered batch. If the numbering of the answer does not
+// line up with the input, the batch is split in half and retried, down to single sentences.
+func (t *Translator) translateBatch(ctx context.Context, currentBatch []string, targetLang string, contextSize int) ([]TranslationPair, error) {
+	return t.translateAligned(ctx, currentBatch, targetLang, contextSize, "")
+}
 
-	// Regex to match "1. Text" or "1: Text" or just the text if model forgot numbering
-	reNumber := regexp.MustCompile(`^\d+[\.\:\s]+(.*)$`)
+func (t *Translator) translateAligned(ctx context.Context, currentBatch []string, targetLang string, contextSize int, status string) ([]TranslationPair, error) {
+	responseText, err := t.requestBatch(ctx, currentBatch, targetLang, contextSize)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, line := range lines {
+	// 3. Parse Numbered Output
+	translatedLines, alignErr := t.parseNumberedOutput(responseText, len(currentBatch))
+	if alignErr == nil {
+		pairs := make([]TranslationPair, len(currentBatch))
+		for j := range currentBatch {
+			pairs[j] = TranslationPair{Original: currentBatch[j], Translated: translatedLines[j], Status: status}
+		}
+		return pairs, nil
+	}
+
+	if len(currentBatch) == 1 {
+		return []TranslationPair{{Original: currentBatch[0], Translated: missingTranslation, Status: StatusMissing}}, nil
+	}
+
+	// Misaligned: retry both halves separately
+	mid := (len(currentBatch) + 1) / 2
+	first, err := t.translateAligned(ctx, currentBatch[:mid], targetLang, contextSize, StatusRetried)
+	if err != nil {
+		return nil, err
+	}
+	second, err := t.translateAligned(ctx, currentBatch[mid:], targetLang, contextSize, StatusRetried)
+	if err != nil {
+		return nil, err
+	}
+	return append(first, second...), nil
+}
+
+// requestBatch sends one numbered batch to the provider and returns the raw answer.
+// Every request, including the retries of a misaligned batch, waits for the rate limit.
+func (t *Translator) requestBatch(ctx context.Context, currentBatch []string, targetLang string, contextSize int) (string, error) {
+	// Prepare numbered input
+	var inputBuilder strings.Builder
+	for j, s := range currentBatch {
+		inputBuilder.WriteString(fmt.Sprintf("%d. %s\n", j+1, s))
+	}
+
+	prompt := fmt.Sprintf(`You are a professional translator.
+Task: Translate the following numbered sentences into %s.
+Rules:
+1. Output exactly %d translated sentences.
+2. Use the same numbering format: "1. [translation]\n2. [translation]..."
+3. Do not include any introductory text, notes, or explanations.
+4. If a line is just punctuation, keep it as is.
+`, targetLang, len(currentBatch))
+
+	// Only the terms that occur in this batch, to keep the prompt small
+	if terms := glossaryPrompt(t.opts.Glossary.Match(currentBatch)); terms != "" {
+		prompt += "5. Follow the glossary below for terminology.\n\n" + terms
+	}
+	prompt += "\nInput:\n" + inputBuilder.String()
+
+	options := map[string]interface{}{
+		"num_ctx":     contextSize,
+		"num_predict": 2048,
+		"temperature": 0.1,
+	}
+
+	if err := t.limiter.wait(ctx); err != nil {
+		return "", err
+	}
+	return t.provider.Chat(ctx, prompt, options, nil)
+}
+
+// Regex to match "1. Text", "1: Text", "1) Text" or "1、Text"
+var reNumbered = regexp.MustCompile(`^(\d+)\s*[\.\:\)、]\s*(.*)$`)
+
+// parseNumberedOutput extracts the translations from lines starting with "1. ", "2. ", etc.
+// It returns an error unless the answer contains exactly entries 1..expectedCount, in
+// order, each non-empty. Unnumbered lines continue the previous entry (wrapped output);
+// text before the first entry is ignored. A single expected line may be unnumbered.
+func (t *Translator) parseNumberedOutput(output string, expectedCount int) ([]string, error) {
+	var results []string
+	var plain []string
+
+	for _, line := range strings.Split(output, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
 
-		matches := reNumber.FindStringSubmatch(line)
-		if len(matches) > 1 {
-			results = append(results, strings.TrimSpace(matches[1]))
-		} else {
-			// If model didn't use numbering but gave a line of text, take it
-			results = append(results, line)
+		matches := reNumbered.FindStringSubmatch(line)
+		if matches == nil {
+			if len(results) > 0 {
+				results[len(results)-1] = strings.TrimSpace(results[len(results)-1] + " " + line)
+			} else {
+				plain = append(plain, line)
+			}
+			continue
+		}
+
+		num, _ := strconv.Atoi(matches[1])
+		if num != len(results)+1 {
+			return nil, fmt.Errorf("expected entry %d, got %d", len(results)+1, num)
 		}
+		results = append(results, strings.TrimSpace(matches[2]))
 	}
 
-	// If the model produced a single block instead of lines,
-	// this parser might return too few items.
-	// The caller handles padding with "missing".
-	return results
+	// The model sometimes drops the number when translating a single sentence
+	if len(results) == 0 && expectedCount == 1 && len(plain) > 0 {
+		return []string{strings.Join(plain, " ")}, nil
+	}
+
+	if len(results) != expectedCount {
+		return nil, fmt.Errorf("expected %d entries, got %d", expectedCount, len(results))
+	}
+	for i, r := range results {
+		if r == "" {
+			return nil, fmt.Errorf("entry %d is empty", i+1)
+		}
+	}
+	return results, nil
 }
 
 // splitSentences splits text into sentences or logical segments using regex.
@@ -2,8 +2,14 @@ package translation
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // MockProvider implements analyzer.LLMProvider for testing
@@ -45,3 +51,265 @@ func TestTranslate(t *testing.T) {
 		t.Errorf("Expected 'World', got: %s", results[1].Translated)
 	}
 }
+
+// EchoProvider "translates" each numbered input line by upper-casing it, with a delay
+// that varies per batch so that parallel batches finish out of order.
+type EchoProvider struct {
+	MockProvider
+	inFlight    int32
+	maxInFlight int32
+	calls       int32
+	failOn      string
+}
+
+var inputLine = regexp.MustCompile(`(?m)^(\d+)\. (.*)$`)
+
+func (e *EchoProvider) Chat(ctx context.Context, prompt string, opts map[string]interface{}, cb func(string)) (string, error) {
+	n := atomic.AddInt32(&e.inFlight, 1)
+	defer atomic.AddInt32(&e.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&e.maxInFlight)
+		if n <= max || atomic.CompareAndSwapInt32(&e.maxInFlight, max, n) {
+			break
+		}
+	}
+	call := atomic.AddInt32(&e.calls, 1)
+
+	input := prompt[strings.Index(prompt, "Input:"):]
+	if e.failOn != "" && strings.Contains(input, e.failOn) {
+		return "", errors.New("provider failed")
+	}
+
+	select {
+	case <-time.After(time.Duration(5*(4-call%4)) * time.Millisecond):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	var out strings.Builder
+	for _, m := range inputLine.FindAllStringSubmatch(input, -1) {
+		fmt.Fprintf(&out, "%s. %s\n", m[1], strings.ToUpper(m[2]))
+	}
+	return out.String(), nil
+}
+
+func numberedText(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("sentence %d", i)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestTranslate_ConcurrentKeepsOrder(t *testing.T) {
+	provider := &EchoProvider{}
+	tr := NewTranslatorWithOptions(provider, Options{Workers: 4})
+
+	var mu sync.Mutex
+	var progress []int
+	results, err := tr.Translate(context.Background(), numberedText(50), "English", 4096, func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if total != 8 {
+			t.Errorf("Expected 8 batches, got %d", total)
+		}
+		progress = append(progress, done)
+	})
+	if err != nil {
+		t.Fatalf("Translate failed: %v", err)
+	}
+
+	if len(results) != 50 {
+		t.Fatalf("Expected 50 results, got %d", len(results))
+	}
+	for i, pair := range results {
+		want := fmt.Sprintf("SENTENCE %d", i)
+		if pair.Translated != want || pair.Original != fmt.Sprintf("sentence %d", i) {
+			t.Fatalf("Result %d out of order: %+v", i, pair)
+		}
+	}
+
+	if provider.maxInFlight < 2 || provider.maxInFlight > 4 {
+		t.Errorf("Expected between 2 and 4 parallel requests, got %d", provider.maxInFlight)
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i] < progress[i-1] {
+			t.Fatalf("Progress went backwards: %v", progress)
+		}
+	}
+	if progress[0] != 0 || progress[len(progress)-1] != 8 {
+		t.Errorf("Expected progress from 0 to 8, got %v", progress)
+	}
+}
+
+func TestTranslate_ErrorCancelsInFlight(t *testing.T) {
+	provider := &EchoProvider{failOn: "sentence 7"}
+	tr := NewTranslatorWithOptions(provider, Options{Workers: 3})
+
+	_, err := tr.Translate(context.Background(), numberedText(200), "English", 4096, nil)
+	if err == nil || !strings.Contains(err.Error(), "provider failed") {
+		t.Fatalf("Expected provider error, got %v", err)
+	}
+	if calls := atomic.LoadInt32(&provider.calls); calls >= 29 {
+		t.Errorf("Expected remaining batches to be skipped, got %d calls", calls)
+	}
+}
+
+func TestTranslate_ContextCancel(t *testing.T) {
+	provider := &EchoProvider{}
+	tr := NewTranslatorWithOptions(provider, Options{Workers: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var once sync.Once
+	_, err := tr.Translate(ctx, numberedText(200), "English", 4096, func(done, total int) {
+		if done >= 2 {
+			once.Do(cancel)
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestTranslate_RateLimit(t *testing.T) {
+	tr := NewTranslatorWithOptions(&MockProvider{Response: "1. a"}, Options{Workers: 4, RateLimit: 1200}) // one request per 50ms
+
+	start := time.Now()
+	if _, err := tr.Translate(context.Background(), "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\no\np\nq\nr\ns\nt\nu", "English", 4096, nil); err != nil {
+		t.Fatalf("Translate failed: %v", err)
+	}
+	// 3 batches: the first starts immediately, the next two are spaced 50ms apart
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected rate limiting to space requests, finished in %v", elapsed)
+	}
+}
+
+func TestParseNumberedOutput_Alignment(t *testing.T) {
+	tr := NewTranslator(&MockProvider{})
+	tests := []struct {
+		name     string
+		output   string
+		expected int
+		want     []string
+		wantErr  bool
+	}{
+		{"aligned", "1. a\n2. b\n3. c", 3, []string{"a", "b", "c"}, false},
+		{"intro text and wrapped line", "Here you go:\n1. a\n2. b\ncontinued\n3) c", 3, []string{"a", "b continued", "c"}, false},
+		{"merged lines", "1. a\n2. b and c", 3, nil, true},
+		{"skipped number", "1. a\n3. c", 3, nil, true},
+		{"out of order", "2. b\n1. a", 2, nil, true},
+		{"empty entry", "1. a\n2.", 2, nil, true},
+		{"single unnumbered", "just the translation", 1, []string{"just the translation"}, false},
+		{"unnumbered batch", "a\nb", 2, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tr.parseNumberedOutput(tt.output, tt.expected)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// MergingProvider merges the last two lines of any batch of three or more, and
+// silently skips inputs containing "untranslatable".
+type MergingProvider struct {
+	MockProvider
+	mu      sync.Mutex
+	batches []int
+}
+
+func (m *MergingProvider) Chat(ctx context.Context, prompt string, opts map[string]interface{}, cb func(string)) (string, error) {
+	lines := inputLine.FindAllStringSubmatch(prompt[strings.Index(prompt, "Input:"):], -1)
+	m.mu.Lock()
+	m.batches = append(m.batches, len(lines))
+	m.mu.Unlock()
+
+	if len(lines) == 1 && strings.Contains(lines[0][2], "untranslatable") {
+		return "\n", nil
+	}
+	var out strings.Builder
+	n := 0
+	for i, l := range lines {
+		if len(lines) > 2 && i == len(lines)-1 {
+			break // merged into the previous line
+		}
+		if strings.Contains(l[2], "untranslatable") {
+			continue
+		}
+		n++
+		fmt.Fprintf(&out, "%d. T(%s)\n", n, l[2])
+	}
+	return out.String(), nil
+}
+
+func TestTranslate_RetriesMisalignedBatches(t *testing.T) {
+	provider := &MergingProvider{}
+	tr := NewTranslator(provider)
+
+	input := "s1\ns2\ns3\ns4\nuntranslatable\ns6\ns7"
+	results, err := tr.Translate(context.Background(), input, "English", 4096, nil)
+	if err != nil {
+		t.Fatalf("Translate failed: %v", err)
+	}
+	if len(results) != 7 {
+		t.Fatalf("Expected 7 results, got %d", len(results))
+	}
+	for i, pair := range results {
+		if pair.Original == "untranslatable" {
+			if pair.Status != StatusMissing || pair.Translated != missingTranslation {
+				t.Errorf("Expected missing placeholder for %q, got %+v", pair.Original, pair)
+			}
+			continue
+		}
+		if pair.Translated != "T("+pair.Original+")" {
+			t.Errorf("Result %d misaligned: %+v", i, pair)
+		}
+		if pair.Status != StatusRetried {
+			t.Errorf("Expected status retried for %q, got %q", pair.Original, pair.Status)
+		}
+	}
+	if provider.batches[0] != 7 || provider.batches[len(provider.batches)-1] > 2 {
+		t.Errorf("Expected shrinking batch sizes, got %v", provider.batches)
+	}
+
+	q := Summarize(results)
+	if q.Total != 7 || q.Aligned != 0 || q.Retried != 6 || q.Missing != 1 {
+		t.Errorf("Unexpected quality: %+v", q)
+	}
+}
+
+// timedProvider records when each request reaches the wrapped provider.
+type timedProvider struct {
+	MergingProvider
+	mu     sync.Mutex
+	starts []time.Time
+}
+
+func (p *timedProvider) Chat(ctx context.Context, prompt string, opts map[string]interface{}, cb func(string)) (string, error) {
+	p.mu.Lock()
+	p.starts = append(p.starts, time.Now())
+	p.mu.Unlock()
+	return p.MergingProvider.Chat(ctx, prompt, opts, cb)
+}
+
+func TestTranslate_RateLimitCoversRetries(t *testing.T) {
+	provider := &timedProvider{}
+	tr := NewTranslatorWithOptions(provider, Options{Workers: 2, RateLimit: 1200}) // one request per 50ms
+
+	if _, err := tr.Translate(context.Background(), "s1\ns2\ns3\ns4\ns5\ns6\ns7\ns8\ns9\ns10", "English", 4096, nil); err != nil {
+		t.Fatalf("Translate failed: %v", err)
+	}
+	if len(provider.starts) <= 2 {
+		t.Fatalf("Expected misaligned batches to be retried, got %d requests", len(provider.starts))
+	}
+	for i := 1; i < len(provider.starts); i++ {
+		if gap := provider.starts[i].Sub(provider.starts[i-1]); gap < 40*time.Millisecond {
+			t.Errorf("Request %d started %v after the previous one, want at least 50ms", i, gap)
+		}
+	}
+}
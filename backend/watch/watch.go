@@ -0,0 +1,272 @@
+package watch
+
+import (
+	"Varys/backend/document"
+	"Varys/backend/service"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Subfolders of the watched folder that processed files are moved into, each
+// with a .log file of its task.
+const (
+	ArchiveDir = "archive"
+	ErrorDir   = "error"
+)
+
+// stuckFile lists, in the watched folder, files that were processed but could
+// not be moved away, so a restarted watcher does not process them again.
+const stuckFile = ".varys-stuck.json"
+
+// DefaultInterval is how often the folder is checked. A file is processed once
+// its size and modification time did not change for one interval.
+const DefaultInterval = 5 * time.Second
+
+// Media files recognized by extension; documents are recognized by document.DetectName.
+var mediaExtensions = map[string]bool{
+	".mp3": true, ".m4a": true, ".aac": true, ".wav": true, ".flac": true, ".ogg": true, ".opus": true,
+	".wma": true, ".amr": true, ".3gp": true,
+	".mp4": true, ".m4v": true, ".mov": true, ".mkv": true, ".webm": true, ".avi": true,
+}
+
+// Supported reports whether the file name is a media file or document the
+// pipeline can ingest.
+func Supported(name string) bool {
+	return mediaExtensions[strings.ToLower(filepath.Ext(name))] || document.DetectName(name) != ""
+}
+
+// Watcher ingests files dropped into a folder, such as the one a voice
+// recorder syncs to. Files leave the folder once handled, so a restarted
+// watcher does not see them again.
+type Watcher struct {
+	Dir       string
+	Processor service.Processor
+	Options   service.Options
+	Logger    service.EventLogger
+	Interval  time.Duration
+
+	pending map[string]fileState // files seen by the last scan, not yet settled
+	stuck   map[string]fileState // processed but could not be moved away (see stuckFile)
+}
+
+type fileState struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// New returns a watcher for dir that hands settled files to p.
+func New(dir string, p service.Processor, opts service.Options, logger service.EventLogger) *Watcher {
+	return &Watcher{
+		Dir:       dir,
+		Processor: p,
+		Options:   opts,
+		Logger:    logger,
+		Interval:  DefaultInterval,
+		pending:   map[string]fileState{},
+		stuck:     loadStuck(dir),
+	}
+}
+
+// Run checks the folder every Interval until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) error {
+	if w.Interval <= 0 {
+		return fmt.Errorf("interval must be positive, got %s", w.Interval)
+	}
+	if st, err := os.Stat(w.Dir); err != nil || !st.IsDir() {
+		return fmt.Errorf("not a folder: %s", w.Dir)
+	}
+	for _, sub := range []string{ArchiveDir, ErrorDir} {
+		if err := os.MkdirAll(filepath.Join(w.Dir, sub), 0755); err != nil {
+			return err
+		}
+	}
+	w.Logger.Log(fmt.Sprintf("Watching %s for new files...", w.Dir))
+	for {
+		if err := w.Scan(ctx); err != nil {
+			w.Logger.Log(fmt.Sprintf("Failed to read folder: %v", err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.Interval):
+		}
+	}
+}
+
+// Scan checks the folder once and processes, one by one, the files that did
+// not change since the previous scan. Subfolders, hidden files and files of
+// other types are ignored.
+func (w *Watcher) Scan(ctx context.Context) error {
+	entries, err := os.ReadDir(w.Dir)
+	if err != nil {
+		return err
+	}
+	present := map[string]bool{}
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || strings.HasPrefix(name, ".") || !Supported(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		present[name] = true
+		state := fileState{Size: info.Size(), ModTime: info.ModTime()}
+		if stuck, ok := w.stuck[name]; ok && stuck.Size == state.Size && stuck.ModTime.Equal(state.ModTime) {
+			continue
+		}
+		prev, seen := w.pending[name]
+		w.pending[name] = state
+		if !seen || !prev.ModTime.Equal(state.ModTime) || prev.Size != state.Size || state.Size == 0 {
+			// New or still being written
+			continue
+		}
+		delete(w.pending, name)
+		w.process(ctx, name, state)
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+	for name := range w.pending {
+		if !present[name] {
+			delete(w.pending, name)
+		}
+	}
+	pruned := false
+	for name := range w.stuck {
+		if !present[name] {
+			// Removed by hand as asked
+			delete(w.stuck, name)
+			pruned = true
+		}
+	}
+	if pruned {
+		saveStuck(w.Dir, w.stuck)
+	}
+	return nil
+}
+
+// process runs the pipeline on one file and moves it to the archive or error
+// folder with its log. Interrupted tasks leave the file in place for the next run.
+func (w *Watcher) process(ctx context.Context, name string, state fileState) {
+	path := filepath.Join(w.Dir, name)
+	w.Logger.Log("New file: " + name)
+	rec := &recorder{next: w.Logger}
+	rec.Log("Source: " + path)
+
+	result, err := w.Processor.ProcessTask(ctx, path, w.Options, rec)
+	if ctx.Err() != nil {
+		w.Logger.Log(fmt.Sprintf("Interrupted: %s will be processed on the next run.", name))
+		return
+	}
+
+	dest := ArchiveDir
+	switch {
+	case err != nil:
+		rec.Log(fmt.Sprintf("Failed: %v", err))
+		dest = ErrorDir
+	case result.Skipped:
+		rec.Log(fmt.Sprintf("Already in the vault: %s", result.NotePath))
+	default:
+		rec.Log(fmt.Sprintf("Note saved to: %s", result.NotePath))
+	}
+
+	moved, mErr := moveInto(path, filepath.Join(w.Dir, dest))
+	if mErr != nil {
+		// Leaving the file would process it again on every scan
+		w.Logger.Log(fmt.Sprintf("Failed to move %s: %v. Remove it from the folder by hand.", name, mErr))
+		w.stuck[name] = state
+		if err := saveStuck(w.Dir, w.stuck); err != nil {
+			w.Logger.Log(fmt.Sprintf("Failed to remember %s as processed: %v", name, err))
+		}
+		return
+	}
+	logPath := strings.TrimSuffix(moved, filepath.Ext(moved)) + ".log"
+	if err := os.WriteFile(logPath, []byte(rec.String()), 0644); err != nil {
+		w.Logger.Log(fmt.Sprintf("Failed to write log: %v", err))
+	}
+	w.Logger.Log(fmt.Sprintf("Moved %s to %s/.", name, dest))
+}
+
+// loadStuck reads the stuck files of dir. Entries only match a file with the
+// same size and modification time, so a new file under the name is processed.
+func loadStuck(dir string) map[string]fileState {
+	stuck := map[string]fileState{}
+	if data, err := os.ReadFile(filepath.Join(dir, stuckFile)); err == nil {
+		json.Unmarshal(data, &stuck)
+	}
+	return stuck
+}
+
+func saveStuck(dir string, stuck map[string]fileState) error {
+	data, err := json.MarshalIndent(stuck, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, stuckFile), data, 0644)
+}
+
+// moveInto moves the file at path into dir, numbering the name if a file of
+// that name is already there, and returns the new path.
+func moveInto(path, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	dest := filepath.Join(dir, base)
+	for i := 2; ; i++ {
+		_, errFile := os.Stat(dest)
+		_, errLog := os.Stat(strings.TrimSuffix(dest, ext) + ".log")
+		if os.IsNotExist(errFile) && os.IsNotExist(errLog) {
+			break
+		}
+		dest = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, i, ext))
+	}
+	return dest, os.Rename(path, dest)
+}
+
+// recorder passes events on and keeps the log lines of one task.
+type recorder struct {
+	next service.EventLogger
+	mu   sync.Mutex
+	b    strings.Builder
+}
+
+func (r *recorder) Log(msg string) {
+	r.write(msg)
+	r.next.Log(msg)
+}
+
+func (r *recorder) Progress(percent float64) {
+	r.next.Progress(percent)
+}
+
+func (r *recorder) AnalysisChunk(token string) {
+	r.next.AnalysisChunk(token)
+}
+
+func (r *recorder) Error(err error) {
+	r.write(fmt.Sprintf("Error: %v", err))
+	r.next.Error(err)
+}
+
+func (r *recorder) write(msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(&r.b, "[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), msg)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.b.String()
+}
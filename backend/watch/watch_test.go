@@ -0,0 +1,172 @@
+package watch
+
+import (
+	"Varys/backend/service"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Log(string)           {}
+func (discardLogger) Progress(float64)     {}
+func (discardLogger) AnalysisChunk(string) {}
+func (discardLogger) Error(error)          {}
+
+// fakeProcessor records the files it was given and fails those named "bad*".
+type fakeProcessor struct {
+	calls  []string
+	cancel context.CancelFunc // cancels the run during the task, if set
+}
+
+func (p *fakeProcessor) ProcessTask(ctx context.Context, url string, opts service.Options, logger service.EventLogger) (*service.TaskResult, error) {
+	p.calls = append(p.calls, filepath.Base(url))
+	logger.Log("Transcribing...")
+	if p.cancel != nil {
+		p.cancel()
+		return nil, ctx.Err()
+	}
+	if strings.HasPrefix(filepath.Base(url), "bad") {
+		return nil, errors.New("transcription failed")
+	}
+	return &service.TaskResult{NotePath: "/vault/Note.md"}, nil
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	p := &fakeProcessor{}
+	w := New(dir, p, service.Options{}, discardLogger{})
+	ctx := context.Background()
+
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("memo.m4a", "audio")
+	write("bad.mp3", "audio")
+	write("paper.pdf", "%PDF-1.4")
+	write("notes.json", "{}")   // not media or a document
+	write(".memo.m4a.part", "") // hidden
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+
+	// New files wait one scan to settle
+	w.Scan(ctx)
+	if len(p.calls) != 0 {
+		t.Fatalf("processed unsettled files: %v", p.calls)
+	}
+	// A file that is still growing waits again
+	write("paper.pdf", "%PDF-1.4 more")
+	w.Scan(ctx)
+	if strings.Join(p.calls, ",") != "bad.mp3,memo.m4a" {
+		t.Fatalf("calls = %v", p.calls)
+	}
+	w.Scan(ctx)
+	if strings.Join(p.calls, ",") != "bad.mp3,memo.m4a,paper.pdf" {
+		t.Fatalf("calls = %v", p.calls)
+	}
+
+	for _, path := range []string{"archive/memo.m4a", "archive/memo.log", "archive/paper.pdf", "error/bad.mp3", "error/bad.log", "notes.json"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("%s missing: %v", path, err)
+		}
+	}
+	log, _ := os.ReadFile(filepath.Join(dir, "error", "bad.log"))
+	if !strings.Contains(string(log), "Transcribing...") || !strings.Contains(string(log), "Failed: transcription failed") {
+		t.Errorf("error log = %q", log)
+	}
+	log, _ = os.ReadFile(filepath.Join(dir, "archive", "memo.log"))
+	if !strings.Contains(string(log), "Note saved to: /vault/Note.md") {
+		t.Errorf("archive log = %q", log)
+	}
+
+	// Handled files are gone: a restarted watcher finds nothing to do
+	w = New(dir, p, service.Options{}, discardLogger{})
+	w.Scan(ctx)
+	w.Scan(ctx)
+	if len(p.calls) != 3 {
+		t.Errorf("calls after restart = %v", p.calls)
+	}
+
+	// A new recording under a used name does not overwrite the archived one
+	write("memo.m4a", "second recording")
+	w.Scan(ctx)
+	w.Scan(ctx)
+	for _, path := range []string{"archive/memo (2).m4a", "archive/memo (2).log"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("%s missing: %v", path, err)
+		}
+	}
+}
+
+func TestScan_Interrupted(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "memo.mp3"), []byte("audio"), 0644)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &fakeProcessor{cancel: cancel}
+	w := New(dir, p, service.Options{}, discardLogger{})
+
+	w.Scan(ctx)
+	w.Scan(ctx)
+	if len(p.calls) != 1 {
+		t.Fatalf("calls = %v", p.calls)
+	}
+	// The file stays for the next run
+	if _, err := os.Stat(filepath.Join(dir, "memo.mp3")); err != nil {
+		t.Errorf("interrupted file was moved: %v", err)
+	}
+}
+
+func TestRun_NotAFolder(t *testing.T) {
+	w := New(filepath.Join(t.TempDir(), "missing"), &fakeProcessor{}, service.Options{}, discardLogger{})
+	if err := w.Run(context.Background()); err == nil {
+		t.Error("expected an error for a missing folder")
+	}
+}
+
+func TestScan_StuckFileSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "memo.mp3"), []byte("audio"), 0644)
+	// A file where the archive folder should be makes the move fail
+	os.WriteFile(filepath.Join(dir, ArchiveDir), nil, 0644)
+	p := &fakeProcessor{}
+	ctx := context.Background()
+
+	w := New(dir, p, service.Options{}, discardLogger{})
+	w.Scan(ctx)
+	w.Scan(ctx)
+	if len(p.calls) != 1 {
+		t.Fatalf("calls = %v", p.calls)
+	}
+
+	w = New(dir, p, service.Options{}, discardLogger{})
+	w.Scan(ctx)
+	w.Scan(ctx)
+	if len(p.calls) != 1 {
+		t.Errorf("stuck file processed again after a restart: %v", p.calls)
+	}
+
+	// A new file under the same name is processed
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "memo.mp3"), later, later)
+	w.Scan(ctx)
+	w.Scan(ctx)
+	if len(p.calls) != 2 {
+		t.Errorf("changed file was not processed: %v", p.calls)
+	}
+}
+
+func TestRun_InvalidInterval(t *testing.T) {
+	w := New(t.TempDir(), &fakeProcessor{}, service.Options{}, discardLogger{})
+	w.Interval = 0
+	if err := w.Run(context.Background()); err == nil {
+		t.Error("expected an error for a zero interval")
+	}
+}
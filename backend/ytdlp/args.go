@@ -0,0 +1,134 @@
+// Package ytdlp holds the command line options shared by every yt-dlp call.
+package ytdlp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Cookie sources.
+const (
+	CookiesNone    = "none"    // no cookies; only public media can be fetched
+	CookiesBrowser = "browser" // read cookies from an installed browser (default)
+	CookiesFile    = "file"    // read a Netscape cookies.txt file
+)
+
+// DefaultBrowser is used when the browser source is selected without a name.
+const DefaultBrowser = "chrome"
+
+// Browsers lists the browsers supported by --cookies-from-browser.
+var Browsers = []string{"brave", "chrome", "chromium", "edge", "firefox", "opera", "safari", "vivaldi", "whale"}
+
+// Cookies describes how yt-dlp authenticates against the site.
+type Cookies struct {
+	Source  string // CookiesNone, CookiesBrowser or CookiesFile; empty means CookiesBrowser
+	Browser string // browser name for CookiesBrowser, e.g. "firefox"
+	Profile string // optional browser profile name or path
+	File    string // cookies.txt path for CookiesFile
+}
+
+// Args returns the cookie options for yt-dlp.
+func (c Cookies) Args() []string {
+	switch c.Source {
+	case CookiesNone:
+		return nil
+	case CookiesFile:
+		if c.File == "" {
+			return nil
+		}
+		return []string{"--cookies", c.File}
+	default:
+		spec := c.Browser
+		if spec == "" {
+			spec = DefaultBrowser
+		}
+		if c.Profile != "" {
+			spec += ":" + c.Profile
+		}
+		return []string{"--cookies-from-browser", spec}
+	}
+}
+
+// Validate reports settings yt-dlp would reject.
+func (c Cookies) Validate() error {
+	switch c.Source {
+	case CookiesNone:
+		return nil
+	case CookiesFile:
+		if c.File == "" {
+			return fmt.Errorf("cookies file path is required")
+		}
+		return nil
+	case "", CookiesBrowser:
+		if c.Browser == "" {
+			return nil
+		}
+		for _, b := range Browsers {
+			if strings.EqualFold(b, c.Browser) {
+				return nil
+			}
+		}
+		return fmt.Errorf("unsupported browser %q (use one of %s)", c.Browser, strings.Join(Browsers, ", "))
+	default:
+		return fmt.Errorf("invalid cookies source %q (use none, browser or file)", c.Source)
+	}
+}
+
+// Args builds a yt-dlp argument list: the shared options followed by args.
+func Args(cookies Cookies, args ...string) []string {
+	return append(cookies.Args(), args...)
+}
+
+// BrowserProfileDir returns the directory where the browser keeps its profiles on this
+// system, or "" if it cannot be found. Used by diagnostics only; yt-dlp has its own lookup.
+func BrowserProfileDir(browser string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if browser == "" {
+		browser = DefaultBrowser
+	}
+
+	var candidates []string
+	switch runtime.GOOS {
+	case "darwin":
+		support := filepath.Join(home, "Library", "Application Support")
+		candidates = map[string][]string{
+			"brave":    {filepath.Join(support, "BraveSoftware", "Brave-Browser")},
+			"chrome":   {filepath.Join(support, "Google", "Chrome")},
+			"chromium": {filepath.Join(support, "Chromium")},
+			"edge":     {filepath.Join(support, "Microsoft Edge")},
+			"firefox":  {filepath.Join(support, "Firefox")},
+			"opera":    {filepath.Join(support, "com.operasoftware.Opera")},
+			"safari":   {filepath.Join(home, "Library", "Cookies"), filepath.Join(home, "Library", "Containers", "com.apple.Safari")},
+			"vivaldi":  {filepath.Join(support, "Vivaldi")},
+			"whale":    {filepath.Join(support, "Naver", "Whale")},
+		}[strings.ToLower(browser)]
+	case "linux":
+		config := filepath.Join(home, ".config")
+		candidates = map[string][]string{
+			"brave":    {filepath.Join(config, "BraveSoftware", "Brave-Browser")},
+			"chrome":   {filepath.Join(config, "google-chrome")},
+			"chromium": {filepath.Join(config, "chromium")},
+			"edge":     {filepath.Join(config, "microsoft-edge")},
+			"firefox":  {filepath.Join(home, ".mozilla", "firefox"), filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox")},
+			"opera":    {filepath.Join(config, "opera")},
+			"vivaldi":  {filepath.Join(config, "vivaldi")},
+			"whale":    {filepath.Join(config, "naver-whale")},
+		}[strings.ToLower(browser)]
+	default:
+		// Windows paths vary too much between installs; assume present
+		return browser
+	}
+
+	for _, dir := range candidates {
+		if st, err := os.Stat(dir); err == nil && st.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package ytdlp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCookiesArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		cookies Cookies
+		want    []string
+	}{
+		{"default browser", Cookies{}, []string{"--cookies-from-browser", "chrome"}},
+		{"browser with profile", Cookies{Source: CookiesBrowser, Browser: "firefox", Profile: "work"}, []string{"--cookies-from-browser", "firefox:work"}},
+		{"file", Cookies{Source: CookiesFile, File: "/tmp/cookies.txt"}, []string{"--cookies", "/tmp/cookies.txt"}},
+		{"none", Cookies{Source: CookiesNone, Browser: "chrome"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cookies.Args(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Args() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	got := Args(Cookies{Source: CookiesNone}, "--dump-json", "url")
+	if !reflect.DeepEqual(got, []string{"--dump-json", "url"}) {
+		t.Errorf("Args() = %v", got)
+	}
+}
+
+func TestCookiesValidate(t *testing.T) {
+	tests := []struct {
+		cookies Cookies
+		wantErr bool
+	}{
+		{Cookies{}, false},
+		{Cookies{Source: CookiesBrowser, Browser: "Firefox"}, false},
+		{Cookies{Source: CookiesBrowser, Browser: "netscape"}, true},
+		{Cookies{Source: CookiesFile}, true},
+		{Cookies{Source: CookiesFile, File: "cookies.txt"}, false},
+		{Cookies{Source: CookiesNone}, false},
+		{Cookies{Source: "keychain"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cookies.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.cookies, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"Varys/backend/config"
+	"Varys/backend/feed"
+	"Varys/backend/service"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	feedBackfill int
+	feedMaxItems int
+)
+
+// newFeedCmd builds the "feed" command group: subscriptions to RSS/Atom feeds and
+// podcasts whose new items are ingested by "feed sync".
+func newFeedCmd() *cobra.Command {
+	feedCmd := &cobra.Command{
+		Use:   "feed",
+		Short: "Follow RSS/Atom feeds and podcasts",
+		Long: `Follow RSS/Atom feeds and podcasts. "feed sync" ingests the items published since the last sync:
+podcast episodes are downloaded and transcribed, linked posts are scraped as articles.
+Subscriptions and the items already ingested are kept in feeds.json in the config directory.`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add [feed URL]",
+		Short: "Subscribe to a feed",
+		Long:  `Subscribe to a feed. Items already in the feed are not ingested, except the newest --backfill ones.`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			st := openFeedState()
+			ctx, stop := interruptContext()
+			defer stop()
+			f, err := feed.Fetch(ctx, args[0])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Items are newest first: everything after the backfill counts as seen
+			var seen []string
+			for i, item := range f.Items {
+				if i >= feedBackfill {
+					seen = append(seen, item.GUID)
+				}
+			}
+			if _, err := st.Add(args[0], f.Title, seen); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := st.Save(); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Subscribed to %s (%d items, %d to ingest on the next sync)\n", f.Title, len(f.Items), len(f.Items)-len(seen))
+		},
+	}
+	addCmd.Flags().IntVar(&feedBackfill, "backfill", 0, "Number of existing items, newest first, to ingest on the next sync")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List subscribed feeds",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			st := openFeedState()
+			if len(st.Feeds) == 0 {
+				fmt.Println("No feeds. Subscribe with: varys-cli feed add <URL>")
+				return
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TITLE\tURL\tLAST SYNC")
+			for _, sub := range st.Feeds {
+				synced := "never"
+				if !sub.SyncedAt.IsZero() {
+					synced = sub.SyncedAt.Format("2006-01-02 15:04")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", sub.Title, sub.URL, synced)
+			}
+			w.Flush()
+		},
+	}
+
+	syncCmd := &cobra.Command{
+		Use:   "sync [feed URL]",
+		Short: "Ingest new items of all feeds, or of one feed",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			st := openFeedState()
+			subs := st.Feeds
+			if len(args) == 1 {
+				sub := st.Find(args[0])
+				if sub == nil {
+					fmt.Printf("Error: not subscribed to %s (use feed add)\n", args[0])
+					os.Exit(1)
+				}
+				subs = []*feed.Subscription{sub}
+			}
+			if len(subs) == 0 {
+				fmt.Println("No feeds. Subscribe with: varys-cli feed add <URL>")
+				return
+			}
+
+			dm := loadDependencies()
+			opts := loadOptions(cmd)
+			svc := service.NewCoreService(dm)
+			presenter := &CLIPresenter{}
+			ctx, stop := interruptContext()
+			defer stop()
+
+			failed := false
+			for _, sub := range subs {
+				fmt.Printf("Varys CLI syncing feed: %s\n", sub.URL)
+				result, err := svc.SyncFeed(ctx, st, sub, opts, feedMaxItems, presenter)
+				if err != nil {
+					if ctx.Err() != nil {
+						fmt.Printf("\nSync interrupted.\n")
+						os.Exit(1)
+					}
+					fmt.Printf("\nFeed failed: %v\n", err)
+					failed = true
+					continue
+				}
+				fmt.Printf("\n%s: %d new, %d notes saved, %d failed.\n", result.Title, result.New, len(result.Results), result.Failed)
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+	syncCmd.Flags().IntVar(&feedMaxItems, "max-items", 0, "Maximum number of new items to ingest per feed (0 = all)")
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [feed URL]",
+		Short: "Unsubscribe from a feed",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			st := openFeedState()
+			if !st.Remove(args[0]) {
+				fmt.Printf("Error: not subscribed to %s\n", args[0])
+				os.Exit(1)
+			}
+			if err := st.Save(); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Unsubscribed from %s\n", args[0])
+		},
+	}
+
+	feedCmd.AddCommand(addCmd, listCmd, syncCmd, removeCmd)
+	return feedCmd
+}
+
+func openFeedState() *feed.State {
+	path, err := config.GetFeedStatePath()
+	if err == nil {
+		var st *feed.State
+		if st, err = feed.OpenState(path); err == nil {
+			return st
+		}
+	}
+	fmt.Printf("Feed State Error: %v\n", err)
+	os.Exit(1)
+	return nil
+}
@@ -1,16 +1,22 @@
 package main
 
 import (
+	"Varys/backend/analyzer"
 	"Varys/backend/config"
 	"Varys/backend/dependency"
+	"Varys/backend/downloader"
 	"Varys/backend/search"
 	"Varys/backend/service"
+	"Varys/backend/storage"
+	"Varys/backend/ytdlp"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"runtime"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -43,24 +49,79 @@ var (
 	openAfterComplete bool
 	aiProvider        string
 	model             string
+	baseURL           string
+	ollamaHost        string
 	translationMod    string
+	translationWorker int
+	glossaryPath      string
+	subtitleSource    string
+	cookiesFlag       string
 	targetLang        string
 	contextSize       int
+	analysisMode      string
 	vaultPath         string
 	searchLimit       int
 	searchProvider    string
 	tavilyKey         string
+	playlist          bool
+	playlistAfter     string
+	playlistBefore    string
+	maxItems          int
+	titleMatch        string
+	fromStage         string
+	onDuplicate       string
+	noteTemplate      string
 )
 
 func runTask(url string, cmd *cobra.Command) {
 	// 1. Init Dependencies
+	dm := loadDependencies()
+
+	// 2. Load Config and merge CLI flags
+	opts := loadOptions(cmd)
+
+	// 3. Init Service
+	svc := service.NewCoreService(dm)
+	presenter := &CLIPresenter{}
+
+	if playlist || downloader.IsPlaylistURL(url) {
+		runPlaylist(url, svc, opts, presenter)
+		return
+	}
+
+	fmt.Printf("Varys CLI starting task: %s\n", url)
+	ctx, stop := interruptContext()
+	defer stop()
+	result, err := svc.ProcessTask(ctx, url, opts, presenter)
+	if err != nil {
+		fmt.Printf("\nTask failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	if result.Skipped {
+		fmt.Printf("\nAlready in the vault: %s\n", result.NotePath)
+	} else {
+		fmt.Printf("\nSuccess! Note saved to: %s\n", result.NotePath)
+	}
+
+	if openAfterComplete {
+		fmt.Printf("Opening note...\n")
+		openFile(result.NotePath)
+	}
+}
+
+func loadDependencies() *dependency.Manager {
 	dm, err := dependency.NewManager()
 	if err != nil {
 		fmt.Printf("Dependency Error: %v\n", err)
 		os.Exit(1)
 	}
+	return dm
+}
 
-	// 2. Load Config
+// loadOptions reads the saved configuration and applies the flags given on the
+// command line.
+func loadOptions(cmd *cobra.Command) service.Options {
 	cm, _ := config.NewManager()
 	cfg, err := cm.Load()
 	if err != nil {
@@ -68,20 +129,8 @@ func runTask(url string, cmd *cobra.Command) {
 		os.Exit(1)
 	}
 
-	// 3. Merge CLI Flags with Config
-	opts := service.Options{
-		AudioOnly:      !videoOnly,
-		ModelPath:      cfg.ModelPath,
-		LLMModel:       cfg.LLMModel,
-		TranslationMod: cfg.TranslationModel,
-		AIProvider:     cfg.AIProvider,
-		OpenAIKey:      cfg.OpenAIKey,
-		OpenAIModel:    cfg.OpenAIModel,
-		TargetLanguage: cfg.TargetLanguage,
-		ContextSize:    cfg.ContextSize,
-		CustomPrompt:   cfg.CustomPrompt,
-		VaultPath:      cfg.VaultPath,
-	}
+	opts := service.OptionsFromConfig(cfg)
+	opts.AudioOnly = !videoOnly
 
 	// Override if flags are provided
 	if v, err := cmd.Flags().GetBool("video"); err == nil && cmd.Flags().Changed("video") {
@@ -95,43 +144,122 @@ func runTask(url string, cmd *cobra.Command) {
 	if cmd.Flags().Changed("model") {
 		if opts.AIProvider == "openai" {
 			opts.OpenAIModel = model
+		} else if analyzer.IsOpenAICompatible(opts.AIProvider) {
+			opts.CompatibleModel = model
 		} else {
 			opts.LLMModel = model
 		}
 	}
+	if cmd.Flags().Changed("base-url") {
+		opts.CompatibleBaseURL = baseURL
+	}
+	if cmd.Flags().Changed("ollama-host") {
+		opts.Ollama.Host = ollamaHost
+	}
 	if cmd.Flags().Changed("translation-model") {
 		opts.TranslationMod = translationMod
 	}
+	if cmd.Flags().Changed("translation-workers") {
+		opts.TranslationWorkers = translationWorker
+	}
+	if cmd.Flags().Changed("cookies") {
+		opts.Cookies = parseCookiesFlag(cookiesFlag)
+	}
+	if cmd.Flags().Changed("subtitles") {
+		opts.SubtitleSource = subtitleSource
+	}
+	if cmd.Flags().Changed("glossary") {
+		opts.GlossaryPath = glossaryPath
+	}
 	if cmd.Flags().Changed("target-lang") {
 		opts.TargetLanguage = targetLang
 	}
 	if cmd.Flags().Changed("context-size") {
 		opts.ContextSize = contextSize
 	}
+	if cmd.Flags().Changed("analysis-mode") {
+		switch analysisMode {
+		case analyzer.AnalysisModeAuto, analyzer.AnalysisModeSingle, analyzer.AnalysisModeChunked:
+		default:
+			fmt.Printf("Error: invalid analysis mode %q (use auto, single or chunked)\n", analysisMode)
+			os.Exit(1)
+		}
+		opts.AnalysisMode = analysisMode
+	}
 	if cmd.Flags().Changed("vault") {
 		opts.VaultPath = vaultPath
 	}
+	if cmd.Flags().Changed("from-stage") {
+		stage, err := service.ParseStage(fromStage)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.FromStage = stage
+	}
+	if cmd.Flags().Changed("on-duplicate") {
+		mode, err := storage.ParseDuplicateMode(onDuplicate)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts.OnDuplicate = mode
+	}
+	if cmd.Flags().Changed("template") {
+		opts.NoteTemplate = noteTemplate
+	}
 
 	if opts.ContextSize == 0 {
 		opts.ContextSize = 8192
 	}
+	if _, err := opts.LoadNoteTemplate(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return opts
+}
 
-	// 4. Init Service
-	svc := service.NewCoreService(dm)
-	presenter := &CLIPresenter{}
+// interruptContext is cancelled on Ctrl+C or SIGTERM. External tools run in their own
+// process group and do not see the terminal's SIGINT, so the context has to stop them.
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
 
-	fmt.Printf("Varys CLI starting task: %s\n", url)
-	result, err := svc.ProcessTask(context.Background(), url, opts, presenter)
+// parseCookiesFlag maps --cookies to a cookie source: "none", a cookies.txt path,
+// or a browser name with an optional profile ("firefox:work").
+func parseCookiesFlag(value string) ytdlp.Cookies {
+	if value == ytdlp.CookiesNone {
+		return ytdlp.Cookies{Source: ytdlp.CookiesNone}
+	}
+	if st, err := os.Stat(value); err == nil && !st.IsDir() {
+		return ytdlp.Cookies{Source: ytdlp.CookiesFile, File: value}
+	}
+	browser, profile, _ := strings.Cut(value, ":")
+	return ytdlp.Cookies{Source: ytdlp.CookiesBrowser, Browser: browser, Profile: profile}
+}
+
+// runPlaylist processes every entry of a playlist or channel that passes the filter flags.
+func runPlaylist(url string, svc *service.CoreService, opts service.Options, presenter *CLIPresenter) {
+	filter, err := downloader.ParsePlaylistFilter(playlistAfter, playlistBefore, maxItems, titleMatch)
 	if err != nil {
-		fmt.Printf("\nTask failed: %v\n", err)
+		fmt.Printf("Invalid playlist filter: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Varys CLI starting playlist: %s\n", url)
+	ctx, stop := interruptContext()
+	defer stop()
+	result, err := svc.ProcessPlaylist(ctx, url, opts, filter, presenter)
+	if err != nil {
+		fmt.Printf("\nPlaylist failed: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("\nSuccess! Note saved to: %s\n", result.NotePath)
+	fmt.Printf("\nDone! %d notes saved, %d failed. Index: %s\n", len(result.Results), result.Failed, result.IndexPath)
 
 	if openAfterComplete {
-		fmt.Printf("Opening note...\n")
-		openFile(result.NotePath)
+		fmt.Printf("Opening index...\n")
+		openFile(result.IndexPath)
 	}
 }
 
@@ -185,7 +313,11 @@ An interactive TUI will open to show the results:
 				cm.Save(cfg)
 			}
 
-			sm := search.NewSearchManager(cfg.TavilyKey)
+			cookies := cfg.YtDlpCookies()
+			if cmd.Flags().Changed("cookies") {
+				cookies = parseCookiesFlag(cookiesFlag)
+			}
+			sm := search.NewSearchManager(cfg.TavilyKey, cookies)
 			p, err := sm.GetProvider(searchProvider)
 			if err != nil {
 				fmt.Printf("Error: %v\n", err)
@@ -215,21 +347,49 @@ An interactive TUI will open to show the results:
 	rootCmd.PersistentFlags().BoolVarP(&openAfterComplete, "open", "o", false, "Open the generated note after completion")
 	rootCmd.PersistentFlags().StringVarP(&aiProvider, "ai-provider", "p", "", "AI provider to use (ollama or Cloud LLMs)")
 	rootCmd.PersistentFlags().StringVar(&model, "model", "", "LLM model name")
+	rootCmd.PersistentFlags().StringVar(&baseURL, "base-url", "", "Base URL of an OpenAI-compatible server (e.g. http://localhost:8080/v1)")
+	rootCmd.PersistentFlags().StringVar(&ollamaHost, "ollama-host", "", "Ollama server address (e.g. http://192.168.1.20:11434)")
 	rootCmd.PersistentFlags().StringVar(&translationMod, "translation-model", "", "Model used for translation")
+	rootCmd.PersistentFlags().IntVar(&translationWorker, "translation-workers", 0, "Number of translation batches sent in parallel")
+	rootCmd.PersistentFlags().StringVar(&cookiesFlag, "cookies", "", "yt-dlp cookies: a browser name (chrome, firefox:profile), a cookies.txt path, or none")
+	rootCmd.PersistentFlags().StringVar(&subtitleSource, "subtitles", "", "Transcript source: manual (platform subtitles), auto (also auto-generated captions) or whisper")
+	rootCmd.PersistentFlags().StringVar(&glossaryPath, "glossary", "", "CSV or YAML glossary of terms with fixed translations")
 	rootCmd.PersistentFlags().StringVar(&targetLang, "target-lang", "", "Target language for analysis and translation")
 	rootCmd.PersistentFlags().IntVar(&contextSize, "context-size", 0, "Context window size in tokens")
+	rootCmd.PersistentFlags().StringVar(&analysisMode, "analysis-mode", "", "Analysis mode for long texts (auto, single, chunked)")
 	rootCmd.PersistentFlags().StringVar(&vaultPath, "vault", "", "Override path to Obsidian Vault")
+	rootCmd.PersistentFlags().StringVar(&noteTemplate, "template", "", "Note template: a built-in name (default, english, minimal) or a template file in the vault or config dir")
+	rootCmd.PersistentFlags().StringVar(&onDuplicate, "on-duplicate", "", "What to do with sources already in the vault: update (generated sections only), skip or version (new copy)")
 	rootCmd.PersistentFlags().StringVar(&tavilyKey, "tavily-key", "", "Tavily API Key for web search")
 
+	// Playlist Flags
+	rootCmd.Flags().BoolVar(&playlist, "playlist", false, "Treat the URL as a playlist or channel (detected automatically for YouTube and Bilibili)")
+	rootCmd.Flags().StringVar(&playlistAfter, "after", "", "Only process playlist entries uploaded on or after this date (YYYY-MM-DD)")
+	rootCmd.Flags().StringVar(&playlistBefore, "before", "", "Only process playlist entries uploaded on or before this date (YYYY-MM-DD)")
+	rootCmd.Flags().IntVar(&maxItems, "max-items", 0, "Maximum number of playlist entries to process (0 = all)")
+	rootCmd.Flags().StringVar(&titleMatch, "title-match", "", "Only process playlist entries whose title matches this regular expression")
+	rootCmd.Flags().StringVar(&fromStage, "from-stage", "", "Rerun the task from this stage instead of resuming (metadata, media, transcript, translation, analysis)")
+
 	// Search Flags
 	searchCmd.Flags().IntVarP(&searchLimit, "limit", "l", 5, "Number of search results to fetch")
 	searchCmd.Flags().StringVarP(&searchProvider, "provider", "s", "yt-dlp", "Search provider (yt-dlp, tavily)")
 
 	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(newFeedCmd())
+	rootCmd.AddCommand(newWatchCmd())
+	rootCmd.AddCommand(newServeCmd())
 
 	// Completion
 	rootCmd.RegisterFlagCompletionFunc("ai-provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"ollama", "openai"}, cobra.ShellCompDirectiveNoFileComp
+		return []string{"ollama", "openai", "openai-compatible", "llamacpp"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	rootCmd.RegisterFlagCompletionFunc("analysis-mode", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"auto", "single", "chunked"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	rootCmd.RegisterFlagCompletionFunc("subtitles", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"manual", "auto", "whisper"}, cobra.ShellCompDirectiveNoFileComp
 	})
 
 	rootCmd.RegisterFlagCompletionFunc("target-lang", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -59,6 +60,7 @@ type SearchModel struct {
 	loading    bool
 	choice     []search.SearchResult
 	query      string
+	ctx        context.Context
 	provider   search.SearchProvider
 	opts       search.SearchOptions
 	err        error
@@ -68,7 +70,7 @@ type SearchModel struct {
 	lastHeight int
 }
 
-func NewSearchModel(query string, provider search.SearchProvider, opts search.SearchOptions) SearchModel {
+func NewSearchModel(ctx context.Context, query string, provider search.SearchProvider, opts search.SearchOptions) SearchModel {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#9D00FF"))
@@ -86,6 +88,7 @@ func NewSearchModel(query string, provider search.SearchProvider, opts search.Se
 		spinner:  s,
 		selected: make(map[int]bool),
 		query:    query,
+		ctx:      ctx,
 		provider: provider,
 		opts:     opts,
 		loading:  true,
@@ -97,7 +100,7 @@ func (m SearchModel) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
 		func() tea.Msg {
-			res, err := m.provider.Search(m.query, m.opts)
+			res, err := m.provider.Search(m.ctx, m.query, m.opts)
 			return SearchDoneMsg{Results: res, Err: err}
 		},
 	)
@@ -268,7 +271,11 @@ func RunSearchTUI(query string, provider search.SearchProvider, opts search.Sear
 		}
 	}
 
-	m := NewSearchModel(query, provider, opts)
+	// Quitting while results are loading cancels the search and stops yt-dlp
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewSearchModel(ctx, query, provider, opts)
 	p = tea.NewProgram(m, tea.WithAltScreen())
 
 	finalModel, err := p.Run()
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"Varys/backend/config"
+	"Varys/backend/queue"
+	"Varys/backend/server"
+	"Varys/backend/service"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr    string
+	serveToken   string
+	serveWorkers int
+)
+
+// newServeCmd builds the "serve" command, which exposes the pipeline over a local REST API.
+func newServeCmd() *cobra.Command {
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run a local REST API to submit URLs and follow their jobs",
+		Long: `Run a local REST API so browser extensions, launcher scripts and other tools can submit URLs
+without the desktop app. Jobs run on a queue kept in api-jobs.json in the config directory, and their
+logs and analysis tokens can be followed as server-sent events.
+
+Only localhost addresses are accepted. Every request needs "Authorization: Bearer TOKEN"; the token
+is taken from --token, then VARYS_API_TOKEN, then the api-token file in the config directory,
+which is created on first run.
+
+  POST /api/jobs               {"url": "https://...", "audio_only": true, "playlist": {"max_items": 5}}
+  GET  /api/jobs
+  GET  /api/jobs/{id}
+  POST /api/jobs/{id}/cancel
+  GET  /api/jobs/{id}/events   (server-sent events; ?token= works for EventSource)`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := server.CheckLoopback(serveAddr); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			token, tokenSource := serveToken, "--token"
+			if token == "" {
+				token, tokenSource = os.Getenv("VARYS_API_TOKEN"), "VARYS_API_TOKEN"
+			}
+			if token == "" {
+				path, err := config.GetAPITokenPath()
+				if err == nil {
+					token, err = server.LoadToken(path)
+				}
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				tokenSource = path
+			}
+
+			dm := loadDependencies()
+			opts := loadOptions(cmd)
+			workers := serveWorkers
+			if !cmd.Flags().Changed("workers") {
+				cm, _ := config.NewManager()
+				if cfg, err := cm.Load(); err == nil {
+					workers = cfg.QueueWorkers
+				}
+			}
+			jobsPath := ""
+			if dir, err := config.GetConfigDir(); err == nil {
+				jobsPath = filepath.Join(dir, "api-jobs.json")
+			}
+
+			// Bind first: restored jobs must not start when the port is taken
+			ln, err := server.Listen(serveAddr)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			srv := server.New(service.NewCoreService(dm), opts, token, jobsPath, workers)
+			ctx, stop := interruptContext()
+			defer stop()
+			if err := srv.Start(ctx); err != nil {
+				ln.Close()
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Listening on http://%s (token: %s)\n", serveAddr, tokenSource)
+			err = srv.Serve(ctx, ln)
+			stop()
+			srv.Wait()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("\nServer stopped. Unfinished jobs resume on the next start.")
+		},
+	}
+	serveCmd.Flags().StringVar(&serveAddr, "addr", server.DefaultAddr, "Address to listen on (localhost only)")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "API token (default: $VARYS_API_TOKEN or the api-token file in the config directory)")
+	serveCmd.Flags().IntVar(&serveWorkers, "workers", queue.DefaultWorkers, "Jobs processed at the same time (overrides queue_workers from the config)")
+	return serveCmd
+}
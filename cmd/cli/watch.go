@@ -0,0 +1,46 @@
+package main
+
+import (
+	"Varys/backend/service"
+	"Varys/backend/storage"
+	"Varys/backend/watch"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var watchInterval time.Duration
+
+// newWatchCmd builds the "watch" command, which ingests files dropped into a folder.
+func newWatchCmd() *cobra.Command {
+	watchCmd := &cobra.Command{
+		Use:   "watch [folder]",
+		Short: "Ingest media and documents dropped into a folder",
+		Long: `Watch a folder, e.g. the one a voice recorder syncs to, and ingest every new media file or document
+once it has stopped growing. Processed files are moved to the "archive" subfolder and failed ones to "error",
+each next to a .log file of its task. Sources already in the vault are skipped unless --on-duplicate is given,
+so files left behind by an interrupted run are not ingested twice.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dm := loadDependencies()
+			opts := loadOptions(cmd)
+			if !cmd.Flags().Changed("on-duplicate") {
+				opts.OnDuplicate = storage.DuplicateSkip
+			}
+
+			w := watch.New(args[0], service.NewCoreService(dm), opts, &CLIPresenter{})
+			w.Interval = watchInterval
+			ctx, stop := interruptContext()
+			defer stop()
+			if err := w.Run(ctx); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("\nStopped watching.")
+		},
+	}
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", watch.DefaultInterval, "How often to check the folder; files are ingested once unchanged for one interval")
+	return watchCmd
+}
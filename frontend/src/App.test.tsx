@@ -1,10 +1,14 @@
 import { describe, it, expect, vi } from 'vitest';
-import { render, screen, fireEvent } from '@testing-library/react';
+import { render, screen, fireEvent, act } from '@testing-library/react';
 import App from './App';
 import '@testing-library/jest-dom';
 
 const appMocks = vi.hoisted(() => ({
-    SubmitTask: vi.fn(() => Promise.resolve("Mock Response")),
+    EnqueueTask: vi.fn((url: string, audioOnly: boolean) => Promise.resolve({ id: 'job-1', url, audio_only: audioOnly, status: 'queued', progress: 0 } as any)),
+    ListJobs: vi.fn(() => Promise.resolve([] as any[])),
+    RetryJob: vi.fn(() => Promise.resolve({} as any)),
+    RemoveJob: vi.fn(() => Promise.resolve()),
+    ClearFinishedJobs: vi.fn(() => Promise.resolve()),
     GetConfig: vi.fn(() => Promise.resolve({ 
         vault_path: '', 
         model_path: '', 
@@ -30,16 +34,21 @@ const appMocks = vi.hoisted(() => ({
     GetConfigPath: vi.fn(() => Promise.resolve("/tmp/config.json")),
     GetAppVersion: vi.fn(() => Promise.resolve("v0.4.3")),
     ReadClipboardText: vi.fn(() => Promise.resolve("sk-test-clipboard-key-12345678")),
-    CancelTask: vi.fn(() => Promise.resolve()),
+    CancelJob: vi.fn(() => Promise.resolve()),
     OpenOllamaModelLibrary: vi.fn(() => Promise.resolve("ok")),
     GetDefaultPrompt: vi.fn(() => Promise.resolve("Mock Default Prompt")),
+    GetNoteTemplates: vi.fn(() => Promise.resolve(["default", "english", "minimal"])),
     LocateConfigFile: vi.fn(() => Promise.resolve()),
     OpenFile: vi.fn(() => Promise.resolve()),
 }));
 
 // Mock the Wails JS backend call
 vi.mock('../wailsjs/go/app/App', () => ({
-    SubmitTask: appMocks.SubmitTask,
+    EnqueueTask: appMocks.EnqueueTask,
+    ListJobs: appMocks.ListJobs,
+    RetryJob: appMocks.RetryJob,
+    RemoveJob: appMocks.RemoveJob,
+    ClearFinishedJobs: appMocks.ClearFinishedJobs,
     GetConfig: appMocks.GetConfig,
     CheckDependencies: appMocks.CheckDependencies,
     GetStartupDiagnostics: appMocks.GetStartupDiagnostics,
@@ -54,16 +63,21 @@ vi.mock('../wailsjs/go/app/App', () => ({
     GetConfigPath: appMocks.GetConfigPath,
     GetAppVersion: appMocks.GetAppVersion,
     ReadClipboardText: appMocks.ReadClipboardText,
-    CancelTask: appMocks.CancelTask,
+    CancelJob: appMocks.CancelJob,
     OpenOllamaModelLibrary: appMocks.OpenOllamaModelLibrary,
     GetDefaultPrompt: appMocks.GetDefaultPrompt,
+    GetNoteTemplates: appMocks.GetNoteTemplates,
     LocateConfigFile: appMocks.LocateConfigFile,
     OpenFile: appMocks.OpenFile,
 }));
 
-// Mock Wails Runtime (for EventsOn)
+// Mock Wails Runtime (for EventsOn); handlers are kept so tests can emit job events
+const eventHandlers = vi.hoisted(() => ({} as Record<string, (data: any) => void>));
 vi.mock('../wailsjs/runtime', () => ({
-    EventsOn: vi.fn(() => () => {}),
+    EventsOn: vi.fn((name: string, cb: (data: any) => void) => {
+        eventHandlers[name] = cb;
+        return () => {};
+    }),
     WindowSetTitle: vi.fn(),
 }));
 
@@ -94,8 +108,7 @@ describe('App Component', () => {
 
     it('triggers OpenFile when clicking a successful task result', async () => {
         const mockPath = "/path/to/note.md";
-        appMocks.SubmitTask.mockResolvedValue(`Saved to: ${mockPath}`);
-        
+
         render(<App />);
 
         // Simulate running a task
@@ -105,6 +118,12 @@ describe('App Component', () => {
         fireEvent.change(input, { target: { value: 'https://youtube.com/watch?v=123' } });
         fireEvent.click(button);
 
+        // The job is queued, then the backend reports it as done
+        await screen.findByText("Queued...");
+        act(() => {
+            eventHandlers['job:update']({ id: 'job-1', url: 'https://youtube.com/watch?v=123', status: 'done', progress: 100, result: mockPath });
+        });
+
         // Wait for completion message (using findBy to wait for async state update)
         const resultMessage = await screen.findByText(/Task completed/i);
         expect(resultMessage).toBeInTheDocument();
@@ -2,6 +2,7 @@ import { useState, useRef, useEffect } from 'react';
 import { useTaskRunner } from './hooks/useTaskRunner';
 import LogConsole from './components/LogConsole';
 import AnalysisViewer from './components/AnalysisViewer';
+import JobList from './components/JobList';
 import { GetStartupDiagnostics, OpenFile } from '../wailsjs/go/app/App';
 
 interface DashboardProps {
@@ -16,38 +17,41 @@ export default function Dashboard(props: DashboardProps) {
     const inputRef = useRef<HTMLInputElement>(null);
 
     const {
-        isProcessing,
+        jobs,
+        selectedJob,
+        selectJob,
         logs,
         analysisStream,
         progress,
         resultText,
         runTask,
-        cancel
+        cancel,
+        retry,
+        remove,
+        clearFinished
     } = useTaskRunner();
 
     useEffect(() => {
         inputRef.current?.focus();
     }, []);
 
-    const handleProcessToggle = async () => {
-        if (isProcessing) {
-            cancel();
-        } else {
-            try {
-                const diag = await GetStartupDiagnostics();
-                if (!diag.ready) {
-                    props.onPreflightFailed?.();
-                    return;
-                }
-            } catch (err) {
-                console.error('Failed to run startup diagnostics', err);
+    const handleSubmit = async () => {
+        if (!url.trim()) return;
+        try {
+            const diag = await GetStartupDiagnostics();
+            if (!diag.ready) {
+                props.onPreflightFailed?.();
+                return;
             }
-            runTask(url, downloadVideo);
+        } catch (err) {
+            console.error('Failed to run startup diagnostics', err);
         }
+        await runTask(url, downloadVideo);
+        setUrl('');
     };
 
     const handleKeyDown = (e: React.KeyboardEvent) => {
-        if (e.key === 'Enter') handleProcessToggle();
+        if (e.key === 'Enter') handleSubmit();
     };
 
     const handleOpenResult = () => {
@@ -57,8 +61,8 @@ export default function Dashboard(props: DashboardProps) {
         }
     };
 
-    const isSuccess = resultText && resultText.startsWith("Saved to: ");
-    const isError = resultText && resultText.includes("failed");
+    const isSuccess = selectedJob?.status === 'done';
+    const isError = selectedJob?.status === 'failed';
 
     return (
         <div className="flex flex-col h-full max-w-5xl mx-auto p-6 w-full relative">
@@ -78,11 +82,10 @@ export default function Dashboard(props: DashboardProps) {
                         onChange={(e) => setUrl(e.target.value)}
                         onKeyDown={handleKeyDown}
                         placeholder="Enter YouTube/Bilibili URL"
-                        disabled={isProcessing}
                     />
 
                     <div className="absolute right-2 top-1/2 -translate-y-1/2 flex items-center bg-black/40 backdrop-blur-sm rounded-full px-2 py-1.5 border border-white/5">
-                        <label className={`flex items-center cursor-pointer gap-2 select-none ${isProcessing ? 'opacity-50 cursor-not-allowed' : ''}`}>
+                        <label className="flex items-center cursor-pointer gap-2 select-none">
                             <span className={`text-xs font-semibold transition-colors ${downloadVideo ? 'text-varys-secondary' : 'text-slate-400'}`}>Video</span>
                             <div className="relative">
                                 <input
@@ -90,7 +93,6 @@ export default function Dashboard(props: DashboardProps) {
                                     className="sr-only peer"
                                     checked={downloadVideo}
                                     onChange={(e) => setDownloadVideo(e.target.checked)}
-                                    disabled={isProcessing}
                                 />
                                 <div className="w-7 h-4 bg-slate-700 rounded-full peer peer-checked:bg-varys-secondary transition-colors"></div>
                                 <div className="absolute left-[2px] top-[2px] bg-white w-3 h-3 rounded-full transition-transform peer-checked:translate-x-3"></div>
@@ -100,26 +102,27 @@ export default function Dashboard(props: DashboardProps) {
                 </div>
 
                 <button
-                    className={`px-6 py-4 rounded-xl font-bold transition-all shadow-xl active:scale-95 flex items-center justify-center w-16 group ${
-                        isProcessing
-                        ? 'bg-red-500 hover:bg-red-400 text-white shadow-red-900/40'
-                        : 'bg-varys-primary hover:bg-varys-primary/80 text-white shadow-varys-primary/30'
-                    }`}
-                    onClick={handleProcessToggle}
-                    title={isProcessing ? "Stop & Clear" : "Start Processing"}
+                    className="px-6 py-4 rounded-xl font-bold transition-all shadow-xl active:scale-95 flex items-center justify-center w-16 group bg-varys-primary hover:bg-varys-primary/80 text-white shadow-varys-primary/30"
+                    onClick={handleSubmit}
+                    title="Start Processing"
                 >
-                    {isProcessing ? (
-                        <svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24" fill="currentColor">
-                            <rect x="6" y="6" width="12" height="12" rx="1" />
-                        </svg>
-                    ) : (
-                        <svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24" fill="currentColor" className="group-hover:translate-x-0.5 transition-transform">
-                            <polygon points="5 3 19 12 5 21 5 3" />
-                        </svg>
-                    )}
+                    <svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24" fill="currentColor" className="group-hover:translate-x-0.5 transition-transform">
+                        <polygon points="5 3 19 12 5 21 5 3" />
+                    </svg>
                 </button>
             </div>
 
+            {/* Job Queue */}
+            <JobList
+                jobs={jobs}
+                selectedId={selectedJob?.id}
+                onSelect={selectJob}
+                onCancel={cancel}
+                onRetry={retry}
+                onRemove={remove}
+                onClearFinished={clearFinished}
+            />
+
             {/* Progress Bar */}
             {progress > 0 && (
                 <div className="w-full bg-varys-surface/50 rounded-full h-2 mb-6 overflow-hidden border border-white/5">
@@ -1,5 +1,5 @@
 import { useState, useEffect, useRef } from 'react';
-import { GetConfig, UpdateConfig, SelectVaultPath, SelectModelPath, GetAIModels, GetConfigPath, GetAppVersion, GetStartupDiagnostics, LocateConfigFile, StartOllamaService, StopOllamaService, GetDefaultPrompt } from "../wailsjs/go/app/App";
+import { GetConfig, UpdateConfig, SelectVaultPath, SelectModelPath, GetAIModels, GetConfigPath, GetAppVersion, GetStartupDiagnostics, LocateConfigFile, StartOllamaService, StopOllamaService, GetDefaultPrompt, GetNoteTemplates } from "../wailsjs/go/app/App";
 import { app } from "../wailsjs/go/models";
 import HealthStatusBadge from "./components/health/HealthStatusBadge";
 import HealthItemRow from "./components/health/HealthItemRow";
@@ -7,6 +7,14 @@ import HealthItemRow from "./components/health/HealthItemRow";
 interface Config {
     vault_path: string;
     model_path: string;
+    subtitle_source: string;
+    queue_workers: number;
+    duplicate_mode: string;
+    note_template: string;
+    cookies_source: string;
+    cookies_browser: string;
+    cookies_profile: string;
+    cookies_file: string;
     llm_model: string;
     translation_model: string;
     target_language: string;
@@ -15,6 +23,15 @@ interface Config {
     ai_provider: string;
     openai_model: string;
     openai_key: string;
+    compatible_base_url: string;
+    compatible_model: string;
+    compatible_key: string;
+    ollama_host: string;
+    ollama_timeout: number;
+    ollama_keep_alive: string;
+    ollama_username: string;
+    ollama_password: string;
+    ollama_token: string;
 }
 
 interface SettingsProps {
@@ -27,6 +44,14 @@ export default function Settings(props: SettingsProps) {
     const [cfg, setCfg] = useState<Config>({ 
         vault_path: '', 
         model_path: '', 
+        subtitle_source: 'manual',
+        queue_workers: 2,
+        duplicate_mode: 'update',
+        note_template: '',
+        cookies_source: 'browser',
+        cookies_browser: 'chrome',
+        cookies_profile: '',
+        cookies_file: '',
         llm_model: '', 
         translation_model: 'qwen3:0.6b',
         target_language: '', 
@@ -34,19 +59,41 @@ export default function Settings(props: SettingsProps) {
         custom_prompt: '',
         ai_provider: 'ollama',
         openai_model: 'gpt-4o',
-        openai_key: ''
+        openai_key: '',
+        compatible_base_url: '',
+        compatible_model: '',
+        compatible_key: '',
+        ollama_host: '',
+        ollama_timeout: 0,
+        ollama_keep_alive: '',
+        ollama_username: '',
+        ollama_password: '',
+        ollama_token: ''
     });
     const [diagnostics, setDiagnostics] = useState<app.StartupDiagnostics | null>(null);
     const [aiModels, setAIModels] = useState<string[]>([]);
     const [configPath, setConfigPath] = useState<string>('');
     const [version, setVersion] = useState<string>('');
     const [defaultPrompt, setDefaultPrompt] = useState<string>('');
+    const [noteTemplates, setNoteTemplates] = useState<string[]>([]);
     const [status, setStatus] = useState<{msg: string, type: 'success' | 'error' | ''}>({msg: '', type: ''});
     const systemCheckRef = useRef<HTMLDivElement>(null);
     const displayVersion = version
         ? (version.toLowerCase().startsWith('v') ? version : `v${version}`)
         : '';
 
+    const isCompatible = cfg.ai_provider === 'openai-compatible' || cfg.ai_provider === 'llamacpp';
+    const modelValue = cfg.ai_provider === 'openai' ? cfg.openai_model : isCompatible ? cfg.compatible_model : cfg.llm_model;
+    const setModel = (value: string) => {
+        if (cfg.ai_provider === 'openai') {
+            setCfg({...cfg, openai_model: value});
+        } else if (isCompatible) {
+            setCfg({...cfg, compatible_model: value});
+        } else {
+            setCfg({...cfg, llm_model: value});
+        }
+    };
+
     const languages = [
         "Simplified Chinese",
         "Traditional Chinese",
@@ -73,6 +120,7 @@ export default function Settings(props: SettingsProps) {
         GetConfigPath().then(setConfigPath);
         GetAppVersion().then(setVersion);
         GetDefaultPrompt().then(setDefaultPrompt);
+        GetNoteTemplates().then(setNoteTemplates);
     }, []);
 
     useEffect(() => {
@@ -102,14 +150,14 @@ export default function Settings(props: SettingsProps) {
                 setAIModels([]);
                 return;
             }
-            GetAIModels(cfg.ai_provider, cfg.openai_key)
+            GetAIModels(cfg.ai_provider, isCompatible ? cfg.compatible_key : cfg.openai_key)
                 .then(setAIModels)
                 .catch(err => {
                     console.error("Failed to fetch models", err);
                     setAIModels([]);
                 });
         }
-    }, [cfg.ai_provider, cfg.openai_key]);
+    }, [cfg.ai_provider, cfg.openai_key, cfg.compatible_key]);
 
     const save = () => {
         setStatus({msg: 'Saving...', type: ''});
@@ -193,6 +241,105 @@ export default function Settings(props: SettingsProps) {
                         </div>
                     </div>
 
+                    <div>
+                        <label className="block text-sm font-semibold text-slate-400 mb-2">yt-dlp Cookies</label>
+                        <select
+                            className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 appearance-none shadow-inner"
+                            value={cfg.cookies_source || 'browser'}
+                            onChange={e => setCfg({...cfg, cookies_source: e.target.value})}
+                        >
+                            <option value="browser">From browser</option>
+                            <option value="file">cookies.txt file</option>
+                            <option value="none">No cookies</option>
+                        </select>
+                        {(cfg.cookies_source || 'browser') === 'browser' && (
+                            <div className="flex gap-2 mt-2">
+                                <select
+                                    className="flex-1 bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 appearance-none shadow-inner"
+                                    value={cfg.cookies_browser || 'chrome'}
+                                    onChange={e => setCfg({...cfg, cookies_browser: e.target.value})}
+                                >
+                                    {['chrome', 'firefox', 'safari', 'edge', 'brave', 'chromium', 'opera', 'vivaldi', 'whale'].map(b => <option key={b} value={b}>{b}</option>)}
+                                </select>
+                                <input
+                                    className="flex-1 bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 shadow-inner"
+                                    placeholder="Profile (optional)"
+                                    value={cfg.cookies_profile}
+                                    onChange={e => setCfg({...cfg, cookies_profile: e.target.value})}
+                                />
+                            </div>
+                        )}
+                        {cfg.cookies_source === 'file' && (
+                            <input
+                                className="w-full mt-2 bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 shadow-inner"
+                                placeholder="/path/to/cookies.txt"
+                                value={cfg.cookies_file}
+                                onChange={e => setCfg({...cfg, cookies_file: e.target.value})}
+                            />
+                        )}
+                    </div>
+
+                    <div>
+                        <label className="block text-sm font-semibold text-slate-400 mb-2">Transcript Source</label>
+                        <select
+                            className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 appearance-none shadow-inner"
+                            value={cfg.subtitle_source || 'manual'}
+                            onChange={e => setCfg({...cfg, subtitle_source: e.target.value})}
+                        >
+                            <option value="manual">Platform subtitles, then Whisper</option>
+                            <option value="auto">Platform or auto-generated subtitles, then Whisper</option>
+                            <option value="whisper">Always Whisper</option>
+                        </select>
+                    </div>
+
+                    <div>
+                        <label className="block text-sm font-semibold text-slate-400 mb-2">Concurrent Jobs</label>
+                        <select
+                            className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 appearance-none shadow-inner"
+                            value={cfg.queue_workers || 2}
+                            onChange={e => setCfg({...cfg, queue_workers: parseInt(e.target.value)})}
+                        >
+                            {[1, 2, 3, 4].map(n => <option key={n} value={n}>{n}{n === 2 ? ' (Default)' : ''}</option>)}
+                        </select>
+                        <p className="mt-1 text-[10px] text-slate-500 italic">
+                            Each job downloads and transcribes on its own. Local Whisper and Ollama runs compete for the same CPU/GPU.
+                        </p>
+                    </div>
+
+                    <div>
+                        <label className="block text-sm font-semibold text-slate-400 mb-2">Note Template</label>
+                        <input
+                            type="text"
+                            list="note-templates"
+                            className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 shadow-inner"
+                            placeholder="default"
+                            value={cfg.note_template || ''}
+                            onChange={e => setCfg({...cfg, note_template: e.target.value})}
+                        />
+                        <datalist id="note-templates">
+                            {noteTemplates.map(name => <option key={name} value={name} />)}
+                        </datalist>
+                        <p className="mt-1 text-[10px] text-slate-500 italic">
+                            A built-in template or a file in your vault or config folder, e.g. templates/note.md.
+                        </p>
+                    </div>
+
+                    <div>
+                        <label className="block text-sm font-semibold text-slate-400 mb-2">Already Ingested Sources</label>
+                        <select
+                            className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 appearance-none shadow-inner"
+                            value={cfg.duplicate_mode || 'update'}
+                            onChange={e => setCfg({...cfg, duplicate_mode: e.target.value})}
+                        >
+                            <option value="update">Update generated sections (Default)</option>
+                            <option value="skip">Skip</option>
+                            <option value="version">Create a versioned copy</option>
+                        </select>
+                        <p className="mt-1 text-[10px] text-slate-500 italic">
+                            Sections and properties you added to a note are kept when it is updated.
+                        </p>
+                    </div>
+
                     <div>
                         <label className="block text-sm font-semibold text-slate-400 mb-2">AI Provider</label>
                         <select
@@ -202,9 +349,90 @@ export default function Settings(props: SettingsProps) {
                         >
                             <option value="ollama">Ollama (Local)</option>
                             <option value="openai">OpenAI (Cloud)</option>
+                            <option value="openai-compatible">OpenAI-Compatible Server</option>
+                            <option value="llamacpp">llama.cpp Server</option>
                         </select>
                     </div>
 
+                    {(cfg.ai_provider || 'ollama') === 'ollama' && (
+                        <div>
+                            <label className="block text-sm font-semibold text-slate-400 mb-2">Ollama Host</label>
+                            <input
+                                className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 font-mono shadow-inner"
+                                value={cfg.ollama_host || ''}
+                                onChange={e => setCfg({...cfg, ollama_host: e.target.value})}
+                                placeholder="http://localhost:11434"
+                            />
+                            <div className="grid grid-cols-2 gap-3 mt-3">
+                                <div>
+                                    <label className="block text-xs font-semibold text-slate-500 mb-1">Idle timeout (seconds, 0 = none)</label>
+                                    <input
+                                        type="number"
+                                        min={0}
+                                        className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 shadow-inner"
+                                        value={cfg.ollama_timeout || 0}
+                                        onChange={e => setCfg({...cfg, ollama_timeout: parseInt(e.target.value) || 0})}
+                                    />
+                                </div>
+                                <div>
+                                    <label className="block text-xs font-semibold text-slate-500 mb-1">Keep Alive</label>
+                                    <input
+                                        className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 font-mono shadow-inner"
+                                        value={cfg.ollama_keep_alive || ''}
+                                        onChange={e => setCfg({...cfg, ollama_keep_alive: e.target.value})}
+                                        placeholder="e.g. 10m"
+                                    />
+                                </div>
+                                <div>
+                                    <label className="block text-xs font-semibold text-slate-500 mb-1">Proxy Username</label>
+                                    <input
+                                        className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 shadow-inner"
+                                        value={cfg.ollama_username || ''}
+                                        onChange={e => setCfg({...cfg, ollama_username: e.target.value})}
+                                    />
+                                </div>
+                                <div>
+                                    <label className="block text-xs font-semibold text-slate-500 mb-1">Proxy Password</label>
+                                    <input
+                                        type="password"
+                                        className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 shadow-inner"
+                                        value={cfg.ollama_password || ''}
+                                        onChange={e => setCfg({...cfg, ollama_password: e.target.value})}
+                                    />
+                                </div>
+                            </div>
+                            <label className="block text-xs font-semibold text-slate-500 mt-3 mb-1">Bearer Token (optional, overrides username/password)</label>
+                            <input
+                                type="password"
+                                className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 font-mono shadow-inner"
+                                value={cfg.ollama_token || ''}
+                                onChange={e => setCfg({...cfg, ollama_token: e.target.value})}
+                            />
+                        </div>
+                    )}
+
+                    {isCompatible && (
+                        <div>
+                            <label className="block text-sm font-semibold text-slate-400 mb-2">Base URL</label>
+                            <input
+                                className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 font-mono shadow-inner"
+                                value={cfg.compatible_base_url || ''}
+                                onChange={e => setCfg({...cfg, compatible_base_url: e.target.value})}
+                                placeholder={cfg.ai_provider === 'llamacpp' ? "http://localhost:8080/v1" : "e.g. http://localhost:1234/v1"}
+                            />
+                            <label className="block text-sm font-semibold text-slate-400 mt-4 mb-2">API Key (optional)</label>
+                            <input
+                                type="password"
+                                className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 font-mono shadow-inner"
+                                value={cfg.compatible_key || ''}
+                                onChange={e => setCfg({...cfg, compatible_key: e.target.value})}
+                            />
+                            <p className="mt-1 text-[10px] text-slate-500 italic">
+                                Save settings after changing the Base URL to refresh the model list.
+                            </p>
+                        </div>
+                    )}
+
                     {cfg.ai_provider === 'openai' && (
                         <div>
                             <label className="block text-sm font-semibold text-slate-400 mb-2 flex justify-between">
@@ -228,19 +456,13 @@ export default function Settings(props: SettingsProps) {
 
                     <div>
                         <label className="block text-sm font-semibold text-slate-400 mb-2">
-                            {cfg.ai_provider === 'openai' ? 'OpenAI Model' : 'Ollama Model'}
+                            {cfg.ai_provider === 'openai' ? 'OpenAI Model' : isCompatible ? 'Server Model' : 'Ollama Model'}
                         </label>
                         {aiModels.length > 0 ? (
                             <select
                                 className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 appearance-none shadow-inner"
-                                value={cfg.ai_provider === 'openai' ? cfg.openai_model : cfg.llm_model}
-                                onChange={e => {
-                                    if (cfg.ai_provider === 'openai') {
-                                        setCfg({...cfg, openai_model: e.target.value});
-                                    } else {
-                                        setCfg({...cfg, llm_model: e.target.value});
-                                    }
-                                }}
+                                value={modelValue}
+                                onChange={e => setModel(e.target.value)}
                             >
                                 <option value="" disabled>Select a model...</option>
                                 {aiModels.map(m => <option key={m} value={m}>{m}</option>)}
@@ -248,14 +470,8 @@ export default function Settings(props: SettingsProps) {
                         ) : (
                             <input
                                 className="w-full bg-varys-surface border border-varys-border/20 text-slate-300 px-3 py-2.5 rounded-lg text-sm focus:outline-none focus:border-varys-primary/50 shadow-inner"
-                                value={cfg.ai_provider === 'openai' ? cfg.openai_model : cfg.llm_model}
-                                onChange={e => {
-                                    if (cfg.ai_provider === 'openai') {
-                                        setCfg({...cfg, openai_model: e.target.value});
-                                    } else {
-                                        setCfg({...cfg, llm_model: e.target.value});
-                                    }
-                                }}
+                                value={modelValue}
+                                onChange={e => setModel(e.target.value)}
                                 placeholder={cfg.ai_provider === 'openai' ? "e.g. gpt-4o" : "e.g. qwen3:8b"}
                             />
                         )}
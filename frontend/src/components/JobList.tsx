@@ -0,0 +1,69 @@
+import { queue } from '../../wailsjs/go/models';
+import { isActiveJob } from '../hooks/useTaskRunner';
+
+interface JobListProps {
+    jobs: queue.Job[];
+    selectedId?: string;
+    onSelect: (id: string) => void;
+    onCancel: (id: string) => void;
+    onRetry: (id: string) => void;
+    onRemove: (id: string) => void;
+    onClearFinished: () => void;
+}
+
+const statusStyles: Record<string, string> = {
+    queued: 'bg-slate-600/30 text-slate-300',
+    running: 'bg-varys-secondary/20 text-varys-secondary',
+    done: 'bg-emerald-500/20 text-emerald-400',
+    failed: 'bg-red-500/20 text-red-400',
+    cancelled: 'bg-amber-500/20 text-amber-400',
+};
+
+export default function JobList({ jobs, selectedId, onSelect, onCancel, onRetry, onRemove, onClearFinished }: JobListProps) {
+    if (jobs.length === 0) return null;
+
+    const hasFinished = jobs.some(j => j.status === 'done' || j.status === 'cancelled');
+
+    return (
+        <div className="mb-6 bg-slate-800/50 border border-slate-800 rounded-xl overflow-hidden">
+            <div className="px-4 py-2 border-b border-slate-800 bg-slate-800/80 font-medium text-slate-400 text-xs uppercase tracking-wider flex justify-between items-center">
+                <span>Queue ({jobs.filter(j => isActiveJob(j)).length} active)</span>
+                {hasFinished && (
+                    <button onClick={onClearFinished} className="text-slate-500 hover:text-slate-300 transition-colors normal-case tracking-normal">
+                        Clear finished
+                    </button>
+                )}
+            </div>
+            <ul className="max-h-40 overflow-y-auto divide-y divide-slate-800">
+                {jobs.map(job => (
+                    <li
+                        key={job.id}
+                        onClick={() => onSelect(job.id)}
+                        className={`px-4 py-2 flex items-center gap-3 cursor-pointer text-sm transition-colors ${job.id === selectedId ? 'bg-varys-primary/10' : 'hover:bg-slate-700/30'}`}
+                    >
+                        <span className={`text-[10px] font-bold uppercase px-2 py-0.5 rounded-full w-20 text-center ${statusStyles[job.status] || ''}`}>
+                            {job.status === 'running' && job.progress > 0 ? `${Math.round(job.progress)}%` : job.status}
+                        </span>
+                        <span className="flex-1 truncate text-slate-300" title={job.error || job.url}>{job.url}</span>
+                        {isActiveJob(job) ? (
+                            <button onClick={(e) => { e.stopPropagation(); onCancel(job.id); }} className="text-xs text-red-400 hover:text-red-300" title="Cancel job">
+                                Cancel
+                            </button>
+                        ) : (
+                            <>
+                                {(job.status === 'failed' || job.status === 'cancelled') && (
+                                    <button onClick={(e) => { e.stopPropagation(); onRetry(job.id); }} className="text-xs text-varys-secondary hover:opacity-80" title="Retry job">
+                                        Retry
+                                    </button>
+                                )}
+                                <button onClick={(e) => { e.stopPropagation(); onRemove(job.id); }} className="text-xs text-slate-500 hover:text-slate-300" title="Remove job">
+                                    Remove
+                                </button>
+                            </>
+                        )}
+                    </li>
+                ))}
+            </ul>
+        </div>
+    );
+}
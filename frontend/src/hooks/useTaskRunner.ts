@@ -1,80 +1,125 @@
 import { useState, useEffect, useCallback } from 'react';
-import { SubmitTask, CancelTask } from "../../wailsjs/go/app/App";
+import { EnqueueTask, CancelJob, RetryJob, RemoveJob, ListJobs, ClearFinishedJobs } from "../../wailsjs/go/app/App";
 import { EventsOn } from "../../wailsjs/runtime";
+import { queue } from "../../wailsjs/go/models";
+
+interface JobMessage {
+    job_id: string;
+    message: string;
+}
+
+export const isActiveJob = (job?: queue.Job) => job?.status === 'queued' || job?.status === 'running';
 
 export function useTaskRunner() {
-    const [isProcessing, setIsProcessing] = useState(false);
-    const [logs, setLogs] = useState<string[]>([]);
-    const [analysisStream, setAnalysisStream] = useState("");
-    const [progress, setProgress] = useState(0);
-    const [resultText, setResultText] = useState("");
+    const [jobs, setJobs] = useState<queue.Job[]>([]);
+    const [selectedId, setSelectedId] = useState<string | null>(null);
+    const [logs, setLogs] = useState<Record<string, string[]>>({});
+    const [analysis, setAnalysis] = useState<Record<string, string>>({});
 
-    const addLog = useCallback((msg: string) => {
+    const addLog = useCallback((id: string, msg: string) => {
         const time = new Date().toLocaleTimeString([], { hour12: false });
-        setLogs(prev => [...prev, `[${time}] ${msg}`]);
+        setLogs(prev => ({ ...prev, [id]: [...(prev[id] || []), `[${time}] ${msg}`] }));
     }, []);
 
-    useEffect(() => {
-        const unsubLog = EventsOn("task:log", (msg: string) => addLog(msg));
-        const unsubAnalysis = EventsOn("task:analysis", (chunk: string) => {
-            setAnalysisStream(prev => prev + chunk);
+    const upsertJob = useCallback((job: queue.Job) => {
+        setJobs(prev => {
+            const idx = prev.findIndex(j => j.id === job.id);
+            if (idx === -1) return [...prev, job];
+            const next = [...prev];
+            next[idx] = job;
+            return next;
         });
-        const unsubProgress = EventsOn("task:progress", (p: number) => {
-            setProgress(p);
+    }, []);
+
+    useEffect(() => {
+        // Jobs restored from the last session
+        ListJobs().then(list => setJobs(list || [])).catch(console.error);
+
+        const unsubUpdate = EventsOn("job:update", (job: queue.Job) => upsertJob(job));
+        const unsubLog = EventsOn("job:log", (e: JobMessage) => addLog(e.job_id, e.message));
+        const unsubAnalysis = EventsOn("job:analysis", (e: JobMessage) => {
+            setAnalysis(prev => ({ ...prev, [e.job_id]: (prev[e.job_id] || "") + e.message }));
         });
 
         return () => {
+            unsubUpdate();
             unsubLog();
             unsubAnalysis();
-            unsubProgress();
         };
-    }, [addLog]);
+    }, [addLog, upsertJob]);
 
     const runTask = async (url: string, downloadVideo: boolean) => {
         if (!url) return;
-        
-        setLogs([]);
-        setAnalysisStream("");
-        setProgress(0);
-        setIsProcessing(true);
-        setResultText("Processing...");
-        
         const audioOnly = !downloadVideo;
-        addLog(`Processing URL: ${url} (AudioOnly: ${audioOnly})`);
+        try {
+            const job = await EnqueueTask(url, audioOnly);
+            upsertJob(job);
+            setSelectedId(job.id);
+            addLog(job.id, `Queued URL: ${url} (AudioOnly: ${audioOnly})`);
+        } catch (err: any) {
+            console.error(`Failed to queue task: ${err}`);
+        }
+    };
+
+    const cancel = async (id: string) => {
+        try {
+            await CancelJob(id);
+            addLog(id, "User requested cancellation...");
+        } catch (err: any) {
+            addLog(id, `Failed to cancel: ${err}`);
+        }
+    };
 
+    const retry = async (id: string) => {
         try {
-            const response = await SubmitTask(url, audioOnly);
-            addLog(`Backend Response: ${response}`);
-            setResultText(response); // Use actual response (e.g. "Saved to: ...")
+            upsertJob(await RetryJob(id));
+            setLogs(prev => ({ ...prev, [id]: [] }));
+            setAnalysis(prev => ({ ...prev, [id]: "" }));
+            setSelectedId(id);
         } catch (err: any) {
-            addLog(`Error: ${err}`);
-            setResultText("Task failed");
-        } finally {
-            setProgress(0);
-            setIsProcessing(false);
+            addLog(id, `Failed to retry: ${err}`);
         }
     };
 
-    const cancel = async () => {
-        if (!isProcessing) return;
+    const remove = async (id: string) => {
         try {
-            await CancelTask();
-            addLog("User requested cancellation...");
-            setIsProcessing(false);
-            setResultText("Cancelled");
-            setProgress(0);
+            await RemoveJob(id);
+            setJobs(prev => prev.filter(j => j.id !== id));
+            if (selectedId === id) setSelectedId(null);
         } catch (err: any) {
-            addLog(`Failed to cancel: ${err}`);
+            addLog(id, `Failed to remove: ${err}`);
         }
     };
 
+    const clearFinished = async () => {
+        await ClearFinishedJobs();
+        setJobs(prev => prev.filter(j => j.status !== 'done' && j.status !== 'cancelled'));
+    };
+
+    const selectedJob = jobs.find(j => j.id === selectedId);
+
+    let resultText = "";
+    switch (selectedJob?.status) {
+        case 'queued': resultText = "Queued..."; break;
+        case 'running': resultText = "Processing..."; break;
+        case 'done': resultText = `Saved to: ${selectedJob.result}`; break;
+        case 'failed': resultText = `Task failed: ${selectedJob.error}`; break;
+        case 'cancelled': resultText = "Cancelled"; break;
+    }
+
     return {
-        isProcessing,
-        logs,
-        analysisStream,
-        progress,
+        jobs,
+        selectedJob,
+        selectJob: setSelectedId,
+        isProcessing: isActiveJob(selectedJob),
+        logs: (selectedId && logs[selectedId]) || [],
+        analysisStream: (selectedId && analysis[selectedId]) || "",
+        progress: selectedJob?.status === 'running' ? selectedJob.progress : 0,
         resultText,
         runTask,
-        cancel
+        cancel,
+        retry,
+        remove,
+        clearFinished
     };
 }
@@ -2,13 +2,22 @@
 // This file is automatically generated. DO NOT EDIT
 import {app} from '../models';
 import {config} from '../models';
+import {queue} from '../models';
 
-export function CancelTask():Promise<void>;
+export function CancelJob(arg1:string):Promise<void>;
 
 export function CheckDependencies():Promise<app.DependencyStatus>;
 
 export function CheckYtDlpUpdate():Promise<app.YtDlpUpdateInfo>;
 
+export function ClearFinishedJobs():Promise<void>;
+
+export function EnqueuePlaylist(arg1:string,arg2:boolean,arg3:string,arg4:string,arg5:number,arg6:string):Promise<queue.Job>;
+
+export function EnqueueTask(arg1:string,arg2:boolean):Promise<queue.Job>;
+
+export function EnqueueTasks(arg1:Array<string>,arg2:boolean):Promise<Array<queue.Job>>;
+
 export function GetAIModels(arg1:string,arg2:string):Promise<Array<string>>;
 
 export function GetAppVersion():Promise<string>;
@@ -19,8 +28,14 @@ export function GetConfigPath():Promise<string>;
 
 export function GetDefaultPrompt():Promise<string>;
 
+export function GetJobLogs(arg1:string):Promise<Array<string>>;
+
+export function GetNoteTemplates():Promise<Array<string>>;
+
 export function GetStartupDiagnostics():Promise<app.StartupDiagnostics>;
 
+export function ListJobs():Promise<Array<queue.Job>>;
+
 export function LocateConfigFile():Promise<void>;
 
 export function OpenFile(arg1:string):Promise<void>;
@@ -29,6 +44,10 @@ export function OpenOllamaModelLibrary():Promise<string>;
 
 export function ReadClipboardText():Promise<string>;
 
+export function RemoveJob(arg1:string):Promise<void>;
+
+export function RetryJob(arg1:string):Promise<queue.Job>;
+
 export function SelectModelPath():Promise<string>;
 
 export function SelectVaultPath():Promise<string>;
@@ -37,8 +56,6 @@ export function StartOllamaService():Promise<string>;
 
 export function StopOllamaService():Promise<string>;
 
-export function SubmitTask(arg1:string,arg2:boolean):Promise<string>;
-
 export function UpdateConfig(arg1:config.Config):Promise<void>;
 
 export function UpdateModelPath(arg1:string):Promise<void>;
@@ -2,8 +2,8 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function CancelTask() {
-  return window['go']['app']['App']['CancelTask']();
+export function CancelJob(arg1) {
+  return window['go']['app']['App']['CancelJob'](arg1);
 }
 
 export function CheckDependencies() {
@@ -14,6 +14,22 @@ export function CheckYtDlpUpdate() {
   return window['go']['app']['App']['CheckYtDlpUpdate']();
 }
 
+export function ClearFinishedJobs() {
+  return window['go']['app']['App']['ClearFinishedJobs']();
+}
+
+export function EnqueuePlaylist(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['app']['App']['EnqueuePlaylist'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function EnqueueTask(arg1, arg2) {
+  return window['go']['app']['App']['EnqueueTask'](arg1, arg2);
+}
+
+export function EnqueueTasks(arg1, arg2) {
+  return window['go']['app']['App']['EnqueueTasks'](arg1, arg2);
+}
+
 export function GetAIModels(arg1, arg2) {
   return window['go']['app']['App']['GetAIModels'](arg1, arg2);
 }
@@ -34,10 +50,22 @@ export function GetDefaultPrompt() {
   return window['go']['app']['App']['GetDefaultPrompt']();
 }
 
+export function GetJobLogs(arg1) {
+  return window['go']['app']['App']['GetJobLogs'](arg1);
+}
+
+export function GetNoteTemplates() {
+  return window['go']['app']['App']['GetNoteTemplates']();
+}
+
 export function GetStartupDiagnostics() {
   return window['go']['app']['App']['GetStartupDiagnostics']();
 }
 
+export function ListJobs() {
+  return window['go']['app']['App']['ListJobs']();
+}
+
 export function LocateConfigFile() {
   return window['go']['app']['App']['LocateConfigFile']();
 }
@@ -54,6 +82,14 @@ export function ReadClipboardText() {
   return window['go']['app']['App']['ReadClipboardText']();
 }
 
+export function RemoveJob(arg1) {
+  return window['go']['app']['App']['RemoveJob'](arg1);
+}
+
+export function RetryJob(arg1) {
+  return window['go']['app']['App']['RetryJob'](arg1);
+}
+
 export function SelectModelPath() {
   return window['go']['app']['App']['SelectModelPath']();
 }
@@ -70,10 +106,6 @@ export function StopOllamaService() {
   return window['go']['app']['App']['StopOllamaService']();
 }
 
-export function SubmitTask(arg1, arg2) {
-  return window['go']['app']['App']['SubmitTask'](arg1, arg2);
-}
-
 export function UpdateConfig(arg1) {
   return window['go']['app']['App']['UpdateConfig'](arg1);
 }
@@ -110,15 +110,38 @@ export namespace config {
 	export class Config {
 	    vault_path: string;
 	    model_path: string;
+	    subtitle_source: string;
 	    llm_model: string;
 	    translation_model: string;
 	    target_language: string;
 	    context_size: number;
 	    custom_prompt: string;
+	    analysis_mode: string;
+	    translation_workers: number;
+	    translation_rate_limit: number;
+	    glossary_path: string;
+	    queue_workers: number;
+	    duplicate_mode: string;
+	    note_template: string;
 	    ai_provider: string;
 	    openai_model: string;
 	    openai_key?: string;
 	    tavily_key?: string;
+	    cookies_source: string;
+	    cookies_browser: string;
+	    cookies_profile: string;
+	    cookies_file: string;
+	    compatible_base_url: string;
+	    compatible_model: string;
+	    compatible_key?: string;
+	    compatible_headers?: Record<string, string>;
+	    ollama_host: string;
+	    ollama_timeout: number;
+	    ollama_keep_alive: string;
+	    ollama_username?: string;
+	    ollama_password?: string;
+	    ollama_token?: string;
+	    fallback_providers?: ProviderRef[];
 	
 	    static createFrom(source: any = {}) {
 	        return new Config(source);
@@ -128,16 +151,147 @@ export namespace config {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.vault_path = source["vault_path"];
 	        this.model_path = source["model_path"];
+	        this.subtitle_source = source["subtitle_source"];
 	        this.llm_model = source["llm_model"];
 	        this.translation_model = source["translation_model"];
 	        this.target_language = source["target_language"];
 	        this.context_size = source["context_size"];
 	        this.custom_prompt = source["custom_prompt"];
+	        this.analysis_mode = source["analysis_mode"];
+	        this.translation_workers = source["translation_workers"];
+	        this.translation_rate_limit = source["translation_rate_limit"];
+	        this.glossary_path = source["glossary_path"];
+	        this.queue_workers = source["queue_workers"];
+	        this.duplicate_mode = source["duplicate_mode"];
+	        this.note_template = source["note_template"];
 	        this.ai_provider = source["ai_provider"];
 	        this.openai_model = source["openai_model"];
 	        this.openai_key = source["openai_key"];
 	        this.tavily_key = source["tavily_key"];
+	        this.cookies_source = source["cookies_source"];
+	        this.cookies_browser = source["cookies_browser"];
+	        this.cookies_profile = source["cookies_profile"];
+	        this.cookies_file = source["cookies_file"];
+	        this.compatible_base_url = source["compatible_base_url"];
+	        this.compatible_model = source["compatible_model"];
+	        this.compatible_key = source["compatible_key"];
+	        this.compatible_headers = source["compatible_headers"];
+	        this.ollama_host = source["ollama_host"];
+	        this.ollama_timeout = source["ollama_timeout"];
+	        this.ollama_keep_alive = source["ollama_keep_alive"];
+	        this.ollama_username = source["ollama_username"];
+	        this.ollama_password = source["ollama_password"];
+	        this.ollama_token = source["ollama_token"];
+	        this.fallback_providers = this.convertValues(source["fallback_providers"], ProviderRef);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProviderRef {
+	    provider: string;
+	    model: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProviderRef(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.provider = source["provider"];
+	        this.model = source["model"];
+	    }
+	}
+
+}
+
+export namespace queue {
+	
+	export class PlaylistOptions {
+	    after?: string;
+	    before?: string;
+	    max_items?: number;
+	    title_pattern?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlaylistOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.after = source["after"];
+	        this.before = source["before"];
+	        this.max_items = source["max_items"];
+	        this.title_pattern = source["title_pattern"];
+	    }
+	}
+	export class Job {
+	    id: string;
+	    url: string;
+	    audio_only: boolean;
+	    playlist?: PlaylistOptions;
+	    status: string;
+	    progress: number;
+	    result?: string;
+	    error?: string;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    started_at: any;
+	    // Go type: time
+	    finished_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Job(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.url = source["url"];
+	        this.audio_only = source["audio_only"];
+	        this.playlist = this.convertValues(source["playlist"], PlaylistOptions);
+	        this.status = source["status"];
+	        this.progress = source["progress"];
+	        this.result = source["result"];
+	        this.error = source["error"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.started_at = this.convertValues(source["started_at"], null);
+	        this.finished_at = this.convertValues(source["finished_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }